@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"patungan_app_echo/internal/models"
 	"patungan_app_echo/internal/services"
+	"patungan_app_echo/internal/tasks"
 
 	"github.com/joho/godotenv"
 )
@@ -21,7 +23,9 @@ func main() {
 	dueStr := flag.String("due", "", "Due date (mandatory, format: 2006-01-02 15:04)")
 	taskType := flag.String("tasktype", "onetime", "Task type (optional, default: onetime)")
 	recurring := flag.String("recurring", "", "Recurring interval rule (optional)")
-	maxAttempt := flag.Int("max_attempt", 3, "Max attempts (optional, default: 3)")
+	maxAttempt := flag.Int("max_attempt", 0, "Max attempts (optional, default: the task's own)")
+	timezone := flag.String("timezone", "", "Timezone for the due date and RRULE, e.g. Asia/Jakarta (optional, default: APP_TIMEZONE)")
+	misfire := flag.String("misfire", "", "Misfire policy for recurring tasks: catch_up_all, run_once or skip (optional, default: the task's own)")
 
 	flag.Parse()
 
@@ -54,22 +58,49 @@ func main() {
 		log.Fatalf("Invalid JSON arguments: %v", err)
 	}
 
-	// Parse due date
-	// Try simplified format first (Local time assumed if no timezone info, but time.Parse uses UTC for year/month/day layouts usually unless InLocation is used)
-	// Actually time.Parse treats it as UTC if no tz offset.
-	// Let's assume user inputs something generic, or RFC3339.
+	// Validate arguments against the task definition
+	tasks.DefineTasks()
+	if _, found := tasks.GetHandler(*taskName); !found {
+		log.Fatalf("Unknown task %q. Available tasks: %s", *taskName, strings.Join(tasks.GlobalRegistry.Names(), ", "))
+	}
+	if err := tasks.ValidateArguments(*taskName, args); err != nil {
+		log.Fatalf("Invalid arguments for task %s: %v", *taskName, err)
+	}
+
+	// Parse due date, a date without offset is read in the task timezone
+	if *timezone != "" && !models.IsValidTimezone(*timezone) {
+		log.Fatalf("Unknown timezone %q", *timezone)
+	}
+	loc := models.LoadLocation(*timezone)
+
 	due, err := time.Parse(time.RFC3339, *dueStr)
 	if err != nil {
 		// Try simple format "2006-01-02 15:04"
-		// We'll parse it in Local time context for convenience? Or UTC?
-		// Let's sticking to time.Parse which returns UTC for this layout is safer for server consistency unless we know user's timezone.
-		// Use ParseInLocation with Local?
-		due, err = time.ParseInLocation("2006-01-02 15:04", *dueStr, time.Local)
+		due, err = time.ParseInLocation("2006-01-02 15:04", *dueStr, loc)
 		if err != nil {
-			log.Fatalf("Invalid due date format. Use '2006-01-02 15:04' (Local) or RFC3339: %v", err)
+			log.Fatalf("Invalid due date format. Use '2006-01-02 15:04' (%s) or RFC3339: %v", loc, err)
 		}
 	}
 
+	// Settings not passed are those of the task definition, e.g. catch_up_all for process_plan_schedule
+	defaultMaxAttempt, misfirePolicy := tasks.GetTaskDefaults(*taskName)
+	passed := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { passed[f.Name] = true })
+
+	if !passed["max_attempt"] {
+		*maxAttempt = defaultMaxAttempt
+	} else if *maxAttempt <= 0 {
+		log.Fatalf("Invalid max attempts %d, it must be at least 1", *maxAttempt)
+	}
+	if passed["misfire"] {
+		misfirePolicy = models.MisfirePolicy(*misfire)
+	}
+	switch misfirePolicy {
+	case models.MisfirePolicyCatchUpAll, models.MisfirePolicyRunOnce, models.MisfirePolicySkip:
+	default:
+		log.Fatalf("Invalid misfire policy %q. Use catch_up_all, run_once or skip", *misfire)
+	}
+
 	// Recurring ptr
 	var recurringPtr *string
 	if *recurring != "" {
@@ -83,6 +114,8 @@ func main() {
 		TaskType:          models.ScheduledTaskType(*taskType),
 		RecurringInterval: recurringPtr,
 		MaxAttempt:        *maxAttempt,
+		MisfirePolicy:     misfirePolicy,
+		Timezone:          *timezone,
 		Status:            models.ScheduledTaskStatusActive,
 	}
 
@@ -91,5 +124,5 @@ func main() {
 	}
 
 	fmt.Printf("Successfully created task ID: %d\n", task.ID)
-	fmt.Printf("Task: %s\nDue: %s\nType: %s\n", task.TaskName, task.Due, task.TaskType)
+	fmt.Printf("Task: %s\nDue: %s\nType: %s\n", task.TaskName, task.Due.In(loc), task.TaskType)
 }
@@ -11,10 +11,15 @@ import (
 	"gorm.io/gorm"
 
 	"patungan_app_echo/internal/handlers"
+	"patungan_app_echo/internal/metrics"
 	authMiddleware "patungan_app_echo/internal/middleware"
 	"patungan_app_echo/internal/services"
+	"patungan_app_echo/internal/tasks"
 )
 
+// DefaultMetricsAddr is where the server serves Prometheus metrics unless METRICS_ADDR is set
+const DefaultMetricsAddr = ":9090"
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -65,15 +70,15 @@ func main() {
 		log.Println("Warning: REDIS_URL not set, caching disabled")
 	}
 
-	// Initialize Midtrans
-	midtransService := services.NewMidtransService()
-
 	// Initialize Email
 	emailService := services.NewEmailService()
 
 	// Initialize WAHA
 	wahaService := services.NewWahaService()
 
+	// Initialize Telegram
+	telegramService := services.NewTelegramService()
+
 	// Create Echo instance
 	e := echo.New()
 
@@ -83,6 +88,7 @@ func main() {
 	// Middleware
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
+	e.Use(authMiddleware.Metrics())
 
 	// Inject services into context
 	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -95,26 +101,40 @@ func main() {
 		}
 	})
 
+	// Prometheus metrics are served on an internal listener, not on the public router
+	go serveMetrics(metricsAddrFromEnv())
+
 	// Static file serving
 	e.Static("/static", "web/static")
 
+	// Register task definitions, needed for requeueing failed tasks from the admin pages
+	tasks.DefineTasks()
+
 	// Initialize PaymentService
-	paymentService := services.NewPaymentService(db, midtransService)
+	paymentService := services.NewPaymentService(db, services.NewPaymentProvidersFromEnv()...)
+	paymentService.OnPayment(tasks.NotifyPaymentReceived)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authClient, db)
 	dashboardHandler := handlers.NewDashboardHandler(db)
 	planHandler := handlers.NewPlanHandler(db, cache)
 	userHandler := handlers.NewUserHandler(db, cache)
-	paymentDueHandler := handlers.NewPaymentDueHandler(db, cache, midtransService, paymentService)
-	userPrefHandler := handlers.NewUserPreferenceHandler(db)
+	paymentDueHandler := handlers.NewPaymentDueHandler(db, cache, paymentService)
+	userPrefHandler := handlers.NewUserPreferenceHandler(db, telegramService)
+	deadLetterHandler := handlers.NewDeadLetterHandler(db)
+	taskHandler := handlers.NewTaskHandler(db)
+	notificationTemplateHandler := handlers.NewNotificationTemplateHandler(db)
+	notificationDeliveryHandler := handlers.NewNotificationDeliveryHandler(db)
+	wahaWebhookHandler := handlers.NewWahaWebhookHandler(db)
+	telegramWebhookHandler := handlers.NewTelegramWebhookHandler(db)
+	whatsappSessionHandler := handlers.NewWhatsappSessionHandler(db, wahaService)
 
 	// Public routes
 	e.GET("/login", authHandler.LoginPage)
 	e.POST("/auth/login", authHandler.HandleLogin)
 	e.POST("/auth/logout", authHandler.HandleLogout)
 
-	publicHandler := handlers.NewPublicHandler(db, cache, midtransService, paymentService)
+	publicHandler := handlers.NewPublicHandler(db, cache, paymentService)
 	e.GET("/p/:uuid", publicHandler.ShowPaymentDue)
 	e.POST("/p/:uuid/initiate", publicHandler.InitiatePayment)
 	e.GET("/p/:uuid/active-session", publicHandler.CheckActiveSession)
@@ -147,17 +167,59 @@ func main() {
 	// User Preference (HTMX)
 	protected.GET("/users/:id/preference", userPrefHandler.GetUserPreference)
 	protected.PUT("/users/:id/preference", userPrefHandler.UpdateUserPreference)
+	protected.POST("/users/:id/preference/telegram-link", userPrefHandler.LinkTelegram)
+	protected.GET("/users/:id/notifications", notificationDeliveryHandler.UserDeliveries)
 
 	// Payment dues routes
 	protected.GET("/payment-dues", paymentDueHandler.ListPaymentDues)
+	protected.GET("/payment-dues/:id/reminders", paymentDueHandler.GetRemindersPopup)
+	protected.GET("/payment-dues/:id/notifications", notificationDeliveryHandler.PaymentDueDeliveries)
 	protected.POST("/payments/initiate/:id", paymentDueHandler.InitiatePayment)
 	protected.GET("/api/payments/:id/active-session", paymentDueHandler.CheckActiveSession)
 	protected.GET("/payments/:id/status", paymentDueHandler.CheckPaymentStatus)
 	protected.POST("/payments/:id/mark-complete", paymentDueHandler.HandleMarkAsComplete)
 
+	// Admin routes
+	admin := protected.Group("")
+	admin.Use(authMiddleware.RequireAdmin())
+
+	// Scheduled task routes
+	admin.GET("/admin/tasks", taskHandler.ListTasks)
+	admin.GET("/admin/tasks/:id", taskHandler.ShowTask)
+	admin.GET("/admin/tasks/:id/history", taskHandler.TaskHistory)
+	admin.POST("/admin/tasks/:id/run-now", taskHandler.RunTaskNow)
+	admin.POST("/admin/tasks/:id/disable", taskHandler.DisableTask)
+	admin.POST("/admin/tasks/:id/enable", taskHandler.EnableTask)
+	admin.GET("/admin/tasks/:id/recurrence-popup", taskHandler.GetRecurrencePopup)
+	admin.POST("/admin/tasks/:id/recurrence", taskHandler.UpdateRecurrence)
+
+	// Failed task (dead-letter) routes
+	admin.GET("/admin/dead-letters", deadLetterHandler.ListDeadLetters)
+	admin.GET("/api/admin/dead-letters", deadLetterHandler.ListDeadLettersJSON)
+	admin.POST("/admin/dead-letters/:id/requeue", deadLetterHandler.RequeueDeadLetter)
+	admin.GET("/admin/dead-letters/:id/arguments-popup", deadLetterHandler.GetArgumentsPopup)
+	admin.POST("/admin/dead-letters/:id/arguments", deadLetterHandler.UpdateArguments)
+	admin.POST("/admin/dead-letters/:id/discard", deadLetterHandler.DiscardDeadLetter)
+
+	// Notification template routes
+	admin.GET("/admin/notification-templates", notificationTemplateHandler.ListTemplates)
+	admin.POST("/admin/notification-templates/preview", notificationTemplateHandler.PreviewTemplate)
+	admin.GET("/admin/notification-templates/:event/:channel", notificationTemplateHandler.EditTemplatePage)
+	admin.POST("/admin/notification-templates/:event/:channel", notificationTemplateHandler.UpdateTemplate)
+	admin.POST("/admin/notification-templates/:event/:channel/reset", notificationTemplateHandler.ResetTemplate)
+
+	// WhatsApp session routes
+	admin.GET("/admin/whatsapp", whatsappSessionHandler.SessionPage)
+	admin.GET("/admin/whatsapp/status", whatsappSessionHandler.SessionStatus)
+	admin.GET("/admin/whatsapp/qr", whatsappSessionHandler.LoginQR)
+	admin.POST("/admin/whatsapp/restart", whatsappSessionHandler.RestartSession)
+	admin.POST("/admin/whatsapp/logout", whatsappSessionHandler.LogoutSession)
+
 	// Webhook does not need auth protection, so it should be outside 'protected' group or explicitly allowed
 	// However, we usually put it under public routes
-	e.POST("/payments/callback/midtrans", paymentDueHandler.MidtransCallback)
+	e.POST("/payments/callback/:provider", paymentDueHandler.PaymentCallback)
+	e.POST("/webhooks/waha", wahaWebhookHandler.Webhook)
+	e.POST("/webhooks/telegram", telegramWebhookHandler.Webhook)
 
 	// Redirect root to dashboard (or login if not authenticated)
 	e.GET("/", func(c echo.Context) error {
@@ -173,3 +235,29 @@ func main() {
 	log.Printf("Server starting on port %s", port)
 	e.Logger.Fatal(e.Start(":" + port))
 }
+
+// metricsAddrFromEnv reads METRICS_ADDR, falling back to DefaultMetricsAddr.
+// Setting it to "off" disables the metrics listener.
+func metricsAddrFromEnv() string {
+	if val := os.Getenv("METRICS_ADDR"); val != "" {
+		return val
+	}
+	return DefaultMetricsAddr
+}
+
+// serveMetrics runs the /metrics listener, kept apart from the public port so that it is only
+// reachable from inside the deployment
+func serveMetrics(addr string) {
+	if addr == "off" {
+		log.Println("Metrics listener disabled")
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+
+	log.Printf("Serving metrics on %s/metrics", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Metrics listener stopped: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
 	"patungan_app_echo/internal/services"
@@ -36,10 +37,10 @@ func main() {
 
 	log.Printf("Sending message to %s: %s", chatId, *msg)
 
-	err := service.SendMessage(chatId, *msg)
+	messageID, err := service.SendMessage(context.Background(), chatId, *msg)
 	if err != nil {
 		log.Fatalf("Failed to send message: %v", err)
 	}
 
-	log.Println("Message sent successfully!")
+	log.Printf("Message sent successfully! (id %s)", messageID)
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"patungan_app_echo/internal/models"
+)
+
+// Reconnect backoff bounds for the task listener
+const (
+	listenerMinBackoff = 1 * time.Second
+	listenerMaxBackoff = 30 * time.Second
+)
+
+// listenForDueTasks keeps a dedicated connection LISTENing on the scheduled task channel
+// and signals wake for every notification. When the connection drops it reconnects with
+// backoff and signals wake once more, since notifications sent meanwhile are lost.
+func listenForDueTasks(ctx context.Context, databaseURL string, wake chan<- struct{}) {
+	backoff := listenerMinBackoff
+
+	for ctx.Err() == nil {
+		err := listen(ctx, databaseURL, wake, func() { backoff = listenerMinBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Printf("Task listener disconnected: %v. Reconnecting in %s", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+
+		backoff *= 2
+		if backoff > listenerMaxBackoff {
+			backoff = listenerMaxBackoff
+		}
+	}
+}
+
+// listen runs a single LISTEN session until the connection fails or ctx is canceled
+func listen(ctx context.Context, databaseURL string, wake chan<- struct{}, onConnected func()) error {
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	channel := pgx.Identifier{models.ScheduledTaskNotifyChannel}.Sanitize()
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+
+	log.Printf("Listening for due tasks on channel %s", models.ScheduledTaskNotifyChannel)
+	onConnected()
+	signalWake(wake)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		log.Printf("Received due task notification (task ID: %s)", notification.Payload)
+		signalWake(wake)
+	}
+}
+
+// signalWake requests a processing run without blocking. Signals sent while
+// a run is already pending are merged into it.
+func signalWake(wake chan<- struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
@@ -2,7 +2,10 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
@@ -11,7 +14,9 @@ import (
 
 	"github.com/joho/godotenv"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"patungan_app_echo/internal/metrics"
 	"patungan_app_echo/internal/models"
 	"patungan_app_echo/internal/services"
 	"patungan_app_echo/internal/tasks"
@@ -19,6 +24,26 @@ import (
 
 const MaxConcurrentTasks = 10
 
+// DefaultMisfireThreshold is how late a recurring occurrence may run before it counts as missed
+// and the task's misfire policy applies. It must be comfortably above the polling interval.
+const DefaultMisfireThreshold = 5 * time.Minute
+
+// DefaultLeaseDuration is how long a claimed task stays reserved for a worker
+// before other workers may pick it up again. The lease is renewed while the task runs.
+const DefaultLeaseDuration = 5 * time.Minute
+
+// DefaultMetricsAddr is where the worker serves Prometheus metrics unless WORKER_METRICS_ADDR is set
+const DefaultMetricsAddr = ":9091"
+
+// Worker holds the identity and settings of this worker process
+type Worker struct {
+	db               *gorm.DB
+	id               string
+	leaseDuration    time.Duration
+	misfireThreshold time.Duration
+	retryPolicy      tasks.RetryPolicy
+}
+
 func main() {
 	// Load environment variables
 	if err := godotenv.Load(); err != nil {
@@ -36,14 +61,40 @@ func main() {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 
+	// Initialize Redis, shared by the workers to rate limit WhatsApp messages
+	var cache *services.RedisCache
+	if redisURL := os.Getenv("REDIS_URL"); redisURL != "" {
+		cache, err = services.NewRedisCache(redisURL)
+		if err != nil {
+			log.Printf("Warning: Redis initialization failed: %v", err)
+		}
+	} else {
+		log.Println("Warning: REDIS_URL not set")
+	}
+
 	// Initialize Task Registry
 	tasks.Initialize()
 	tasks.DefineTasks()
+	if err := tasks.EnsureSystemTasks(db); err != nil {
+		log.Printf("Warning: %v", err)
+	}
 
-	log.Println("Worker started. Waiting for next tick...")
+	retryPolicy := tasks.RetryPolicyFromEnv()
+	worker := &Worker{
+		db:               db,
+		id:               workerID(),
+		leaseDuration:    leaseDurationFromEnv(),
+		misfireThreshold: misfireThresholdFromEnv(),
+		retryPolicy:      retryPolicy,
+	}
 
-	// Create context that cancels on interrupt
-	ctx, cancel := context.WithCancel(context.Background())
+	log.Printf("Worker %s started (lease %s). Waiting for next tick...", worker.id, worker.leaseDuration)
+
+	// Create context that cancels on interrupt, carrying the services the handlers use
+	payments := services.NewPaymentService(db, services.NewPaymentProvidersFromEnv()...)
+	payments.OnPayment(tasks.NotifyPaymentReceived)
+	deps := tasks.Deps{Notifiers: services.NewNotifierRegistryFromEnv(cache), Payments: payments, RetryPolicy: retryPolicy}
+	ctx, cancel := context.WithCancel(tasks.WithDeps(context.Background(), deps))
 	defer cancel()
 
 	// Handle graceful shutdown
@@ -55,66 +106,242 @@ func main() {
 		cancel()
 	}()
 
-	// Ticker for 1 minutes
+	// Expose task metrics for Prometheus
+	metrics.RegisterQueueDepth(db)
+	go serveMetrics(ctx, metricsAddrFromEnv())
+
+	// Wake up as soon as a due task is created, instead of waiting for the next tick
+	wake := make(chan struct{}, 1)
+	go listenForDueTasks(ctx, databaseURL, wake)
+
+	// Ticker for 1 minutes, kept as a fallback for missed notifications and retries
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
 	// Run immediately on start
-	processScheduledTasks(ctx, db)
+	worker.processScheduledTasks(ctx)
 
 	for {
 		select {
 		case <-ticker.C:
-			processScheduledTasks(ctx, db)
+			worker.processScheduledTasks(ctx)
+		case <-wake:
+			worker.processScheduledTasks(ctx)
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func processScheduledTasks(ctx context.Context, db *gorm.DB) {
-	log.Println("Checking for pending tasks...")
+// workerID returns WORKER_ID if set, otherwise a hostname-pid identifier
+func workerID() string {
+	if id := os.Getenv("WORKER_ID"); id != "" {
+		return id
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "worker"
+	}
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}
 
-	var pendingTasks []models.ScheduledTask
-	// status=active & due<=now
-	// Note: Gorm query
-	now := time.Now()
-	if err := db.Where("status = ? AND due <= ?", models.ScheduledTaskStatusActive, now).Find(&pendingTasks).Error; err != nil {
-		log.Printf("Error fetching pending tasks: %v", err)
-		return
+// leaseDurationFromEnv reads WORKER_LEASE_DURATION (e.g. "5m"), falling back to DefaultLeaseDuration
+func leaseDurationFromEnv() time.Duration {
+	if val := os.Getenv("WORKER_LEASE_DURATION"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid WORKER_LEASE_DURATION %q, using default %s", val, DefaultLeaseDuration)
 	}
+	return DefaultLeaseDuration
+}
 
-	if len(pendingTasks) == 0 {
-		log.Println("No pending tasks found.")
+// misfireThresholdFromEnv reads WORKER_MISFIRE_THRESHOLD (e.g. "5m"), falling back to DefaultMisfireThreshold
+func misfireThresholdFromEnv() time.Duration {
+	if val := os.Getenv("WORKER_MISFIRE_THRESHOLD"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("Invalid WORKER_MISFIRE_THRESHOLD %q, using default %s", val, DefaultMisfireThreshold)
+	}
+	return DefaultMisfireThreshold
+}
+
+// metricsAddrFromEnv reads WORKER_METRICS_ADDR, falling back to DefaultMetricsAddr.
+// Setting it to "off" disables the metrics listener.
+func metricsAddrFromEnv() string {
+	if val := os.Getenv("WORKER_METRICS_ADDR"); val != "" {
+		return val
+	}
+	return DefaultMetricsAddr
+}
+
+// serveMetrics runs the /metrics listener until the context is cancelled
+func serveMetrics(ctx context.Context, addr string) {
+	if addr == "off" {
+		log.Println("Worker metrics listener disabled")
 		return
 	}
 
-	log.Printf("Found %d pending tasks. Processing with concurrency limit of %d", len(pendingTasks), MaxConcurrentTasks)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	server := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	log.Printf("Serving worker metrics on %s/metrics", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Worker metrics listener stopped: %v", err)
+	}
+}
+
+func (w *Worker) processScheduledTasks(ctx context.Context) {
+	log.Println("Checking for pending tasks...")
 
 	var wg sync.WaitGroup
 	sem := make(chan struct{}, MaxConcurrentTasks)
+	processed := 0
 
-	for _, task := range pendingTasks {
+	for {
 		// Check context cancellation
 		if ctx.Err() != nil {
 			break
 		}
 
-		wg.Add(1)
 		sem <- struct{}{} // Acquire semaphore
 
+		// Claim one task at a time so that a task is only leased once a slot is free
+		task, err := w.claimNextTask()
+		if err != nil {
+			<-sem
+			log.Printf("Error claiming pending task: %v", err)
+			break
+		}
+		if task == nil {
+			<-sem
+			break
+		}
+
+		processed++
+		wg.Add(1)
+
 		go func(t models.ScheduledTask) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release semaphore
-			executeTask(ctx, db, t, 1)
-		}(task)
+			w.runClaimedTask(ctx, t)
+		}(*task)
 	}
 
 	wg.Wait()
-	log.Println("Finished processing batch.")
+
+	if processed == 0 {
+		log.Println("No pending tasks found.")
+		return
+	}
+	log.Printf("Finished processing batch of %d tasks.", processed)
 }
 
-func executeTask(ctx context.Context, db *gorm.DB, task models.ScheduledTask, curAttempt int) {
+// claimNextTask locks the oldest due task that is not leased by another worker and
+// takes a lease on it. Rows locked by concurrent workers are skipped, so each task is
+// handed to exactly one worker. Returns nil when there is nothing to claim.
+func (w *Worker) claimNextTask() (*models.ScheduledTask, error) {
+	var claimed *models.ScheduledTask
+
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+
+		// status=active & due<=now & (no lease or lease expired)
+		var task models.ScheduledTask
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND due <= ?", models.ScheduledTaskStatusActive, now).
+			Where("lease_until IS NULL OR lease_until < ?", now).
+			Order("due asc").
+			Limit(1).
+			Find(&task).Error
+		if err != nil {
+			return err
+		}
+		if task.ID == 0 {
+			return nil
+		}
+
+		if task.ClaimedBy != nil {
+			log.Printf("Reclaiming task %s (ID: %d) from %s, lease expired at %v", task.TaskName, task.ID, *task.ClaimedBy, task.LeaseUntil)
+		}
+
+		leaseUntil := now.Add(w.leaseDuration)
+		if err := tx.Model(&task).Updates(map[string]interface{}{
+			"claimed_by":  w.id,
+			"lease_until": leaseUntil,
+		}).Error; err != nil {
+			return err
+		}
+
+		task.ClaimedBy = &w.id
+		task.LeaseUntil = &leaseUntil
+		claimed = &task
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// runClaimedTask executes a claimed task while periodically renewing its lease
+func (w *Worker) runClaimedTask(ctx context.Context, task models.ScheduledTask) {
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		ticker := time.NewTicker(w.leaseDuration / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				w.renewLease(task.ID)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	w.executeTask(ctx, task)
+}
+
+// renewLease extends the lease of a task still owned by this worker
+func (w *Worker) renewLease(taskID uint) {
+	result := w.db.Model(&models.ScheduledTask{}).
+		Where("id = ? AND claimed_by = ?", taskID, w.id).
+		Update("lease_until", time.Now().Add(w.leaseDuration))
+	if result.Error != nil {
+		log.Printf("Failed to renew lease for task %d: %v", taskID, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("Lease for task %d is no longer held by %s", taskID, w.id)
+	}
+}
+
+// releaseTask writes the final task state and drops the lease, as long as
+// the lease still belongs to this worker
+func (w *Worker) releaseTask(task models.ScheduledTask, updates map[string]interface{}) {
+	updates["claimed_by"] = nil
+	updates["lease_until"] = nil
+
+	result := w.db.Model(&models.ScheduledTask{}).
+		Where("id = ? AND claimed_by = ?", task.ID, w.id).
+		Updates(updates)
+	if result.Error != nil {
+		log.Printf("Failed to update task %s (ID: %d): %v", task.TaskName, task.ID, result.Error)
+	} else if result.RowsAffected == 0 {
+		log.Printf("Task %s (ID: %d) was reclaimed by another worker, discarding result", task.TaskName, task.ID)
+	}
+}
+
+func (w *Worker) executeTask(ctx context.Context, task models.ScheduledTask) {
+	db := w.db
 	log.Printf("Processing task: %s (ID: %d)", task.TaskName, task.ID)
 
 	// Retrieve data
@@ -126,6 +353,9 @@ func executeTask(ctx context.Context, db *gorm.DB, task models.ScheduledTask, cu
 	}
 	task.Arguments["max_attempt"] = task.MaxAttempt
 
+	// Attempt counter is tracked on the task row, so retries survive worker restarts
+	curAttempt := task.Attempt + 1
+
 	// Find task handle
 	handler, found := tasks.GetHandler(task.TaskName)
 	if !found {
@@ -133,22 +363,24 @@ func executeTask(ctx context.Context, db *gorm.DB, task models.ScheduledTask, cu
 
 		// Mark as failed
 		updates := map[string]interface{}{
-			"status": models.ScheduledTaskStatusFailure,
+			"status":  models.ScheduledTaskStatusFailure,
+			"attempt": curAttempt,
 		}
 
 		// Update LastRun
 		now := time.Now()
 		updates["last_run"] = &now
 
-		db.Model(&task).Updates(updates)
+		w.releaseTask(task, updates)
+		metrics.ObserveTask(task.TaskName, models.ScheduledTaskHistoryStatusHandlerNotFound, 0)
 
 		// Log history
 		history := models.ScheduledTaskHistory{
 			ScheduledTaskID: task.ID,
 			TaskName:        task.TaskName,
 			RunAt:           now,
-			Status:          "handler_not_found",
-			AttemptNumber:   1, // Start at 1?
+			Status:          models.ScheduledTaskHistoryStatusHandlerNotFound,
+			AttemptNumber:   curAttempt,
 			Arguments:       task.Arguments,
 			Result:          map[string]interface{}{"error": "Handler not found"},
 		}
@@ -156,25 +388,55 @@ func executeTask(ctx context.Context, db *gorm.DB, task models.ScheduledTask, cu
 		return
 	}
 
-	// Execute task
+	// Apply the misfire policy when a recurring task is picked up late
+	misfire := task.ResolveMisfire(time.Now(), w.misfireThreshold)
+	if len(misfire.Skipped) > 0 {
+		log.Printf("Task %s (ID: %d) missed %d occurrence(s), skipping them per %s policy", task.TaskName, task.ID, len(misfire.Skipped), task.MisfirePolicy)
+	}
+	if !misfire.Run {
+		w.skipMissedOccurrences(task, misfire)
+		return
+	}
+	if !misfire.Occurrence.Equal(task.OccurrenceDue()) {
+		occurrence := misfire.Occurrence
+		task.OriginalDue = &occurrence
+	}
+
+	// Execute task with the deadline declared for it in the registry
+	timeout := tasks.GetTimeout(task.TaskName)
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
 	startTime := time.Now()
-	result, err := handler(ctx, db, task)
+	result, err := handler(runCtx, db.WithContext(runCtx), task)
+	timedOut := errors.Is(runCtx.Err(), context.DeadlineExceeded)
+	cancel()
 	duration := time.Since(startTime)
 	runtimeMs := int(duration.Milliseconds())
 
 	status := ""
 	var resultData map[string]interface{}
-	if err != nil {
-		status = "failure"
+	if err != nil && timedOut {
+		status = models.ScheduledTaskHistoryStatusTimeout
+		resultData = map[string]interface{}{"error": fmt.Sprintf("timed out after %s: %v", timeout, err)}
+		log.Printf("Task %s timed out after %s: %v", task.TaskName, timeout, err)
+	} else if err != nil {
+		status = models.ScheduledTaskHistoryStatusFailure
 		resultData = map[string]interface{}{"error": err.Error()}
 		log.Printf("Task %s failed: %v", task.TaskName, err)
 	} else {
-		status = "success"
+		status = models.ScheduledTaskHistoryStatusSuccess
 		resultData = result
 		log.Printf("Task %s completed successfully.", task.TaskName)
 	}
 
-	// Create History
+	metrics.ObserveTask(task.TaskName, status, duration)
+
+	if len(misfire.Skipped) > 0 {
+		if resultData == nil {
+			resultData = make(map[string]interface{})
+		}
+		resultData["skipped_occurrences"] = misfire.Skipped
+	}
+
 	history := models.ScheduledTaskHistory{
 		ScheduledTaskID: task.ID,
 		TaskName:        task.TaskName,
@@ -185,28 +447,39 @@ func executeTask(ctx context.Context, db *gorm.DB, task models.ScheduledTask, cu
 		Arguments:       task.Arguments,
 		Result:          resultData,
 	}
-	db.Create(&history)
 
 	// Update ScheduledTask
 	taskUpdates := map[string]interface{}{
 		"last_run": &startTime,
 	}
 
-	if status != "success" {
+	if status != models.ScheduledTaskHistoryStatusSuccess {
 		if curAttempt < task.MaxAttempt {
-			log.Printf("Task %s failed (Attempt %d/%d). Retrying...", task.TaskName, curAttempt, task.MaxAttempt)
-			executeTask(ctx, db, task, curAttempt+1)
-			return
+			// Reschedule the attempt instead of retrying right away, releasing the worker slot
+			nextRetry := time.Now().Add(w.retryPolicy.Delay(curAttempt))
+			log.Printf("Task %s failed (Attempt %d/%d). Retrying at %s", task.TaskName, curAttempt, task.MaxAttempt, nextRetry.Format(time.RFC3339))
+
+			occurrence := task.OccurrenceDue()
+			taskUpdates["status"] = models.ScheduledTaskStatusActive
+			taskUpdates["attempt"] = curAttempt
+			taskUpdates["due"] = nextRetry
+			taskUpdates["original_due"] = &occurrence
+			history.NextRetryAt = &nextRetry
+		} else {
+			taskUpdates["status"] = models.ScheduledTaskStatusFailure
+			taskUpdates["attempt"] = curAttempt
 		}
-		taskUpdates["status"] = models.ScheduledTaskStatusFailure
 	} else {
+		taskUpdates["attempt"] = 0
+		taskUpdates["original_due"] = nil
+
 		switch task.TaskType {
 		case models.ScheduledTaskTypeOneTime:
 			taskUpdates["status"] = models.ScheduledTaskStatusDone
 		case models.ScheduledTaskTypeRecurring:
-			nextDue := task.NextDue()
-			// check if the next due is a future date, to avoid the task from being executed repeatedly
-			isNextDueFuture := nextDue.After(task.Due)
+			nextDue := task.NextDueAfterRun()
+			// check if the next due is after the occurrence that just ran, to avoid the task from being executed repeatedly
+			isNextDueFuture := nextDue.After(task.OccurrenceDue())
 			if isNextDueFuture {
 				taskUpdates["status"] = models.ScheduledTaskStatusActive
 				taskUpdates["due"] = nextDue
@@ -216,5 +489,37 @@ func executeTask(ctx context.Context, db *gorm.DB, task models.ScheduledTask, cu
 		}
 	}
 
-	db.Model(&task).Updates(taskUpdates)
+	// Create History, using the worker's db since the task context may already be done
+	db.Create(&history)
+
+	w.releaseTask(task, taskUpdates)
+}
+
+// skipMissedOccurrences moves a recurring task past occurrences its misfire policy does not run
+func (w *Worker) skipMissedOccurrences(task models.ScheduledTask, misfire models.MisfireDecision) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"original_due": nil,
+	}
+	if misfire.NextDue.IsZero() {
+		updates["status"] = models.ScheduledTaskStatusDone
+	} else {
+		updates["due"] = misfire.NextDue
+	}
+
+	history := models.ScheduledTaskHistory{
+		ScheduledTaskID: task.ID,
+		TaskName:        task.TaskName,
+		RunAt:           now,
+		Status:          models.ScheduledTaskHistoryStatusSkipped,
+		Arguments:       task.Arguments,
+		Result: map[string]interface{}{
+			"skipped_occurrences": misfire.Skipped,
+			"next_due":            misfire.NextDue,
+		},
+	}
+	w.db.Create(&history)
+	metrics.ObserveTask(task.TaskName, models.ScheduledTaskHistoryStatusSkipped, 0)
+
+	w.releaseTask(task, updates)
 }
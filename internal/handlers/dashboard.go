@@ -36,8 +36,10 @@ func (h *DashboardHandler) Dashboard(c echo.Context) error {
 	// Stats variables
 	var totalActivePlans int64
 	var pendingDuesCount int64
-	var pendingAmount float64
-	var paidAmount float64
+	var pendingAmount models.Money
+	var overdueDuesCount int64
+	var overdueAmount models.Money
+	var paidAmount models.Money
 	var upcomingDues []models.PaymentDue
 
 	// Logic based on role
@@ -49,12 +51,18 @@ func (h *DashboardHandler) Dashboard(c echo.Context) error {
 		// 2. Payment Stats (Global)
 		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusPending).Count(&pendingDuesCount)
 
-		var pendingResult struct{ Total float64 }
-		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusPending).Select("COALESCE(SUM(calculated_pay_amount), 0) as total").Scan(&pendingResult)
+		var pendingResult struct{ Total models.Money }
+		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusPending).Select("COALESCE(SUM(calculated_pay_amount), 0)::bigint as total").Scan(&pendingResult)
 		pendingAmount = pendingResult.Total
 
-		var paidResult struct{ Total float64 }
-		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusPaid).Select("COALESCE(SUM(calculated_pay_amount), 0) as total").Scan(&paidResult)
+		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusOverdue).Count(&overdueDuesCount)
+
+		var overdueResult struct{ Total models.Money }
+		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusOverdue).Select("COALESCE(SUM(calculated_pay_amount + late_fee), 0)::bigint as total").Scan(&overdueResult)
+		overdueAmount = overdueResult.Total
+
+		var paidResult struct{ Total models.Money }
+		h.db.Model(&models.PaymentDue{}).Where("payment_status = ?", models.PaymentStatusPaid).Select("COALESCE(SUM(calculated_pay_amount + late_fee), 0)::bigint as total").Scan(&paidResult)
 		paidAmount = paidResult.Total
 
 		// 3. Upcoming Dues (Global)
@@ -77,12 +85,18 @@ func (h *DashboardHandler) Dashboard(c echo.Context) error {
 		// 2. Payment Stats (My Dues)
 		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusPending).Count(&pendingDuesCount)
 
-		var pendingResult struct{ Total float64 }
-		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusPending).Select("COALESCE(SUM(calculated_pay_amount), 0) as total").Scan(&pendingResult)
+		var pendingResult struct{ Total models.Money }
+		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusPending).Select("COALESCE(SUM(calculated_pay_amount), 0)::bigint as total").Scan(&pendingResult)
 		pendingAmount = pendingResult.Total
 
-		var paidResult struct{ Total float64 }
-		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusPaid).Select("COALESCE(SUM(calculated_pay_amount), 0) as total").Scan(&paidResult)
+		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusOverdue).Count(&overdueDuesCount)
+
+		var overdueResult struct{ Total models.Money }
+		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusOverdue).Select("COALESCE(SUM(calculated_pay_amount + late_fee), 0)::bigint as total").Scan(&overdueResult)
+		overdueAmount = overdueResult.Total
+
+		var paidResult struct{ Total models.Money }
+		h.db.Model(&models.PaymentDue{}).Where("user_id = ? AND payment_status = ?", userID, models.PaymentStatusPaid).Select("COALESCE(SUM(calculated_pay_amount + late_fee), 0)::bigint as total").Scan(&paidResult)
 		paidAmount = paidResult.Total
 
 		// 3. Upcoming Dues (My Dues)
@@ -110,6 +124,8 @@ func (h *DashboardHandler) Dashboard(c echo.Context) error {
 		TotalActivePlans: int(totalActivePlans),
 		PendingDuesCount: int(pendingDuesCount),
 		PendingAmount:    pendingAmount,
+		OverdueDuesCount: int(overdueDuesCount),
+		OverdueAmount:    overdueAmount,
 		PaidAmount:       paidAmount,
 		UpcomingDues:     upcomingDues,
 	}
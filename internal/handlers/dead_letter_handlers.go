@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/tasks"
+	"patungan_app_echo/web/templates/pages"
+	"patungan_app_echo/web/templates/shared"
+)
+
+type DeadLetterHandler struct {
+	db *gorm.DB
+}
+
+func NewDeadLetterHandler(db *gorm.DB) *DeadLetterHandler {
+	return &DeadLetterHandler{db: db}
+}
+
+// loadDeadLetters fetches all failed tasks together with their latest history entry
+func (h *DeadLetterHandler) loadDeadLetters() ([]pages.DeadLetter, error) {
+	var failedTasks []models.ScheduledTask
+	if err := h.db.Where("status = ?", models.ScheduledTaskStatusFailure).
+		Order("updated_at desc").
+		Find(&failedTasks).Error; err != nil {
+		return nil, err
+	}
+
+	deadLetters := make([]pages.DeadLetter, 0, len(failedTasks))
+	if len(failedTasks) == 0 {
+		return deadLetters, nil
+	}
+
+	taskIDs := make([]uint, len(failedTasks))
+	for i, task := range failedTasks {
+		taskIDs[i] = task.ID
+	}
+
+	// Newest history first, so the first entry seen per task is its last run
+	var histories []models.ScheduledTaskHistory
+	if err := h.db.Where("scheduled_task_id IN ?", taskIDs).
+		Order("run_at desc").
+		Find(&histories).Error; err != nil {
+		return nil, err
+	}
+
+	lastHistory := make(map[uint]models.ScheduledTaskHistory)
+	for _, history := range histories {
+		if _, ok := lastHistory[history.ScheduledTaskID]; !ok {
+			lastHistory[history.ScheduledTaskID] = history
+		}
+	}
+
+	for _, task := range failedTasks {
+		deadLetter := pages.DeadLetter{Task: task}
+		if history, ok := lastHistory[task.ID]; ok {
+			deadLetter.LastRunAt = &history.RunAt
+			deadLetter.LastStatus = history.Status
+			if errMsg, ok := history.Result["error"].(string); ok {
+				deadLetter.LastError = errMsg
+			}
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	return deadLetters, nil
+}
+
+// findFailedTask loads a failed task by the :id route parameter
+func (h *DeadLetterHandler) findFailedTask(c echo.Context) (*models.ScheduledTask, error) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	var task models.ScheduledTask
+	if err := h.db.Where("status = ?", models.ScheduledTaskStatusFailure).First(&task, taskID).Error; err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Failed task not found")
+	}
+	return &task, nil
+}
+
+// ListDeadLetters renders the list of failed scheduled tasks
+func (h *DeadLetterHandler) ListDeadLetters(c echo.Context) error {
+	deadLetters, err := h.loadDeadLetters()
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch failed tasks")
+	}
+
+	// Breadcrumbs: Home > Failed Tasks
+	breadcrumbs := []shared.Breadcrumb{
+		{Title: "Home", URL: "/"},
+		{Title: "Failed Tasks", URL: ""},
+	}
+
+	props := pages.DeadLettersProps{
+		Title:       "Failed Tasks",
+		ActiveNav:   "dead-letters",
+		Breadcrumbs: breadcrumbs,
+		UserEmail:   getStringFromContext(c, "userEmail"),
+		UserUID:     getStringFromContext(c, "userUID"),
+		DeadLetters: deadLetters,
+	}
+
+	return pages.DeadLetters(props).Render(c.Request().Context(), c.Response())
+}
+
+// ListDeadLettersJSON returns the failed scheduled tasks as JSON
+func (h *DeadLetterHandler) ListDeadLettersJSON(c echo.Context) error {
+	deadLetters, err := h.loadDeadLetters()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch failed tasks",
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"count": len(deadLetters),
+		"tasks": deadLetters,
+	})
+}
+
+// RequeueDeadLetter puts a failed task back in the queue
+func (h *DeadLetterHandler) RequeueDeadLetter(c echo.Context) error {
+	task, err := h.findFailedTask(c)
+	if err != nil {
+		return err
+	}
+
+	if err := tasks.RequeueTask(h.db, task); err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Failed to requeue task: "+err.Error())
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/dead-letters")
+}
+
+// GetArgumentsPopup renders the popup for editing the arguments of a failed task
+func (h *DeadLetterHandler) GetArgumentsPopup(c echo.Context) error {
+	task, err := h.findFailedTask(c)
+	if err != nil {
+		return err
+	}
+
+	argsJSON, err := json.MarshalIndent(task.Arguments, "", "  ")
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to encode task arguments")
+	}
+
+	return pages.DeadLetterArgumentsPopup(*task, string(argsJSON)).Render(c.Request().Context(), c.Response())
+}
+
+// UpdateArguments replaces the arguments of a failed task, leaving it in the dead-letter list
+func (h *DeadLetterHandler) UpdateArguments(c echo.Context) error {
+	task, err := h.findFailedTask(c)
+	if err != nil {
+		return err
+	}
+
+	var arguments map[string]interface{}
+	if err := json.Unmarshal([]byte(c.FormValue("arguments")), &arguments); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Arguments must be a valid JSON object")
+	}
+	if err := tasks.ValidateArguments(task.TaskName, arguments); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid arguments: "+err.Error())
+	}
+
+	task.Arguments = arguments
+	if err := h.db.Model(task).Select("arguments").Updates(task).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update task arguments")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/dead-letters")
+}
+
+// DiscardDeadLetter removes a failed task and detaches it from its plan
+func (h *DeadLetterHandler) DiscardDeadLetter(c echo.Context) error {
+	task, err := h.findFailedTask(c)
+	if err != nil {
+		return err
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Plan{}).
+			Where("scheduled_task_id = ?", task.ID).
+			Update("scheduled_task_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(task).Error
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to discard task: "+err.Error())
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/dead-letters")
+}
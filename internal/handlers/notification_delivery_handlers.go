@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/web/templates/pages"
+)
+
+// deliveryHistoryLimit bounds how many deliveries a history popup shows
+const deliveryHistoryLimit = 50
+
+type NotificationDeliveryHandler struct {
+	db *gorm.DB
+}
+
+func NewNotificationDeliveryHandler(db *gorm.DB) *NotificationDeliveryHandler {
+	return &NotificationDeliveryHandler{db: db}
+}
+
+// deliveriesQuery loads deliveries newest first, with their attempts
+func (h *NotificationDeliveryHandler) deliveriesQuery() *gorm.DB {
+	return h.db.Preload("AttemptLog", func(db *gorm.DB) *gorm.DB {
+		return db.Order("attempt ASC")
+	}).
+		Order("created_at DESC").
+		Limit(deliveryHistoryLimit)
+}
+
+// canView reports whether the current user may see the notifications of userID
+func canView(c echo.Context, userID uint) bool {
+	userType, _ := c.Get("userType").(models.UserType)
+	return userType == models.UserTypeAdmin || userID == getUintFromContext(c, "userID")
+}
+
+// PaymentDueDeliveries renders the notifications sent for a payment due
+func (h *NotificationDeliveryHandler) PaymentDueDeliveries(c echo.Context) error {
+	dueID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment due ID")
+	}
+
+	var due models.PaymentDue
+	if err := h.db.Preload("Plan").Preload("User").First(&due, dueID).Error; err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Payment due not found")
+	}
+	if !canView(c, due.UserID) {
+		return echo.NewHTTPError(http.StatusForbidden, "You cannot view this payment due")
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := h.deliveriesQuery().Where("payment_due_id = ?", due.ID).Find(&deliveries).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch notifications")
+	}
+
+	subtitle := fmt.Sprintf("%s · %s · Due %s", due.Plan.Name, due.User.Name, due.LocalDueDate().Format("02 Jan 2006"))
+	return pages.NotificationDeliveriesPopup("Notifications", subtitle, deliveries, due.Plan.Location()).Render(c.Request().Context(), c.Response())
+}
+
+// UserDeliveries renders the notifications sent to a user
+func (h *NotificationDeliveryHandler) UserDeliveries(c echo.Context) error {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid user ID")
+	}
+	if !canView(c, uint(userID)) {
+		return echo.NewHTTPError(http.StatusForbidden, "You cannot view this user's notifications")
+	}
+
+	var user models.User
+	if err := h.db.First(&user, userID).Error; err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := h.deliveriesQuery().Preload("PaymentDue.Plan").Where("user_id = ?", user.ID).Find(&deliveries).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch notifications")
+	}
+
+	return pages.NotificationDeliveriesPopup("Notifications", user.Name, deliveries, models.AppLocation()).Render(c.Request().Context(), c.Response())
+}
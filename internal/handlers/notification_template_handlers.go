@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/web/templates/pages"
+	"patungan_app_echo/web/templates/shared"
+)
+
+type NotificationTemplateHandler struct {
+	db *gorm.DB
+}
+
+func NewNotificationTemplateHandler(db *gorm.DB) *NotificationTemplateHandler {
+	return &NotificationTemplateHandler{db: db}
+}
+
+// templateKey reads the event and channel from the route parameters
+func templateKey(c echo.Context) (models.NotificationEvent, models.NotificationChannel, error) {
+	event := models.NotificationEvent(c.Param("event"))
+	channel := models.NotificationChannel(c.Param("channel"))
+	if !event.IsValid() || !models.IsTemplateChannel(channel) {
+		return "", "", echo.NewHTTPError(http.StatusNotFound, "Notification template not found")
+	}
+	return event, channel, nil
+}
+
+// findTemplate returns the stored template of an event and channel, or the default when none is stored
+func (h *NotificationTemplateHandler) findTemplate(event models.NotificationEvent, channel models.NotificationChannel) (models.NotificationTemplate, bool, error) {
+	var tmpl models.NotificationTemplate
+	err := h.db.Where("event = ? AND channel = ?", event, channel).First(&tmpl).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.DefaultNotificationTemplate(event, channel), false, nil
+	}
+	if err != nil {
+		return tmpl, false, err
+	}
+	return tmpl, true, nil
+}
+
+// ListTemplates renders every event and channel with the template currently in use
+func (h *NotificationTemplateHandler) ListTemplates(c echo.Context) error {
+	var stored []models.NotificationTemplate
+	if err := h.db.Find(&stored).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch notification templates")
+	}
+
+	custom := make(map[string]models.NotificationTemplate, len(stored))
+	for _, tmpl := range stored {
+		custom[string(tmpl.Event)+"/"+string(tmpl.Channel)] = tmpl
+	}
+
+	var rows []pages.NotificationTemplateRow
+	for _, event := range models.NotificationEvents {
+		for _, channel := range models.TemplateChannels {
+			if tmpl, ok := custom[string(event)+"/"+string(channel)]; ok {
+				rows = append(rows, pages.NotificationTemplateRow{Template: tmpl, Custom: true})
+				continue
+			}
+			rows = append(rows, pages.NotificationTemplateRow{Template: models.DefaultNotificationTemplate(event, channel)})
+		}
+	}
+
+	breadcrumbs := []shared.Breadcrumb{
+		{Title: "Home", URL: "/"},
+		{Title: "Notification Templates", URL: ""},
+	}
+
+	props := pages.NotificationTemplatesProps{
+		Title:       "Notification Templates",
+		ActiveNav:   "notification-templates",
+		Breadcrumbs: breadcrumbs,
+		UserEmail:   getStringFromContext(c, "userEmail"),
+		UserUID:     getStringFromContext(c, "userUID"),
+		Rows:        rows,
+	}
+
+	return pages.NotificationTemplates(props).Render(c.Request().Context(), c.Response())
+}
+
+// renderForm renders the template editor
+func (h *NotificationTemplateHandler) renderForm(c echo.Context, tmpl models.NotificationTemplate, custom bool, errMsg string) error {
+	breadcrumbs := []shared.Breadcrumb{
+		{Title: "Home", URL: "/"},
+		{Title: "Notification Templates", URL: "/admin/notification-templates"},
+		{Title: pages.NotificationEventLabel(tmpl.Event), URL: ""},
+	}
+
+	props := pages.NotificationTemplateFormProps{
+		Title:        "Edit Notification Template",
+		ActiveNav:    "notification-templates",
+		Breadcrumbs:  breadcrumbs,
+		UserEmail:    getStringFromContext(c, "userEmail"),
+		UserUID:      getStringFromContext(c, "userUID"),
+		Template:     tmpl,
+		Custom:       custom,
+		ErrorMessage: errMsg,
+	}
+
+	return pages.NotificationTemplateForm(props).Render(c.Request().Context(), c.Response())
+}
+
+// EditTemplatePage renders the editor of an event and channel
+func (h *NotificationTemplateHandler) EditTemplatePage(c echo.Context) error {
+	event, channel, err := templateKey(c)
+	if err != nil {
+		return err
+	}
+
+	tmpl, custom, err := h.findTemplate(event, channel)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch notification template")
+	}
+
+	return h.renderForm(c, tmpl, custom, "")
+}
+
+// UpdateTemplate stores the template of an event and channel
+func (h *NotificationTemplateHandler) UpdateTemplate(c echo.Context) error {
+	event, channel, err := templateKey(c)
+	if err != nil {
+		return err
+	}
+
+	tmpl, custom, err := h.findTemplate(event, channel)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch notification template")
+	}
+
+	tmpl.Subject = strings.TrimSpace(c.FormValue("subject"))
+	tmpl.Body = c.FormValue("body")
+	if err := tmpl.Validate(); err != nil {
+		return h.renderForm(c, tmpl, custom, err.Error())
+	}
+
+	if err := h.db.Save(&tmpl).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to save notification template: "+err.Error())
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/notification-templates")
+}
+
+// ResetTemplate deletes the stored template so the built-in default is used again
+func (h *NotificationTemplateHandler) ResetTemplate(c echo.Context) error {
+	event, channel, err := templateKey(c)
+	if err != nil {
+		return err
+	}
+
+	if err := h.db.Where("event = ? AND channel = ?", event, channel).Delete(&models.NotificationTemplate{}).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to reset notification template")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/notification-templates")
+}
+
+// PreviewTemplate renders the submitted template with sample data for HTMX
+func (h *NotificationTemplateHandler) PreviewTemplate(c echo.Context) error {
+	channel := models.NotificationChannel(c.FormValue("channel"))
+	if !models.IsTemplateChannel(channel) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown channel")
+	}
+
+	tmpl := models.NotificationTemplate{
+		Event:   models.NotificationEvent(c.FormValue("event")),
+		Channel: channel,
+		Subject: c.FormValue("subject"),
+		Body:    c.FormValue("body"),
+	}
+
+	subject, body, err := tmpl.Render(models.SampleNotificationData)
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+
+	return pages.NotificationTemplatePreview(channel, subject, body, errMsg).Render(c.Request().Context(), c.Response())
+}
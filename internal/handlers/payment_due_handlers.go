@@ -2,32 +2,32 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 
+	"patungan_app_echo/internal/metrics"
 	"patungan_app_echo/internal/models"
 	"patungan_app_echo/internal/services"
 	"patungan_app_echo/web/templates/pages"
 	"patungan_app_echo/web/templates/shared"
 
 	"github.com/midtrans/midtrans-go"
-	"github.com/midtrans/midtrans-go/snap"
 )
 
 type PaymentDueHandler struct {
 	db             *gorm.DB
 	cache          *services.RedisCache
-	midtransClient *services.MidtransService
 	paymentService *services.PaymentService
 }
 
-func NewPaymentDueHandler(db *gorm.DB, cache *services.RedisCache, midtransClient *services.MidtransService, paymentService *services.PaymentService) *PaymentDueHandler {
-	return &PaymentDueHandler{db: db, cache: cache, midtransClient: midtransClient, paymentService: paymentService}
+func NewPaymentDueHandler(db *gorm.DB, cache *services.RedisCache, paymentService *services.PaymentService) *PaymentDueHandler {
+	return &PaymentDueHandler{db: db, cache: cache, paymentService: paymentService}
 }
 
 // ListPaymentDues renders the list of payment dues with filtering and sorting
@@ -253,9 +253,9 @@ func (h *PaymentDueHandler) InitiatePayment(c echo.Context) error {
 	forceNew := c.QueryParam("force_new") == "true"
 	callbackURL := getEnv("APP_URL", "http://localhost:8080") + "/payment-dues"
 
-	result, err := h.paymentService.InitiatePayment(&due, forceNew, callbackURL)
+	result, err := h.paymentService.InitiatePayment(c.Request().Context(), &due, forceNew, callbackURL)
 	if err != nil {
-		if err.Error() == "payment already made" {
+		if errors.Is(err, services.ErrPaymentAlreadyMade) {
 			// Specific handling for already paid
 			return c.JSON(http.StatusBadRequest, map[string]string{"message": "Payment is already made. Please check the status."})
 		}
@@ -263,6 +263,7 @@ func (h *PaymentDueHandler) InitiatePayment(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
+		"gateway":      result.Gateway,
 		"token":        result.Token,
 		"redirect_url": result.RedirectURL,
 	})
@@ -276,19 +277,17 @@ func (h *PaymentDueHandler) CheckActiveSession(c echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment due ID")
 	}
 
-	session, err := h.paymentService.CheckActiveSession(uint(dueID))
+	checkout, err := h.paymentService.ActiveCheckout(uint(dueID))
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to check session: "+err.Error())
 	}
 
-	if session != nil {
-		var midtransResp snap.Response
-		if err := json.Unmarshal(session.ResponseMetadata, &midtransResp); err == nil {
-			return c.JSON(http.StatusOK, map[string]interface{}{
-				"active": true,
-				"token":  midtransResp.Token,
-			})
-		}
+	if checkout != nil {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"active":       true,
+			"token":        checkout.Token,
+			"redirect_url": checkout.RedirectURL,
+		})
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -296,53 +295,45 @@ func (h *PaymentDueHandler) CheckActiveSession(c echo.Context) error {
 	})
 }
 
-// MidtransCallback handles validation of Midtrans notifications
-func (h *PaymentDueHandler) MidtransCallback(c echo.Context) error {
-	var notificationPayload map[string]interface{}
-	if err := c.Bind(&notificationPayload); err != nil {
+// PaymentCallback handles the payment notifications of a gateway, verified by its provider
+func (h *PaymentDueHandler) PaymentCallback(c echo.Context) error {
+	gateway := models.PaymentGateway(c.Param("provider"))
+	if _, ok := h.paymentService.Provider(gateway); !ok {
+		// Not labeled by the path, so that unknown paths cannot grow the metric
+		metrics.ObservePaymentCallback("unknown", metrics.CallbackOutcomeUnknownProvider)
+		return echo.NewHTTPError(http.StatusNotFound, "Unknown payment provider")
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil || !json.Valid(body) {
+		metrics.ObservePaymentCallback(gateway, metrics.CallbackOutcomeInvalidPayload)
 		return echo.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload")
 	}
 
 	// Log to PaymentCallbackHistory
-	payloadBytes, _ := json.Marshal(notificationPayload)
 	history := models.PaymentCallbackHistory{
-		PaymentGateway: models.PaymentGatewayMidtrans,
-		Metadata:       payloadBytes,
+		PaymentGateway: gateway,
+		Metadata:       body,
 	}
 	h.db.Create(&history)
 
-	// Extract necessary fields
-	orderID, _ := notificationPayload["order_id"].(string)
-	transactionStatus, _ := notificationPayload["transaction_status"].(string)
-	fraudStatus, _ := notificationPayload["fraud_status"].(string)
-	signatureKey, _ := notificationPayload["signature_key"].(string)
-	statusCode, _ := notificationPayload["status_code"].(string)
-	grossAmount, _ := notificationPayload["gross_amount"].(string)
-
-	if !h.midtransClient.VerifySignature(signatureKey, orderID, statusCode, grossAmount) {
-		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid Signature")
-	}
-
-	// Parse Order ID to get PaymentDueID
-	// Format: payment-due-{id}-{timestamp}
-	parts := strings.Split(orderID, "-")
-	if len(parts) < 3 {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid order ID format")
-	}
-	dueIDStr := parts[2] // payment (0), due (1), ID (2), timestamp (3)
-	dueID, err := strconv.ParseUint(dueIDStr, 10, 32)
-	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment due ID in order ID")
-	}
-
-	// Fetch PaymentDue
-	var due models.PaymentDue
-	if err := h.db.First(&due, dueID).Error; err != nil {
-		return echo.NewHTTPError(http.StatusNotFound, "Payment due not found")
+	if err := h.paymentService.HandleWebhook(gateway, body, c.Request().Header); err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebhookSignature):
+			metrics.ObservePaymentCallback(gateway, metrics.CallbackOutcomeInvalidSignature)
+			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid Signature")
+		case errors.Is(err, services.ErrInvalidWebhookPayload):
+			metrics.ObservePaymentCallback(gateway, metrics.CallbackOutcomeInvalidPayload)
+			return echo.NewHTTPError(http.StatusBadRequest, "Invalid payload")
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			metrics.ObservePaymentCallback(gateway, metrics.CallbackOutcomeNotFound)
+			return echo.NewHTTPError(http.StatusNotFound, "Payment due not found")
+		default:
+			metrics.ObservePaymentCallback(gateway, metrics.CallbackOutcomeError)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to process callback")
+		}
 	}
-
-	// Handle status
-	h.paymentService.HandleTransactionStatus(&due, orderID, transactionStatus, fraudStatus, notificationPayload["payment_type"].(string), notificationPayload["gross_amount"].(string))
+	metrics.ObservePaymentCallback(gateway, metrics.CallbackOutcomeProcessed)
 
 	return c.JSON(http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -369,11 +360,13 @@ func (h *PaymentDueHandler) HandleMarkAsComplete(c echo.Context) error {
 
 	// 3. Mark as Paid using helper
 	if due.PaymentStatus != models.PaymentStatusPaid {
-		h.paymentService.MarkAsPaid(&due, map[string]interface{}{
+		if err := h.paymentService.MarkAsPaid(&due, map[string]interface{}{
 			"payment_type":    "manual",
-			"gross_amount":    due.CalculatedPayAmount,
+			"gross_amount":    due.PayableAmount(),
 			"payment_gateway": string(models.PaymentGatewayManual), // Pass as string, helper converts back
-		})
+		}); err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to mark payment due as paid")
+		}
 	}
 
 	// 4. Return updated component
@@ -393,7 +386,7 @@ func (h *PaymentDueHandler) HandleMarkAsComplete(c echo.Context) error {
 	return pages.PaymentDueItem(due, displayMode, currentUserID, models.UserTypeAdmin).Render(c.Request().Context(), c.Response())
 }
 
-// CheckPaymentStatus checks the status of a payment due with Midtrans
+// CheckPaymentStatus checks the status of a payment due with its payment gateway
 func (h *PaymentDueHandler) CheckPaymentStatus(c echo.Context) error {
 	id := c.Param("id")
 	dueID, err := strconv.ParseUint(id, 10, 32)
@@ -408,7 +401,7 @@ func (h *PaymentDueHandler) CheckPaymentStatus(c echo.Context) error {
 	currentUserID := getUintFromContext(c, "userID")
 
 	// Use PaymentService to verify status
-	if err := h.paymentService.VerifyPaymentStatus(uint(dueID)); err != nil {
+	if err := h.paymentService.VerifyPaymentStatus(c.Request().Context(), uint(dueID)); err != nil {
 		// Log error but proceed to show current state, or return error?
 		// For now, let's proceed so user sees something even if check failed (e.g. network issue)
 		// Or maybe return error to let user know check failed.
@@ -431,6 +424,33 @@ func (h *PaymentDueHandler) CheckPaymentStatus(c echo.Context) error {
 	return pages.PaymentDueItem(due, displayMode, currentUserID, currentUserType).Render(c.Request().Context(), c.Response())
 }
 
+// GetRemindersPopup renders the reminder history of a payment due
+func (h *PaymentDueHandler) GetRemindersPopup(c echo.Context) error {
+	id := c.Param("id")
+	dueID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid payment due ID")
+	}
+
+	var due models.PaymentDue
+	err = h.db.Preload("Plan").Preload("User").
+		Preload("Reminders", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Reminders.ScheduledTask").
+		First(&due, dueID).Error
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Payment due not found")
+	}
+
+	userType, _ := c.Get("userType").(models.UserType)
+	if userType != models.UserTypeAdmin && due.UserID != getUintFromContext(c, "userID") {
+		return echo.NewHTTPError(http.StatusForbidden, "You cannot view this payment due")
+	}
+
+	return pages.PaymentDueRemindersPopup(due).Render(c.Request().Context(), c.Response())
+}
+
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
 		return value
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
@@ -157,7 +159,7 @@ func (h *PlanHandler) CreatePlanPage(c echo.Context) error {
 		UserEmail:          getStringFromContext(c, "userEmail"),
 		UserUID:            getStringFromContext(c, "userUID"),
 		IsEdit:             false,
-		FormattedStartDate: time.Now().Format("2006-01-02"),
+		FormattedStartDate: time.Now().In(models.AppLocation()).Format("2006-01-02"),
 		AllUsers:           users,
 
 		ParticipantPortions: make(map[uint]int),
@@ -179,18 +181,17 @@ func (h *PlanHandler) StorePlan(c echo.Context) error {
 		}
 
 		priceStr := c.FormValue("total_price")
-		totalPrice, _ := strconv.ParseFloat(priceStr, 64)
+		totalPrice, _ := models.ParseMoney(priceStr)
 
 		participantPortions := make(map[uint]int)
-		for _, idStr := range c.Request().Form["participants"] {
-			if uid, err := strconv.ParseUint(idStr, 10, 32); err == nil {
-				portionStr := c.FormValue("portion_" + idStr)
-				portion := 1
-				if p, err := strconv.Atoi(portionStr); err == nil && p > 0 {
-					portion = p
-				}
-				participantPortions[uint(uid)] = portion
+		participantFixedAmounts := make(map[uint]models.Money)
+		participantPercentages := make(map[uint]float64)
+		for _, p := range participantsFromForm(c, 0) {
+			participantPortions[p.UserID] = max(1, p.Portion)
+			if p.FixedAmount != nil {
+				participantFixedAmounts[p.UserID] = *p.FixedAmount
 			}
+			participantPercentages[p.UserID] = p.Percentage
 		}
 
 		recurringInterval := c.FormValue("recurring_interval")
@@ -204,26 +205,33 @@ func (h *PlanHandler) StorePlan(c echo.Context) error {
 			TotalPrice:              totalPrice,
 			PaymentType:             c.FormValue("payment_type"),
 			RecurringInterval:       recurringIntervalPtr,
+			Timezone:                c.FormValue("timezone"),
+			SplitMode:               models.SplitMode(c.FormValue("split_mode")),
 			AllowInvitationAfterPay: c.FormValue("allow_invitation") == "on",
 		}
+		plan.GracePeriodDays, plan.LateFee, _ = overdueSettingsFromForm(c)
+		plan.PaymentTermDays, plan.Reminders, _ = reminderSettingsFromForm(c)
+		plan.GroupDigest, _ = groupDigestFromForm(c)
 
 		startDateStr := c.FormValue("plan_start_date")
 		if startDateStr == "" {
-			startDateStr = time.Now().Format("2006-01-02")
+			startDateStr = time.Now().In(plan.Location()).Format("2006-01-02")
 		}
 
 		props := pages.PlanFormProps{
-			Title:               "Create New Plan",
-			ActiveNav:           "plans",
-			Breadcrumbs:         breadcrumbs,
-			UserEmail:           getStringFromContext(c, "userEmail"),
-			UserUID:             getStringFromContext(c, "userUID"),
-			IsEdit:              false,
-			Plan:                plan,
-			FormattedStartDate:  startDateStr,
-			AllUsers:            users,
-			ParticipantPortions: participantPortions,
-			ErrorMessage:        errMsg,
+			Title:                   "Create New Plan",
+			ActiveNav:               "plans",
+			Breadcrumbs:             breadcrumbs,
+			UserEmail:               getStringFromContext(c, "userEmail"),
+			UserUID:                 getStringFromContext(c, "userUID"),
+			IsEdit:                  false,
+			Plan:                    plan,
+			FormattedStartDate:      startDateStr,
+			AllUsers:                users,
+			ParticipantPortions:     participantPortions,
+			ParticipantFixedAmounts: participantFixedAmounts,
+			ParticipantPercentages:  participantPercentages,
+			ErrorMessage:            errMsg,
 		}
 
 		return pages.PlanForm(props).Render(c.Request().Context(), c.Response())
@@ -235,7 +243,7 @@ func (h *PlanHandler) StorePlan(c echo.Context) error {
 	}
 
 	priceStr := c.FormValue("total_price")
-	totalPrice, err := strconv.ParseFloat(priceStr, 64)
+	totalPrice, err := models.ParseMoney(priceStr)
 	if err != nil || totalPrice < 1000 {
 		return renderError("Total price must be at least 1000")
 	}
@@ -245,10 +253,38 @@ func (h *PlanHandler) StorePlan(c echo.Context) error {
 		return renderError("At least one participant is required")
 	}
 
+	timezone := c.FormValue("timezone")
+	if timezone != "" && !models.IsValidTimezone(timezone) {
+		return renderError("Unknown timezone " + timezone)
+	}
+
+	splitMode := models.SplitMode(c.FormValue("split_mode"))
+	if splitMode == "" {
+		splitMode = models.SplitModeShares
+	}
+	if !splitMode.IsValid() {
+		return renderError("Unknown split mode " + string(splitMode))
+	}
+
+	gracePeriodDays, lateFee, err := overdueSettingsFromForm(c)
+	if err != nil {
+		return renderError(err.Error())
+	}
+
+	paymentTermDays, reminders, err := reminderSettingsFromForm(c)
+	if err != nil {
+		return renderError(err.Error())
+	}
+
+	groupDigest, err := groupDigestFromForm(c)
+	if err != nil {
+		return renderError(err.Error())
+	}
+
 	startDateStr := c.FormValue("plan_start_date")
 
-	// Basic parsing - assuming standard date format YYYY-MM-DD from HTML date input
-	planStartDate, err := timeFromForm(startDateStr)
+	// Basic parsing - assuming standard date format YYYY-MM-DD from HTML date input, at midnight in the plan timezone
+	planStartDate, err := timeFromForm(startDateStr, models.LoadLocation(timezone))
 	if err != nil {
 		// handle error appropriately, maybe re-render form with error
 	}
@@ -271,39 +307,25 @@ func (h *PlanHandler) StorePlan(c echo.Context) error {
 		PaymentType:             paymentType,
 		RecurringInterval:       recurringIntervalPtr,
 		PlanStartDate:           planStartDate,
+		Timezone:                timezone,
+		SplitMode:               splitMode,
+		GracePeriodDays:         gracePeriodDays,
+		LateFee:                 lateFee,
+		PaymentTermDays:         paymentTermDays,
+		Reminders:               reminders,
+		GroupDigest:             groupDigest,
 		AllowInvitationAfterPay: c.FormValue("allow_invitation") == "on",
+		Participants:            participantsFromForm(c, 0),
 	}
 
-	if err := h.db.Create(&plan).Error; err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create plan")
+	// Make sure the split covers the total price before anyone gets billed
+	if _, err := plan.SplitAmounts(); err != nil {
+		return renderError("Invalid split: " + err.Error())
 	}
 
-	// Handle participants
-	participantIDs := c.Request().Form["participants"]
-	if len(participantIDs) > 0 {
-		var participants []models.PlanParticipant
-		for _, idStr := range participantIDs {
-			uid, err := strconv.ParseUint(idStr, 10, 32)
-			if err == nil {
-				// Parse portion specific for this user
-				portionStr := c.FormValue("portion_" + idStr)
-				portion := 1
-				if p, err := strconv.Atoi(portionStr); err == nil && p >= 0 {
-					portion = p
-				}
-
-				participants = append(participants, models.PlanParticipant{
-					UserID:  uint(uid),
-					Portion: portion,
-				})
-			}
-		}
-		if len(participants) > 0 {
-			plan.Participants = participants
-			if err := h.db.Save(&plan).Error; err != nil {
-				// log error but continue
-			}
-		}
+	// Participants are created together with the plan
+	if err := h.db.Create(&plan).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create plan")
 	}
 
 	return c.Redirect(http.StatusSeeOther, "/plans")
@@ -324,8 +346,14 @@ func (h *PlanHandler) EditPlanPage(c echo.Context) error {
 	// Build selected participants map
 	// Map from UserID -> Portion
 	participantPortions := make(map[uint]int)
+	participantFixedAmounts := make(map[uint]models.Money)
+	participantPercentages := make(map[uint]float64)
 	for _, p := range plan.Participants {
 		participantPortions[p.UserID] = p.Portion
+		if p.FixedAmount != nil {
+			participantFixedAmounts[p.UserID] = *p.FixedAmount
+		}
+		participantPercentages[p.UserID] = p.Percentage
 	}
 
 	// Breadcrumbs: Home > Plans > Edit
@@ -343,10 +371,12 @@ func (h *PlanHandler) EditPlanPage(c echo.Context) error {
 		UserUID:            getStringFromContext(c, "userUID"),
 		IsEdit:             true,
 		Plan:               plan,
-		FormattedStartDate: plan.PlanStartDate.Format("2006-01-02"),
+		FormattedStartDate: plan.PlanStartDate.In(plan.Location()).Format("2006-01-02"),
 		AllUsers:           allUsers,
 
-		ParticipantPortions: participantPortions,
+		ParticipantPortions:     participantPortions,
+		ParticipantFixedAmounts: participantFixedAmounts,
+		ParticipantPercentages:  participantPercentages,
 	}
 
 	return pages.PlanForm(props).Render(c.Request().Context(), c.Response())
@@ -381,7 +411,7 @@ func (h *PlanHandler) UpdatePlan(c echo.Context) error {
 
 	plan.Name = c.FormValue("name")
 	priceStr := c.FormValue("total_price")
-	plan.TotalPrice, _ = strconv.ParseFloat(priceStr, 64)
+	plan.TotalPrice, _ = models.ParseMoney(priceStr)
 	plan.PaymentType = c.FormValue("payment_type")
 	recurringInterval := c.FormValue("recurring_interval")
 
@@ -391,38 +421,74 @@ func (h *PlanHandler) UpdatePlan(c echo.Context) error {
 		plan.RecurringInterval = nil
 	}
 
+	previousTimezone := plan.Timezone
+	if timezone := c.FormValue("timezone"); timezone != "" {
+		if !models.IsValidTimezone(timezone) {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown timezone "+timezone)
+		}
+		plan.Timezone = timezone
+	}
+
 	startDateStr := c.FormValue("plan_start_date")
 	if startDateStr != "" {
-		plan.PlanStartDate, _ = timeFromForm(startDateStr)
+		plan.PlanStartDate, _ = timeFromForm(startDateStr, plan.Location())
 	}
 
 	plan.AllowInvitationAfterPay = c.FormValue("allow_invitation") == "on"
 
-	if err := h.db.Save(&plan).Error; err != nil {
-		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update plan: "+err.Error())
+	if splitMode := models.SplitMode(c.FormValue("split_mode")); splitMode != "" {
+		if !splitMode.IsValid() {
+			return echo.NewHTTPError(http.StatusBadRequest, "Unknown split mode "+string(splitMode))
+		}
+		plan.SplitMode = splitMode
 	}
 
-	// Handle participants update
-	// Handle participants update
-	participantIDs := c.Request().Form["participants"]
-	var newParticipants []models.PlanParticipant
+	gracePeriodDays, lateFee, err := overdueSettingsFromForm(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	plan.GracePeriodDays = gracePeriodDays
+	plan.LateFee = lateFee
 
-	for _, idStr := range participantIDs {
-		uid, err := strconv.ParseUint(idStr, 10, 32)
-		if err == nil {
-			// Parse portion specific for this user
-			portionStr := c.FormValue("portion_" + idStr)
-			portion := 1
-			if p, err := strconv.Atoi(portionStr); err == nil && p >= 0 {
-				portion = p
-			}
+	paymentTermDays, reminders, err := reminderSettingsFromForm(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	plan.PaymentTermDays = paymentTermDays
+	plan.Reminders = reminders
+
+	groupDigest, err := groupDigestFromForm(c)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	plan.GroupDigest = groupDigest
 
-			newParticipants = append(newParticipants, models.PlanParticipant{
-				PlanID:  plan.ID,
-				UserID:  uint(uid),
-				Portion: portion,
-			})
+	// Validate the new split before saving anything
+	newParticipants := participantsFromForm(c, plan.ID)
+	splitCheck := plan
+	splitCheck.Participants = newParticipants
+	if _, err := splitCheck.SplitAmounts(); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid split: "+err.Error())
+	}
+
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&plan).Error; err != nil {
+			return err
 		}
+		if plan.Timezone == previousTimezone || plan.ScheduledTaskID == nil {
+			return nil
+		}
+
+		// The schedule of the plan is expanded in the plan timezone, move its task along
+		var task models.ScheduledTask
+		if err := tx.First(&task, *plan.ScheduledTaskID).Error; err != nil {
+			return err
+		}
+		task.MoveToTimezone(plan.Timezone)
+		return tx.Model(&task).Select("timezone", "due", "original_due").Updates(&task).Error
+	})
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update plan: "+err.Error())
 	}
 
 	// Transaction to replace participants
@@ -461,7 +527,7 @@ func (h *PlanHandler) DeletePlan(c echo.Context) error {
 
 		// 2. Handle payment dues
 		var paymentDues []models.PaymentDue
-		tx.Preload("UserPayment").Where("plan_id = ?", planID).Find(&paymentDues)
+		tx.Preload("Plan").Preload("User").Preload("UserPayment").Where("plan_id = ?", planID).Find(&paymentDues)
 
 		for _, due := range paymentDues {
 			if due.PaymentStatus == models.PaymentStatusPaid {
@@ -479,6 +545,9 @@ func (h *PlanHandler) DeletePlan(c echo.Context) error {
 					if err := tx.Create(&refund).Error; err != nil {
 						return err
 					}
+					if err := tasks.NotifyRefund(tx, due, refund); err != nil {
+						return err
+					}
 				}
 			}
 			// Cancel the payment due regardless
@@ -505,9 +574,9 @@ func (h *PlanHandler) DeletePlan(c echo.Context) error {
 	return c.Redirect(http.StatusSeeOther, "/plans")
 }
 
-// Helper to parse date from HTML input type="date"
-func timeFromForm(value string) (time.Time, error) {
-	return time.Parse("2006-01-02", value)
+// Helper to parse date from HTML input type="date" as midnight in the given timezone
+func timeFromForm(value string, loc *time.Location) (time.Time, error) {
+	return time.ParseInLocation("2006-01-02", value, loc)
 }
 
 // GetSchedulePopup renders the schedule popup for a plan
@@ -535,15 +604,14 @@ func (h *PlanHandler) SchedulePlan(c echo.Context) error {
 	}
 
 	taskArgs := tasks.ProcessPlanScheduleArgs{
-		PlanID:            plan.ID,
-		Due:               due,
-		RecurringInterval: plan.RecurringInterval,
+		PlanID: plan.ID,
 	}
 
-	createdTask, err := tasks.ProcessPlanScheduleTask.CreateTask(taskArgs)
+	createdTask, err := tasks.ProcessPlanScheduleTask.NewTask(taskArgs, due, plan.RecurringInterval)
 	if err != nil {
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to create task args")
 	}
+	createdTask.Timezone = plan.Timezone
 
 	if plan.ScheduledTaskID == nil {
 		// Create new task
@@ -566,11 +634,22 @@ func (h *PlanHandler) SchedulePlan(c echo.Context) error {
 		task.Status = createdTask.Status
 		task.TaskType = createdTask.TaskType
 		task.MaxAttempt = createdTask.MaxAttempt
+		task.MisfirePolicy = createdTask.MisfirePolicy
+		task.Timezone = createdTask.Timezone
 		task.LastRun = nil // Reset last run
+		task.Attempt = 0
+		task.OriginalDue = nil
 
 		if err := h.db.Save(task).Error; err != nil {
 			return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update scheduled task")
 		}
+
+		// New tasks notify the worker on create, rescheduled ones have to do it themselves
+		if !task.Due.After(time.Now()) {
+			if err := models.NotifyScheduledTaskDue(h.db, task.ID); err != nil {
+				log.Printf("Failed to notify workers about task %d: %v", task.ID, err)
+			}
+		}
 	}
 
 	return c.Redirect(http.StatusSeeOther, "/plans")
@@ -593,3 +672,142 @@ func (h *PlanHandler) DisableSchedulePlan(c echo.Context) error {
 
 	return c.Redirect(http.StatusSeeOther, "/plans")
 }
+
+// participantsFromForm reads the selected participants and their split settings from the plan form.
+// A blank fixed amount leaves the participant splitting the rest by portion in mixed mode.
+func participantsFromForm(c echo.Context, planID uint) []models.PlanParticipant {
+	var participants []models.PlanParticipant
+	for _, idStr := range c.Request().Form["participants"] {
+		uid, err := strconv.ParseUint(idStr, 10, 32)
+		if err != nil {
+			continue
+		}
+
+		participant := models.PlanParticipant{
+			PlanID:  planID,
+			UserID:  uint(uid),
+			Portion: 1,
+		}
+		if p, err := strconv.Atoi(c.FormValue("portion_" + idStr)); err == nil && p >= 0 {
+			participant.Portion = p
+		}
+		if amount, err := models.ParseMoney(c.FormValue("fixed_amount_" + idStr)); err == nil {
+			participant.FixedAmount = &amount
+		}
+		if pct, err := strconv.ParseFloat(c.FormValue("percentage_"+idStr), 64); err == nil {
+			participant.Percentage = pct
+		}
+
+		participants = append(participants, participant)
+	}
+	return participants
+}
+
+// overdueSettingsFromForm reads the grace period and late fee rule from the plan form
+func overdueSettingsFromForm(c echo.Context) (int, models.LateFeePolicy, error) {
+	gracePeriodDays := 0
+	if val := c.FormValue("grace_period_days"); val != "" {
+		days, err := strconv.Atoi(val)
+		if err != nil || days < 0 {
+			return 0, models.LateFeePolicy{}, fmt.Errorf("grace period must be a number of days")
+		}
+		gracePeriodDays = days
+	}
+
+	policy := models.LateFeePolicy{
+		Type:       models.LateFeeType(c.FormValue("late_fee_type")),
+		Recurrence: models.LateFeeRecurrence(c.FormValue("late_fee_recurrence")),
+	}
+	if policy.Type == "" {
+		policy.Type = models.LateFeeTypeNone
+	}
+	if policy.Recurrence == "" {
+		policy.Recurrence = models.LateFeeRecurrenceOnce
+	}
+	if val := c.FormValue("late_fee_amount"); val != "" {
+		amount, err := models.ParseMoney(val)
+		if err != nil {
+			return 0, models.LateFeePolicy{}, fmt.Errorf("late fee must be an amount in rupiah")
+		}
+		policy.Amount = amount
+	}
+	if val := c.FormValue("late_fee_percentage"); val != "" {
+		pct, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, models.LateFeePolicy{}, fmt.Errorf("late fee percentage must be a number")
+		}
+		policy.Percentage = pct
+	}
+	if val := c.FormValue("late_fee_period_days"); val != "" {
+		days, err := strconv.Atoi(val)
+		if err != nil {
+			return 0, models.LateFeePolicy{}, fmt.Errorf("late fee period must be a number of days")
+		}
+		policy.PeriodDays = days
+	}
+
+	if !policy.IsValid() {
+		return 0, models.LateFeePolicy{}, fmt.Errorf("invalid late fee settings")
+	}
+	return gracePeriodDays, policy, nil
+}
+
+// reminderSettingsFromForm reads the payment term and reminder schedule from the plan form
+func reminderSettingsFromForm(c echo.Context) (int, models.ReminderPolicy, error) {
+	days := func(field, label string) (int, error) {
+		val := c.FormValue(field)
+		if val == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%s must be a number of days", label)
+		}
+		return n, nil
+	}
+
+	paymentTermDays, err := days("payment_term_days", "payment term")
+	if err != nil {
+		return 0, models.ReminderPolicy{}, err
+	}
+
+	policy := models.ReminderPolicy{
+		Enabled:   c.FormValue("reminder_enabled") == "on",
+		OnDueDate: c.FormValue("reminder_on_due_date") == "on",
+	}
+	if policy.DaysBeforeDue, err = days("reminder_days_before_due", "days before due"); err != nil {
+		return 0, models.ReminderPolicy{}, err
+	}
+	if policy.OverdueIntervalDays, err = days("reminder_overdue_interval_days", "overdue reminder interval"); err != nil {
+		return 0, models.ReminderPolicy{}, err
+	}
+	if val := c.FormValue("reminder_max_overdue_reminders"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil || n < 0 {
+			return 0, models.ReminderPolicy{}, fmt.Errorf("number of overdue reminders must be a whole number")
+		}
+		policy.MaxOverdueReminders = n
+	}
+
+	if !policy.IsValid() {
+		return 0, models.ReminderPolicy{}, fmt.Errorf("invalid reminder settings, overdue reminders need an interval")
+	}
+	return paymentTermDays, policy, nil
+}
+
+// groupDigestFromForm reads the WhatsApp group digest settings from the plan form
+func groupDigestFromForm(c echo.Context) (models.GroupDigestPolicy, error) {
+	policy := models.GroupDigestPolicy{GroupID: strings.TrimSpace(c.FormValue("group_digest_group_id"))}
+	if val := c.FormValue("group_digest_interval_days"); val != "" {
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return models.GroupDigestPolicy{}, fmt.Errorf("group digest interval must be a number of days")
+		}
+		policy.IntervalDays = n
+	}
+
+	if !policy.IsValid() {
+		return models.GroupDigestPolicy{}, fmt.Errorf("group digest interval must be a number of days")
+	}
+	return policy, nil
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
 
@@ -17,16 +18,11 @@ import (
 type PublicHandler struct {
 	db             *gorm.DB
 	cache          *services.RedisCache
-	midtransClient *services.MidtransService
 	paymentService *services.PaymentService
 }
 
-func NewPublicHandler(db *gorm.DB, cache *services.RedisCache, midtransClient *services.MidtransService, paymentService *services.PaymentService) *PublicHandler {
-	if midtransClient == nil {
-		// Initialize Midtrans if not provided (fallback)
-		midtransClient = services.NewMidtransService()
-	}
-	return &PublicHandler{db: db, cache: cache, midtransClient: midtransClient, paymentService: paymentService}
+func NewPublicHandler(db *gorm.DB, cache *services.RedisCache, paymentService *services.PaymentService) *PublicHandler {
+	return &PublicHandler{db: db, cache: cache, paymentService: paymentService}
 }
 
 // ShowPaymentDue renders the public payment due page
@@ -71,15 +67,16 @@ func (h *PublicHandler) InitiatePayment(c echo.Context) error {
 	forceNew := c.QueryParam("force_new") == "true"
 	callbackURL := getEnv("APP_URL", "http://localhost:8080") + "/p/" + uuid
 
-	result, err := h.paymentService.InitiatePayment(&due, forceNew, callbackURL)
+	result, err := h.paymentService.InitiatePayment(c.Request().Context(), &due, forceNew, callbackURL)
 	if err != nil {
-		if err.Error() == "payment already made" {
+		if errors.Is(err, services.ErrPaymentAlreadyMade) {
 			return c.JSON(http.StatusBadRequest, map[string]string{"message": "Payment is already made. Please check the status."})
 		}
 		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to initiate payment: "+err.Error())
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
+		"gateway":      result.Gateway,
 		"token":        result.Token,
 		"redirect_url": result.RedirectURL,
 	})
@@ -126,7 +123,7 @@ func (h *PublicHandler) CheckStatus(c echo.Context) error {
 	}
 
 	// Verify status with PaymentService
-	if err := h.paymentService.VerifyPaymentStatus(due.ID); err != nil {
+	if err := h.paymentService.VerifyPaymentStatus(c.Request().Context(), due.ID); err != nil {
 		// Log error but proceed to return current status from DB
 		log.Printf("Failed to verify payment status for due %d: %v", due.ID, err)
 	}
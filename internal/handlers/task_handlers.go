@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/teambition/rrule-go"
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/tasks"
+	"patungan_app_echo/web/templates/pages"
+	"patungan_app_echo/web/templates/shared"
+)
+
+type TaskHandler struct {
+	db *gorm.DB
+}
+
+func NewTaskHandler(db *gorm.DB) *TaskHandler {
+	return &TaskHandler{db: db}
+}
+
+// findTask loads a scheduled task by the :id route parameter
+func (h *TaskHandler) findTask(c echo.Context) (*models.ScheduledTask, error) {
+	taskID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Invalid task ID")
+	}
+
+	var task models.ScheduledTask
+	if err := h.db.First(&task, taskID).Error; err != nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Task not found")
+	}
+	return &task, nil
+}
+
+// ListTasks renders the list of scheduled tasks with pagination and filtering
+func (h *TaskHandler) ListTasks(c echo.Context) error {
+	// Parse query parameters
+	filterStatus := c.QueryParam("filter_status")
+	filterName := c.QueryParam("filter_name")
+	dueFrom := c.QueryParam("due_from")
+	dueTo := c.QueryParam("due_to")
+
+	// Parse pagination
+	pageStr := c.QueryParam("page")
+	page := 1
+	if pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+	pageSize := 20
+
+	// Build base query
+	query := h.db.Model(&models.ScheduledTask{})
+
+	// Apply filters
+	if filterStatus != "" {
+		query = query.Where("status = ?", filterStatus)
+	}
+	if filterName != "" {
+		query = query.Where("task_name = ?", filterName)
+	}
+	if dueFrom != "" {
+		if from, err := timeFromForm(dueFrom, models.AppLocation()); err == nil {
+			query = query.Where("due >= ?", from)
+		}
+	}
+	if dueTo != "" {
+		// The end date is inclusive, so match everything before the next day
+		if to, err := timeFromForm(dueTo, models.AppLocation()); err == nil {
+			query = query.Where("due < ?", to.AddDate(0, 0, 1))
+		}
+	}
+
+	// Get total count
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count tasks")
+	}
+
+	// Calculate pagination
+	totalPages := int((totalCount + int64(pageSize) - 1) / int64(pageSize))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+	offset := (page - 1) * pageSize
+
+	var scheduledTasks []models.ScheduledTask
+	if err := query.Order("due desc").Limit(pageSize).Offset(offset).Find(&scheduledTasks).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch tasks")
+	}
+
+	// Fetch task names for filter dropdown
+	var taskNames []string
+	h.db.Model(&models.ScheduledTask{}).Distinct("task_name").Order("task_name").Pluck("task_name", &taskNames)
+
+	// Breadcrumbs: Home > Tasks
+	breadcrumbs := []shared.Breadcrumb{
+		{Title: "Home", URL: "/"},
+		{Title: "Tasks", URL: ""},
+	}
+
+	props := pages.TasksListProps{
+		Title:        "Scheduled Tasks",
+		ActiveNav:    "tasks",
+		Breadcrumbs:  breadcrumbs,
+		UserEmail:    getStringFromContext(c, "userEmail"),
+		UserUID:      getStringFromContext(c, "userUID"),
+		Tasks:        scheduledTasks,
+		FilterStatus: filterStatus,
+		FilterName:   filterName,
+		DueFrom:      dueFrom,
+		DueTo:        dueTo,
+		CurrentPage:  page,
+		TotalPages:   totalPages,
+		TotalCount:   int(totalCount),
+		PageSize:     pageSize,
+		TaskNames:    taskNames,
+	}
+
+	return pages.TasksList(props).Render(c.Request().Context(), c.Response())
+}
+
+// ShowTask renders the detail page of a scheduled task. Its history is loaded separately by TaskHistory.
+func (h *TaskHandler) ShowTask(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	// Breadcrumbs: Home > Tasks > Task #ID
+	breadcrumbs := []shared.Breadcrumb{
+		{Title: "Home", URL: "/"},
+		{Title: "Tasks", URL: "/admin/tasks"},
+		{Title: "Task #" + strconv.FormatUint(uint64(task.ID), 10), URL: ""},
+	}
+
+	props := pages.TaskDetailProps{
+		Title:       "Task " + task.TaskName,
+		ActiveNav:   "tasks",
+		Breadcrumbs: breadcrumbs,
+		UserEmail:   getStringFromContext(c, "userEmail"),
+		UserUID:     getStringFromContext(c, "userUID"),
+		Task:        *task,
+	}
+
+	return pages.TaskDetail(props).Render(c.Request().Context(), c.Response())
+}
+
+// TaskHistory renders one page of a task's execution history (HTMX partial)
+func (h *TaskHandler) TaskHistory(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	page := 1
+	if p, err := strconv.Atoi(c.QueryParam("page")); err == nil && p > 0 {
+		page = p
+	}
+	pageSize := 10
+
+	query := h.db.Model(&models.ScheduledTaskHistory{}).Where("scheduled_task_id = ?", task.ID)
+
+	var totalCount int64
+	if err := query.Count(&totalCount).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to count task history")
+	}
+
+	totalPages := int((totalCount + int64(pageSize) - 1) / int64(pageSize))
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	var histories []models.ScheduledTaskHistory
+	if err := query.Order("run_at desc").Limit(pageSize).Offset((page - 1) * pageSize).Find(&histories).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to fetch task history")
+	}
+
+	props := pages.TaskHistoryProps{
+		TaskID:      task.ID,
+		Histories:   histories,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+		TotalCount:  int(totalCount),
+	}
+
+	return pages.TaskHistory(props).Render(c.Request().Context(), c.Response())
+}
+
+// RunTaskNow queues a task to be picked up by the worker immediately. Done and disabled tasks are refused.
+func (h *TaskHandler) RunTaskNow(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	if task.Leased(time.Now()) {
+		return echo.NewHTTPError(http.StatusConflict, "Task is currently running")
+	}
+
+	run, err := tasks.RunTaskNow(h.db, task)
+	if errors.Is(err, tasks.ErrTaskNotRunnable) {
+		return echo.NewHTTPError(http.StatusConflict, "Task cannot be run now, "+err.Error())
+	} else if err != nil {
+		return echo.NewHTTPError(http.StatusUnprocessableEntity, "Failed to queue task: "+err.Error())
+	}
+
+	// A recurring task may run as a separate one-time task, show that one
+	return c.Redirect(http.StatusSeeOther, fmt.Sprintf("/admin/tasks/%d", run.ID))
+}
+
+// DisableTask stops a task from being picked up by the worker. A running task is refused, the
+// worker would overwrite the status once the run ends.
+func (h *TaskHandler) DisableTask(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if task.Leased(now) {
+		return echo.NewHTTPError(http.StatusConflict, "Task is currently running")
+	}
+
+	// A worker may claim the task meanwhile, only disable it while no lease is held
+	result := h.db.Model(task).
+		Where("claimed_by IS NULL OR lease_until IS NULL OR lease_until <= ?", now).
+		Update("status", models.ScheduledTaskStatusDisabled)
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to disable task")
+	}
+	if result.RowsAffected == 0 {
+		return echo.NewHTTPError(http.StatusConflict, "Task is currently running")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/tasks/"+c.Param("id"))
+}
+
+// EnableTask reactivates a disabled task. Other tasks are refused: failed tasks are requeued and
+// done tasks do not run again. A recurring task whose due date passed meanwhile keeps it, so the
+// worker handles the missed occurrences by the task's misfire policy when it picks the task up.
+func (h *TaskHandler) EnableTask(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	if task.Status != models.ScheduledTaskStatusDisabled {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Only disabled tasks can be enabled, it is %s", task.Status))
+	}
+
+	result := h.db.Model(task).
+		Where("status = ?", models.ScheduledTaskStatusDisabled).
+		Updates(map[string]interface{}{
+			"status":  models.ScheduledTaskStatusActive,
+			"attempt": 0,
+		})
+	if result.Error != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to enable task")
+	}
+	if result.RowsAffected == 0 {
+		return echo.NewHTTPError(http.StatusConflict, "Only disabled tasks can be enabled")
+	}
+
+	if !task.Due.After(time.Now()) {
+		if err := models.NotifyScheduledTaskDue(h.db, task.ID); err != nil {
+			log.Printf("Failed to notify workers about task %d: %v", task.ID, err)
+		}
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/tasks/"+c.Param("id"))
+}
+
+// GetRecurrencePopup renders the popup for editing a recurring task's RRULE
+func (h *TaskHandler) GetRecurrencePopup(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	return pages.TaskRecurrencePopup(*task).Render(c.Request().Context(), c.Response())
+}
+
+// UpdateRecurrence changes the RRULE of a recurring task and moves its due date to the next occurrence
+func (h *TaskHandler) UpdateRecurrence(c echo.Context) error {
+	task, err := h.findTask(c)
+	if err != nil {
+		return err
+	}
+
+	if task.TaskType != models.ScheduledTaskTypeRecurring {
+		return echo.NewHTTPError(http.StatusBadRequest, "Only recurring tasks have a recurrence rule")
+	}
+
+	interval := strings.TrimSpace(c.FormValue("recurring_interval"))
+	if _, err := rrule.StrToRRule(interval); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid recurrence rule: "+err.Error())
+	}
+
+	task.RecurringInterval = &interval
+	updates := map[string]interface{}{
+		"recurring_interval": interval,
+	}
+	// Pending occurrences follow the new rule, a task waiting for a retry keeps its due date
+	if task.OriginalDue == nil {
+		updates["due"] = task.NextDue()
+	}
+
+	if err := h.db.Model(task).Updates(updates).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to update recurrence rule")
+	}
+
+	return c.Redirect(http.StatusSeeOther, "/admin/tasks/"+c.Param("id"))
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"patungan_app_echo/internal/models"
+)
+
+// newMockDB returns a gorm connection whose statements are checked against the expectations of mock
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+// expectTask expects the lookup of task 4 by findTask
+func expectTask(mock sqlmock.Sqlmock, status models.ScheduledTaskStatus, due time.Time, leaseUntil *time.Time) {
+	rows := sqlmock.NewRows([]string{"id", "task_name", "status", "task_type", "due", "claimed_by", "lease_until"})
+	var claimedBy *string
+	if leaseUntil != nil {
+		worker := "worker-1"
+		claimedBy = &worker
+	}
+	rows.AddRow(4, "process_plan_schedule", status, models.ScheduledTaskTypeRecurring, due, claimedBy, leaseUntil)
+	mock.ExpectQuery(`SELECT \* FROM "scheduled_tasks"`).WillReturnRows(rows)
+}
+
+// taskRequest returns the context of a request for task 4
+func taskRequest() (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPost, "/admin/tasks/4", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("4")
+	return c, rec
+}
+
+// statusOf returns the HTTP status a handler responded with
+func statusOf(err error, rec *httptest.ResponseRecorder) int {
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.Code
+	}
+	return rec.Code
+}
+
+func TestDisableTask(t *testing.T) {
+	now := time.Now()
+	leased := now.Add(time.Minute)
+	expired := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		leaseUntil *time.Time
+		claimed    bool // whether the update finds the task without a lease
+		wantStatus int
+	}{
+		{name: "idle", wantStatus: http.StatusSeeOther},
+		{name: "lease expired", leaseUntil: &expired, wantStatus: http.StatusSeeOther},
+		{name: "running", leaseUntil: &leased, wantStatus: http.StatusConflict},
+		{name: "claimed meanwhile", claimed: true, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectTask(mock, models.ScheduledTaskStatusActive, now, tt.leaseUntil)
+			if tt.wantStatus == http.StatusSeeOther || tt.claimed {
+				affected := int64(1)
+				if tt.claimed {
+					affected = 0
+				}
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "scheduled_tasks" SET "status"=\$1,"updated_at"=\$2 WHERE \(claimed_by IS NULL OR lease_until IS NULL OR lease_until <= \$3\) AND "scheduled_tasks"."deleted_at" IS NULL AND "id" = \$4`).
+					WithArgs(models.ScheduledTaskStatusDisabled, sqlmock.AnyArg(), sqlmock.AnyArg(), 4).
+					WillReturnResult(sqlmock.NewResult(0, affected))
+				mock.ExpectCommit()
+			}
+
+			c, rec := taskRequest()
+			err := NewTaskHandler(db).DisableTask(c)
+			if got := statusOf(err, rec); got != tt.wantStatus {
+				t.Errorf("DisableTask() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestEnableTask(t *testing.T) {
+	past := time.Now().Add(-48 * time.Hour)
+
+	tests := []struct {
+		name       string
+		status     models.ScheduledTaskStatus
+		wantStatus int
+	}{
+		{name: "disabled", status: models.ScheduledTaskStatusDisabled, wantStatus: http.StatusSeeOther},
+		{name: "done", status: models.ScheduledTaskStatusDone, wantStatus: http.StatusConflict},
+		{name: "failed", status: models.ScheduledTaskStatusFailure, wantStatus: http.StatusConflict},
+		{name: "active", status: models.ScheduledTaskStatusActive, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			expectTask(mock, tt.status, past, nil)
+			if tt.wantStatus == http.StatusSeeOther {
+				// The missed due date is kept for the worker to apply the misfire policy
+				mock.ExpectBegin()
+				mock.ExpectExec(`UPDATE "scheduled_tasks" SET "attempt"=\$1,"status"=\$2,"updated_at"=\$3 WHERE status = \$4 AND "scheduled_tasks"."deleted_at" IS NULL AND "id" = \$5`).
+					WithArgs(0, models.ScheduledTaskStatusActive, sqlmock.AnyArg(), models.ScheduledTaskStatusDisabled, 4).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectExec(`SELECT pg_notify`).WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			c, rec := taskRequest()
+			err := NewTaskHandler(db).EnableTask(c)
+			if got := statusOf(err, rec); got != tt.wantStatus {
+				t.Errorf("EnableTask() status = %d, want %d (error %v)", got, tt.wantStatus, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
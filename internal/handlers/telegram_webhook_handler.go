@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+)
+
+type TelegramWebhookHandler struct {
+	db     *gorm.DB
+	secret string
+}
+
+// NewTelegramWebhookHandler reads the webhook secret token from TELEGRAM_WEBHOOK_SECRET
+func NewTelegramWebhookHandler(db *gorm.DB) *TelegramWebhookHandler {
+	return &TelegramWebhookHandler{db: db, secret: os.Getenv("TELEGRAM_WEBHOOK_SECRET")}
+}
+
+// Webhook receives the updates of the Telegram bot and stores the "/start <code>" messages sent
+// from the notification settings, which look up the chat to link by the code. Every update is
+// acknowledged, Telegram would otherwise send it again.
+func (h *TelegramWebhookHandler) Webhook(c echo.Context) error {
+	if h.secret == "" {
+		log.Println("Telegram webhook called but TELEGRAM_WEBHOOK_SECRET is not set")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Webhook not configured")
+	}
+	if !services.VerifyTelegramSecret(c.Request().Header.Get("X-Telegram-Bot-Api-Secret-Token"), h.secret) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid Secret")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBodySize))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read body")
+	}
+	update, err := services.ParseTelegramUpdate(body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload")
+	}
+
+	code, ok := update.StartCode()
+	if !ok {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ignored"})
+	}
+
+	start := models.TelegramStart{
+		UpdateID: update.UpdateID,
+		Code:     code,
+		ChatID:   strconv.FormatInt(update.Message.Chat.ID, 10),
+	}
+	if err := h.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&start).Error; err != nil {
+		log.Printf("Failed to store Telegram update %d: %v", update.UpdateID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to store update")
+	}
+
+	// Codes are only looked up shortly after they are sent
+	h.db.Where("created_at < ?", time.Now().Add(-models.TelegramStartTTL)).Delete(&models.TelegramStart{})
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "stored"})
+}
@@ -1,23 +1,31 @@
 package handlers
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 
 	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
 	"patungan_app_echo/web/templates/pages"
 )
 
 type UserPreferenceHandler struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	telegram *services.TelegramService
 }
 
-func NewUserPreferenceHandler(db *gorm.DB) *UserPreferenceHandler {
-	return &UserPreferenceHandler{DB: db}
+func NewUserPreferenceHandler(db *gorm.DB, telegram *services.TelegramService) *UserPreferenceHandler {
+	return &UserPreferenceHandler{DB: db, telegram: telegram}
 }
 
 // GetUserPreference returns the preference modal content for HTMX
@@ -51,7 +59,19 @@ func (h *UserPreferenceHandler) GetUserPreference(c echo.Context) error {
 	}
 
 	// Render the templ component
-	return pages.UserPreferencePopup(user, pref).Render(c.Request().Context(), c.Response())
+	return pages.UserPreferencePopup(user, pref, h.newTelegramLink(), "").Render(c.Request().Context(), c.Response())
+}
+
+// newTelegramLink returns the deep link a user opens to link their Telegram chat. The code is random
+// per popup, so only whoever opened it can link a chat to the user.
+func (h *UserPreferenceHandler) newTelegramLink() pages.TelegramLinkProps {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	code := hex.EncodeToString(buf)
+	return pages.TelegramLinkProps{
+		Code:     code,
+		StartURL: h.telegram.StartURL(code),
+	}
 }
 
 // UpdateUserPreference handles the form submission
@@ -62,9 +82,10 @@ func (h *UserPreferenceHandler) UpdateUserPreference(c echo.Context) error {
 		return c.String(http.StatusBadRequest, "Invalid user ID")
 	}
 
-	channel := c.FormValue("channel")               // "email" or "whatsapp"
+	channel := c.FormValue("channel")               // "email", "whatsapp" or "telegram"
 	waTarget := c.FormValue("whatsapp_target_type") // "personal" or "group"
 	waGroup := c.FormValue("whatsapp_group_id")
+	telegramChatID := strings.TrimSpace(c.FormValue("telegram_chat_id"))
 
 	// Upsert preference
 	var pref models.UserNotifPreference
@@ -80,6 +101,15 @@ func (h *UserPreferenceHandler) UpdateUserPreference(c echo.Context) error {
 	pref.Channel = models.NotificationChannel(channel)
 	pref.WhatsappTargetType = waTarget
 	pref.WhatsappGroupID = waGroup
+	pref.TelegramChatID = telegramChatID
+
+	if errMsg := validateTelegramChatID(pref); errMsg != "" {
+		var user models.User
+		if err := h.DB.First(&user, userID).Error; err != nil {
+			return c.String(http.StatusNotFound, "User not found")
+		}
+		return pages.UserPreferencePopup(user, pref, h.newTelegramLink(), errMsg).Render(c.Request().Context(), c.Response())
+	}
 
 	if err := h.DB.Save(&pref).Error; err != nil {
 		return c.String(http.StatusInternalServerError, "Failed to save preference")
@@ -88,3 +118,61 @@ func (h *UserPreferenceHandler) UpdateUserPreference(c echo.Context) error {
 	// Return Success Component
 	return pages.UserPreferenceSuccess().Render(c.Request().Context(), c.Response())
 }
+
+// validateTelegramChatID checks the Telegram chat of a preference, returning an error message when it is unusable
+func validateTelegramChatID(pref models.UserNotifPreference) string {
+	if pref.Channel != models.NotificationChannelTelegram {
+		return ""
+	}
+	if pref.TelegramChatID == "" {
+		return "Link your Telegram chat or enter its chat ID"
+	}
+	if _, err := strconv.ParseInt(pref.TelegramChatID, 10, 64); err != nil {
+		return "Telegram chat ID must be a number"
+	}
+	return ""
+}
+
+// LinkTelegram looks for the /start message sent from the link in the preference popup, as stored
+// by the Telegram webhook, and fills in the chat it came from
+func (h *UserPreferenceHandler) LinkTelegram(c echo.Context) error {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "Invalid user ID")
+	}
+	if !canView(c, uint(userID)) {
+		return c.String(http.StatusForbidden, "You cannot link Telegram for this user")
+	}
+
+	var user models.User
+	if err := h.DB.First(&user, userID).Error; err != nil {
+		return c.String(http.StatusNotFound, "User not found")
+	}
+
+	code := c.FormValue("telegram_link_code")
+	chatID := strings.TrimSpace(c.FormValue("telegram_chat_id"))
+	if code == "" {
+		return pages.TelegramChatIDField(chatID, "", "Link code is missing, reopen the settings").Render(c.Request().Context(), c.Response())
+	}
+
+	// The bot webhook stores the /start message sent from the link
+	var start models.TelegramStart
+	err = h.DB.Where("code = ? AND created_at > ?", code, time.Now().Add(-models.TelegramStartTTL)).
+		Order("id desc").
+		First(&start).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return pages.TelegramChatIDField(chatID, "", "No message from the link yet. Open the bot, press Start, then try again.").Render(c.Request().Context(), c.Response())
+	} else if err != nil {
+		log.Printf("Failed to look up Telegram chat for user %d: %v", userID, err)
+		return pages.TelegramChatIDField(chatID, "", "Could not look up the chat, try again later").Render(c.Request().Context(), c.Response())
+	}
+	foundChatID := start.ChatID
+
+	// Confirm the link in the chat, the chat ID is filled in either way
+	text := fmt.Sprintf("Halo %s, notifikasi Patungan akan dikirim ke chat ini setelah pengaturan disimpan.", user.Name)
+	if _, err := h.telegram.SendMessage(c.Request().Context(), foundChatID, text); err != nil {
+		log.Printf("Failed to confirm Telegram link for user %d: %v", userID, err)
+	}
+
+	return pages.TelegramChatIDField(foundChatID, "Telegram chat found, save to start receiving notifications", "").Render(c.Request().Context(), c.Response())
+}
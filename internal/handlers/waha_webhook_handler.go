@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+	"patungan_app_echo/internal/tasks"
+)
+
+// maxWebhookBodySize bounds the size of a WAHA webhook request
+const maxWebhookBodySize = 1 << 20
+
+type WahaWebhookHandler struct {
+	db      *gorm.DB
+	hmacKey string
+}
+
+// NewWahaWebhookHandler reads the webhook HMAC key from WAHA_WEBHOOK_HMAC_KEY
+func NewWahaWebhookHandler(db *gorm.DB) *WahaWebhookHandler {
+	return &WahaWebhookHandler{db: db, hmacKey: os.Getenv("WAHA_WEBHOOK_HMAC_KEY")}
+}
+
+// Webhook receives WAHA events and queues incoming messages for the bot. Requests must be
+// signed with the HMAC key configured on the WAHA webhook.
+func (h *WahaWebhookHandler) Webhook(c echo.Context) error {
+	if h.hmacKey == "" {
+		log.Println("WAHA webhook called but WAHA_WEBHOOK_HMAC_KEY is not set")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, "Webhook not configured")
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxWebhookBodySize))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Failed to read body")
+	}
+	if !services.VerifyWebhookSignature(body, c.Request().Header.Get("X-Webhook-Hmac"), h.hmacKey) {
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid Signature")
+	}
+
+	msg, ok, err := services.ParseWahaMessageEvent(body)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid JSON payload")
+	}
+	if !ok {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ignored"})
+	}
+
+	args := tasks.HandleWhatsappMessageArgs{
+		MessageID: msg.ID,
+		ChatID:    msg.From,
+		Sender:    msg.Sender(),
+		Body:      msg.Body,
+	}
+	// WAHA retries webhooks, a message already recorded is not queued again
+	duplicate := false
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		inbound := models.WhatsappInboundMessage{MessageID: msg.ID, ChatID: msg.From}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&inbound)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			duplicate = true
+			return nil
+		}
+
+		task, err := tasks.HandleWhatsappMessageTask.Enqueue(tx, args, time.Now(), nil)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&inbound).Update("scheduled_task_id", task.ID).Error
+	})
+	if err != nil {
+		log.Printf("Failed to queue WhatsApp message %s: %v", msg.ID, err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to queue message")
+	}
+	if duplicate {
+		return c.JSON(http.StatusOK, map[string]string{"status": "duplicate"})
+	}
+
+	// Redeliveries come shortly after the message, older IDs are not needed anymore
+	h.db.Where("created_at < ?", time.Now().Add(-models.WhatsappMessageRetention)).Delete(&models.WhatsappInboundMessage{})
+
+	return c.JSON(http.StatusOK, map[string]string{"status": "queued"})
+}
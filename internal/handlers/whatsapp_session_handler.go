@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+	"patungan_app_echo/web/templates/pages"
+	"patungan_app_echo/web/templates/shared"
+)
+
+// whatsappChecksShown is how many recent session checks the WhatsApp page lists
+const whatsappChecksShown = 20
+
+type WhatsappSessionHandler struct {
+	db   *gorm.DB
+	waha *services.WahaService
+}
+
+func NewWhatsappSessionHandler(db *gorm.DB, waha *services.WahaService) *WhatsappSessionHandler {
+	return &WhatsappSessionHandler{db: db, waha: waha}
+}
+
+// currentStatus asks WAHA for the state of the session
+func (h *WhatsappSessionHandler) currentStatus(c echo.Context) pages.WhatsappSessionStatusProps {
+	status := pages.WhatsappSessionStatusProps{Name: h.waha.Session(), CheckedAt: time.Now()}
+
+	session, err := h.waha.SessionStatus(c.Request().Context())
+	if err != nil {
+		status.Status = models.WhatsappSessionStatusUnreachable
+		status.Error = err.Error()
+		return status
+	}
+	status.Status = session.Status
+	status.Account = session.Account()
+	return status
+}
+
+// SessionPage renders the state of the WhatsApp session and its recent health checks
+func (h *WhatsappSessionHandler) SessionPage(c echo.Context) error {
+	var checks []models.WhatsappSessionCheck
+	if err := h.db.Where("session = ?", h.waha.Session()).
+		Order("created_at desc").
+		Limit(whatsappChecksShown).
+		Find(&checks).Error; err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, "Failed to load session checks")
+	}
+
+	breadcrumbs := []shared.Breadcrumb{
+		{Title: "Home", URL: "/"},
+		{Title: "WhatsApp", URL: ""},
+	}
+
+	props := pages.WhatsappSessionProps{
+		Title:       "WhatsApp",
+		ActiveNav:   "whatsapp",
+		Breadcrumbs: breadcrumbs,
+		UserEmail:   getStringFromContext(c, "userEmail"),
+		UserUID:     getStringFromContext(c, "userUID"),
+		Status:      h.currentStatus(c),
+		Checks:      checks,
+	}
+
+	return pages.WhatsappSession(props).Render(c.Request().Context(), c.Response())
+}
+
+// SessionStatus renders the live state of the session, polled by the WhatsApp page
+func (h *WhatsappSessionHandler) SessionStatus(c echo.Context) error {
+	return pages.WhatsappSessionStatus(h.currentStatus(c)).Render(c.Request().Context(), c.Response())
+}
+
+// LoginQR serves the QR code to pair the WhatsApp account
+func (h *WhatsappSessionHandler) LoginQR(c echo.Context) error {
+	image, err := h.waha.LoginQR(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to fetch QR code: "+err.Error())
+	}
+	c.Response().Header().Set("Cache-Control", "no-store")
+	return c.Blob(http.StatusOK, "image/png", image)
+}
+
+// RestartSession restarts the WhatsApp session
+func (h *WhatsappSessionHandler) RestartSession(c echo.Context) error {
+	if err := h.waha.RestartSession(c.Request().Context()); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to restart session: "+err.Error())
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/whatsapp")
+}
+
+// LogoutSession logs the WhatsApp account out, so another one can be paired with a new QR code
+func (h *WhatsappSessionHandler) LogoutSession(c echo.Context) error {
+	if err := h.waha.LogoutSession(c.Request().Context()); err != nil {
+		return echo.NewHTTPError(http.StatusBadGateway, "Failed to log out session: "+err.Error())
+	}
+	return c.Redirect(http.StatusSeeOther, "/admin/whatsapp")
+}
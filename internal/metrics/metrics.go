@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+)
+
+const namespace = "patungan"
+
+// Notification delivery outcomes
+const (
+	DeliveryOutcomeSuccess  = "success"
+	DeliveryOutcomeFailure  = "failure"
+	DeliveryOutcomeSkipped  = "skipped"
+	DeliveryOutcomeDeferred = "deferred"
+)
+
+// Payment callback outcomes
+const (
+	CallbackOutcomeProcessed        = "processed"
+	CallbackOutcomeInvalidPayload   = "invalid_payload"
+	CallbackOutcomeInvalidSignature = "invalid_signature"
+	CallbackOutcomeUnknownProvider  = "unknown_provider"
+	CallbackOutcomeNotFound         = "not_found"
+	CallbackOutcomeError            = "error"
+)
+
+var (
+	// TaskExecutions counts finished task runs by task name and history status
+	TaskExecutions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "task_executions_total",
+		Help:      "Number of scheduled task executions by task name and status.",
+	}, []string{"task_name", "status"})
+
+	// TaskDuration observes how long task handlers take to run
+	TaskDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "task_duration_seconds",
+		Help:      "Runtime of scheduled task handlers.",
+		Buckets:   []float64{0.05, 0.1, 0.5, 1, 5, 10, 30, 60, 120, 300, 600},
+	}, []string{"task_name"})
+
+	// NotificationDeliveries counts notifications sent per channel and outcome
+	NotificationDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "notification_deliveries_total",
+		Help:      "Number of notification deliveries by channel and outcome.",
+	}, []string{"channel", "outcome"})
+
+	// PaymentCallbacks counts received payment gateway callbacks by provider and outcome
+	PaymentCallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "payment_callbacks_total",
+		Help:      "Number of payment gateway callbacks by provider and outcome.",
+	}, []string{"provider", "outcome"})
+
+	// MidtransCallbacks counts received Midtrans callbacks by outcome.
+	// Deprecated: kept for existing dashboards and alerts, use PaymentCallbacks.
+	MidtransCallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "midtrans_callbacks_total",
+		Help:      "Number of Midtrans payment callbacks by outcome. Deprecated, use payment_callbacks_total.",
+	}, []string{"outcome"})
+
+	// HTTPRequestDuration observes request latency per route
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// ObserveTask records a finished task run
+func ObserveTask(taskName, status string, duration time.Duration) {
+	TaskExecutions.WithLabelValues(taskName, status).Inc()
+	TaskDuration.WithLabelValues(taskName).Observe(duration.Seconds())
+}
+
+// ObserveNotification records a notification delivery attempt
+func ObserveNotification(channel models.NotificationChannel, outcome string) {
+	if channel == "" {
+		channel = "unknown"
+	}
+	NotificationDeliveries.WithLabelValues(string(channel), outcome).Inc()
+}
+
+// ObservePaymentCallback records the outcome of a payment gateway callback
+func ObservePaymentCallback(provider models.PaymentGateway, outcome string) {
+	PaymentCallbacks.WithLabelValues(string(provider), outcome).Inc()
+	if provider == models.PaymentGatewayMidtrans {
+		MidtransCallbacks.WithLabelValues(outcome).Inc()
+	}
+}
+
+// RegisterQueueDepth exposes the number of active tasks that are due, queried on every scrape
+func RegisterQueueDepth(db *gorm.DB) {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "task_queue_depth",
+		Help:      "Number of active scheduled tasks that are due.",
+	}, func() float64 {
+		var count int64
+		err := db.Model(&models.ScheduledTask{}).
+			Where("status = ? AND due <= ?", models.ScheduledTaskStatusActive, time.Now()).
+			Count(&count).Error
+		if err != nil {
+			log.Printf("Failed to count due tasks for metrics: %v", err)
+			return 0
+		}
+		return float64(count)
+	})
+}
+
+// Handler serves the registered metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
@@ -86,3 +86,17 @@ func RequireAuth(authClient *auth.Client, db *gorm.DB, cache *services.RedisCach
 		}
 	}
 }
+
+// RequireAdmin returns a middleware that only lets Admin users through.
+// It must be used after RequireAuth, which sets the userType context value.
+func RequireAdmin() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userType, ok := c.Get("userType").(models.UserType)
+			if !ok || userType != models.UserTypeAdmin {
+				return echo.NewHTTPError(http.StatusForbidden, "Only admins can access this page")
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// GroupDigestPolicy binds a plan to a WhatsApp group that gets one payment status message per cycle
+// instead of a message per member, stored on the plan with a group_digest_ prefix
+type GroupDigestPolicy struct {
+	GroupID      string `gorm:"type:varchar(100)" json:"group_id"` // empty turns the digest off
+	IntervalDays int    `gorm:"default:0" json:"interval_days"`    // 0 only posts the digest when dues are generated
+}
+
+// Enabled reports whether the plan posts digests to a group
+func (p GroupDigestPolicy) Enabled() bool {
+	return strings.TrimSpace(p.GroupID) != ""
+}
+
+// ChatID returns the WhatsApp chat ID of the group
+func (p GroupDigestPolicy) ChatID() string {
+	id := strings.TrimSpace(p.GroupID)
+	if id == "" || strings.HasSuffix(id, "@g.us") {
+		return id
+	}
+	return id + "@g.us"
+}
+
+// IsValid reports whether the policy settings are usable
+func (p GroupDigestPolicy) IsValid() bool {
+	return p.IntervalDays >= 0
+}
+
+// PlanGroupDigest is a payment status message posted to the WhatsApp group of a plan for
+// the dues of one due date
+type PlanGroupDigest struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	PlanID          uint       `gorm:"index:idx_plan_group_digest_cycle" json:"plan_id"`
+	DueDate         time.Time  `gorm:"index:idx_plan_group_digest_cycle" json:"due_date"`
+	GroupID         string     `gorm:"type:varchar(100)" json:"group_id"`
+	ScheduledTaskID *uint      `json:"scheduled_task_id"`
+	SentAt          *time.Time `json:"sent_at,omitempty"`
+
+	Message           string `gorm:"type:text" json:"message"`
+	ProviderMessageID string `gorm:"type:varchar(255)" json:"provider_message_id"`
+
+	// Relationships
+	Plan Plan `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
+}
+
+// monthNames are the Indonesian month names used in digests
+var monthNames = [...]string{"Januari", "Februari", "Maret", "April", "Mei", "Juni", "Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+
+// IndonesianMonth returns the Indonesian name of the month of t, e.g. "Maret"
+func IndonesianMonth(t time.Time) string {
+	return monthNames[t.Month()-1]
+}
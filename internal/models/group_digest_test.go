@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupDigestPolicyChatID(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      GroupDigestPolicy
+		wantChatID  string
+		wantEnabled bool
+	}{
+		{name: "without suffix", policy: GroupDigestPolicy{GroupID: "120363407813232111"}, wantChatID: "120363407813232111@g.us", wantEnabled: true},
+		{name: "with suffix", policy: GroupDigestPolicy{GroupID: " 120363407813232111@g.us "}, wantChatID: "120363407813232111@g.us", wantEnabled: true},
+		{name: "no group", policy: GroupDigestPolicy{GroupID: "  "}, wantChatID: "", wantEnabled: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.ChatID(); got != tt.wantChatID {
+				t.Errorf("ChatID() = %q, want %q", got, tt.wantChatID)
+			}
+			if got := tt.policy.Enabled(); got != tt.wantEnabled {
+				t.Errorf("Enabled() = %v, want %v", got, tt.wantEnabled)
+			}
+		})
+	}
+}
+
+func TestIndonesianMonth(t *testing.T) {
+	tests := []struct {
+		month    time.Month
+		expected string
+	}{
+		{time.January, "Januari"},
+		{time.March, "Maret"},
+		{time.August, "Agustus"},
+		{time.December, "Desember"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			if got := IndonesianMonth(time.Date(2026, tt.month, 15, 0, 0, 0, 0, time.UTC)); got != tt.expected {
+				t.Errorf("IndonesianMonth(%s) = %q, want %q", tt.month, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// LateFeeType defines how the late fee of an overdue due is calculated
+type LateFeeType string
+
+// Late fee type constants
+const (
+	LateFeeTypeNone       LateFeeType = "none"
+	LateFeeTypeFlat       LateFeeType = "flat"
+	LateFeeTypePercentage LateFeeType = "percentage"
+)
+
+// LateFeeRecurrence defines whether a late fee is charged once or for every overdue period
+type LateFeeRecurrence string
+
+// Late fee recurrence constants
+const (
+	LateFeeRecurrenceOnce      LateFeeRecurrence = "once"
+	LateFeeRecurrencePerPeriod LateFeeRecurrence = "per_period"
+)
+
+// LateFeePolicy is the late fee rule of a plan, stored on the plan with a late_fee_ prefix
+type LateFeePolicy struct {
+	Type       LateFeeType       `gorm:"type:varchar(20);default:'none'" json:"type"`
+	Amount     Money             `gorm:"type:bigint;default:0" json:"amount"`               // flat fee in rupiah
+	Percentage float64           `gorm:"type:decimal(5,2);default:0" json:"percentage"`     // fee as a percentage of the due amount
+	Recurrence LateFeeRecurrence `gorm:"type:varchar(20);default:'once'" json:"recurrence"` // once, or again every PeriodDays
+	PeriodDays int               `gorm:"default:0" json:"period_days"`
+}
+
+// IsValid reports whether the policy can be applied
+func (p LateFeePolicy) IsValid() bool {
+	switch p.Type {
+	case LateFeeTypeNone, "":
+		return true
+	case LateFeeTypeFlat:
+		if p.Amount < 0 {
+			return false
+		}
+	case LateFeeTypePercentage:
+		if p.Percentage < 0 || p.Percentage > 100 {
+			return false
+		}
+	default:
+		return false
+	}
+
+	switch p.Recurrence {
+	case LateFeeRecurrenceOnce, "":
+		return true
+	case LateFeeRecurrencePerPeriod:
+		return p.PeriodDays > 0
+	}
+	return false
+}
+
+// FeeFor returns the late fee owed on amount for a due that became overdue at overdueSince.
+// A per-period fee is charged once when the due becomes overdue and again at the start of every further period.
+func (p LateFeePolicy) FeeFor(amount Money, overdueSince, now time.Time) Money {
+	if now.Before(overdueSince) {
+		return 0
+	}
+
+	var fee Money
+	switch p.Type {
+	case LateFeeTypeFlat:
+		fee = p.Amount
+	case LateFeeTypePercentage:
+		fee = Money(math.Round(float64(amount) * p.Percentage / 100))
+	default:
+		return 0
+	}
+
+	if p.Recurrence == LateFeeRecurrencePerPeriod && p.PeriodDays > 0 {
+		period := time.Duration(p.PeriodDays) * 24 * time.Hour
+		periods := int64(now.Sub(overdueSince)/period) + 1
+		fee *= Money(periods)
+	}
+	return fee
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLateFeePolicyFeeFor(t *testing.T) {
+	overdueSince := time.Date(2025, 3, 8, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name     string
+		policy   LateFeePolicy
+		amount   Money
+		now      time.Time
+		expected Money
+	}{
+		{
+			name:     "no late fee",
+			policy:   LateFeePolicy{Type: LateFeeTypeNone},
+			amount:   100000,
+			now:      overdueSince.Add(10 * day),
+			expected: 0,
+		},
+		{
+			name:     "not overdue yet",
+			policy:   LateFeePolicy{Type: LateFeeTypeFlat, Amount: 5000},
+			amount:   100000,
+			now:      overdueSince.Add(-time.Hour),
+			expected: 0,
+		},
+		{
+			name:     "flat fee once",
+			policy:   LateFeePolicy{Type: LateFeeTypeFlat, Amount: 5000, Recurrence: LateFeeRecurrenceOnce},
+			amount:   100000,
+			now:      overdueSince.Add(40 * day),
+			expected: 5000,
+		},
+		{
+			name:     "percentage fee rounds to whole rupiah",
+			policy:   LateFeePolicy{Type: LateFeeTypePercentage, Percentage: 2.5},
+			amount:   33333,
+			now:      overdueSince,
+			expected: 833,
+		},
+		{
+			name:     "per period fee in first period",
+			policy:   LateFeePolicy{Type: LateFeeTypeFlat, Amount: 5000, Recurrence: LateFeeRecurrencePerPeriod, PeriodDays: 7},
+			amount:   100000,
+			now:      overdueSince.Add(6 * day),
+			expected: 5000,
+		},
+		{
+			name:     "per period fee charged again each period",
+			policy:   LateFeePolicy{Type: LateFeeTypePercentage, Percentage: 1, Recurrence: LateFeeRecurrencePerPeriod, PeriodDays: 7},
+			amount:   100000,
+			now:      overdueSince.Add(15 * day),
+			expected: 3000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.policy.FeeFor(tt.amount, overdueSince, tt.now)
+			if result != tt.expected {
+				t.Errorf("FeeFor() = %d; want %d", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLateFeePolicyIsValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   LateFeePolicy
+		expected bool
+	}{
+		{name: "empty policy", policy: LateFeePolicy{}, expected: true},
+		{name: "flat once", policy: LateFeePolicy{Type: LateFeeTypeFlat, Amount: 5000, Recurrence: LateFeeRecurrenceOnce}, expected: true},
+		{name: "negative flat fee", policy: LateFeePolicy{Type: LateFeeTypeFlat, Amount: -1}, expected: false},
+		{name: "percentage above 100", policy: LateFeePolicy{Type: LateFeeTypePercentage, Percentage: 120}, expected: false},
+		{name: "per period without period", policy: LateFeePolicy{Type: LateFeeTypeFlat, Amount: 5000, Recurrence: LateFeeRecurrencePerPeriod}, expected: false},
+		{name: "unknown type", policy: LateFeePolicy{Type: "daily"}, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.policy.IsValid(); result != tt.expected {
+				t.Errorf("IsValid() = %v; want %v", result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in whole rupiah. Rupiah has no minor unit in use and Midtrans
+// only accepts integer amounts, so money is kept as an integer to avoid rounding errors.
+type Money int64
+
+// ParseMoney parses a decimal amount such as "100000" or "100000.00", rounded to the nearest rupiah
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("amount is empty")
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	negative := strings.HasPrefix(whole, "-")
+	value, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	if frac != "" {
+		if _, err := strconv.ParseUint(frac, 10, 64); err != nil {
+			return 0, fmt.Errorf("invalid amount %q", s)
+		}
+		if frac[0] >= '5' {
+			if negative {
+				value--
+			} else {
+				value++
+			}
+		}
+	}
+	return Money(value), nil
+}
+
+// Int64 returns the amount as an integer, as expected by payment gateways
+func (m Money) Int64() int64 {
+	return int64(m)
+}
+
+// String formats the amount without a currency symbol, e.g. "100000"
+func (m Money) String() string {
+	return strconv.FormatInt(int64(m), 10)
+}
+
+// UnmarshalJSON accepts integer and decimal numbers, so task arguments stored
+// while amounts were still floats keep decoding
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var number json.Number
+	if err := json.Unmarshal(data, &number); err != nil {
+		return fmt.Errorf("amount must be a number: %w", err)
+	}
+	parsed, err := ParseMoney(number.String())
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// AllocateByPortions splits total across the given portions so that the shares always sum to total.
+// Each share gets the whole rupiah part of its exact share, and the rupiah left over are handed out
+// one by one to the largest remainders, ties going to the earlier portion (largest remainder method).
+func AllocateByPortions(total Money, portions []int) ([]Money, error) {
+	if total < 0 {
+		return nil, fmt.Errorf("total must not be negative")
+	}
+	totalPortions := 0
+	for _, p := range portions {
+		if p < 0 {
+			return nil, fmt.Errorf("portion must not be negative")
+		}
+		totalPortions += p
+	}
+	if totalPortions == 0 {
+		return nil, fmt.Errorf("total portions is 0")
+	}
+
+	shares := make([]Money, len(portions))
+	remainders := make([]int64, len(portions))
+	allocated := Money(0)
+	for i, p := range portions {
+		exact := int64(total) * int64(p)
+		shares[i] = Money(exact / int64(totalPortions))
+		remainders[i] = exact % int64(totalPortions)
+		allocated += shares[i]
+	}
+
+	for left := total - allocated; left > 0; left-- {
+		largest := -1
+		for i, r := range remainders {
+			if largest == -1 || r > remainders[largest] {
+				largest = i
+			}
+		}
+		shares[largest]++
+		// Each share receives at most one extra rupiah
+		remainders[largest] = -1
+	}
+
+	return shares, nil
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Money
+		wantErr  bool
+	}{
+		{name: "integer", input: "100000", expected: 100000},
+		{name: "midtrans gross amount", input: "100000.00", expected: 100000},
+		{name: "fraction rounds down", input: "33333.33", expected: 33333},
+		{name: "half rounds up", input: "33333.5", expected: 33334},
+		{name: "surrounding spaces", input: " 2500 ", expected: 2500},
+		{name: "empty", input: "", wantErr: true},
+		{name: "not a number", input: "abc", wantErr: true},
+		{name: "bad fraction", input: "10.x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseMoney(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseMoney(%q) = %d; want error", tt.input, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q) returned error: %v", tt.input, err)
+			}
+			if result != tt.expected {
+				t.Errorf("ParseMoney(%q) = %d; want %d", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+	var payload struct {
+		Amount Money `json:"amount"`
+	}
+	if err := json.Unmarshal([]byte(`{"amount": 33333.333333333336}`), &payload); err != nil {
+		t.Fatalf("Unmarshal returned error: %v", err)
+	}
+	if payload.Amount != 33333 {
+		t.Errorf("Amount = %d; want 33333", payload.Amount)
+	}
+}
+
+func TestAllocateByPortions(t *testing.T) {
+	tests := []struct {
+		name     string
+		total    Money
+		portions []int
+		expected []Money
+		wantErr  bool
+	}{
+		{name: "even split", total: 90000, portions: []int{1, 1, 1}, expected: []Money{30000, 30000, 30000}},
+		{name: "remainder goes to earliest on ties", total: 100000, portions: []int{1, 1, 1}, expected: []Money{33334, 33333, 33333}},
+		{name: "two rupiah left over", total: 100001, portions: []int{1, 1, 1}, expected: []Money{33334, 33334, 33333}},
+		{name: "largest remainder wins", total: 100, portions: []int{1, 2, 4}, expected: []Money{14, 29, 57}},
+		{name: "weighted portions", total: 100000, portions: []int{2, 1}, expected: []Money{66667, 33333}},
+		{name: "zero portion gets nothing", total: 1000, portions: []int{0, 3}, expected: []Money{0, 1000}},
+		{name: "no portions", total: 1000, portions: []int{0, 0}, wantErr: true},
+		{name: "negative total", total: -1, portions: []int{1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := AllocateByPortions(tt.total, tt.portions)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("AllocateByPortions(%d, %v) = %v; want error", tt.total, tt.portions, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AllocateByPortions returned error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("AllocateByPortions(%d, %v) = %v; want %v", tt.total, tt.portions, result, tt.expected)
+			}
+
+			var sum Money
+			for _, share := range result {
+				sum += share
+			}
+			if sum != tt.total {
+				t.Errorf("shares sum to %d; want %d", sum, tt.total)
+			}
+		})
+	}
+}
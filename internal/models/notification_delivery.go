@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationDeliveryStatus is where a delivery is in its lifecycle
+type NotificationDeliveryStatus string
+
+// Notification delivery status constants
+const (
+	// NotificationDeliveryStatusPending is waiting for its first attempt or for a retry at NextAttemptAt
+	NotificationDeliveryStatusPending NotificationDeliveryStatus = "pending"
+	NotificationDeliveryStatusSent    NotificationDeliveryStatus = "sent"
+	// NotificationDeliveryStatusFailed ran out of attempts
+	NotificationDeliveryStatusFailed NotificationDeliveryStatus = "failed"
+	// NotificationDeliveryStatusSkipped was not sent, e.g. the user has notifications turned off or the due was paid
+	NotificationDeliveryStatusSkipped NotificationDeliveryStatus = "skipped"
+)
+
+// NotificationDelivery is one notification to one recipient, with the message as it was rendered
+type NotificationDelivery struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID          uint                `gorm:"index" json:"user_id"`
+	PaymentDueID    *uint               `gorm:"index" json:"payment_due_id,omitempty"`
+	ScheduledTaskID *uint               `gorm:"index" json:"scheduled_task_id,omitempty"` // the send_notification task that created the delivery
+	Event           NotificationEvent   `gorm:"type:varchar(50)" json:"event,omitempty"`
+	Channel         NotificationChannel `gorm:"type:varchar(20)" json:"channel"`
+	Recipient       string              `gorm:"type:varchar(255)" json:"recipient"` // email address or WhatsApp chat ID
+	Subject         string              `gorm:"type:varchar(255)" json:"subject,omitempty"`
+	Message         string              `gorm:"type:text" json:"message"`
+	IsHTML          bool                `gorm:"default:false" json:"is_html"`
+
+	Status            NotificationDeliveryStatus `gorm:"type:varchar(20);index" json:"status"`
+	Attempts          int                        `gorm:"default:0" json:"attempts"`
+	MaxAttempts       int                        `gorm:"default:3" json:"max_attempts"`
+	NextAttemptAt     *time.Time                 `gorm:"index" json:"next_attempt_at,omitempty"`
+	SentAt            *time.Time                 `json:"sent_at,omitempty"`
+	ProviderMessageID string                     `gorm:"type:varchar(255)" json:"provider_message_id,omitempty"`
+	LastError         string                     `gorm:"type:text" json:"last_error,omitempty"`
+
+	// Relationships
+	User          User                          `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	PaymentDue    *PaymentDue                   `gorm:"foreignKey:PaymentDueID" json:"payment_due,omitempty"`
+	ScheduledTask *ScheduledTask                `gorm:"foreignKey:ScheduledTaskID;constraint:OnDelete:SET NULL" json:"scheduled_task,omitempty"`
+	AttemptLog    []NotificationDeliveryAttempt `gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE" json:"attempt_log,omitempty"`
+}
+
+// NotificationDeliveryAttempt records the outcome of one try to send a delivery
+type NotificationDeliveryAttempt struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	DeliveryID        uint                       `gorm:"index" json:"delivery_id"`
+	Attempt           int                        `json:"attempt"`
+	Status            NotificationDeliveryStatus `gorm:"type:varchar(20)" json:"status"` // status of the delivery after the attempt
+	ProviderMessageID string                     `gorm:"type:varchar(255)" json:"provider_message_id,omitempty"`
+	Error             string                     `gorm:"type:text" json:"error,omitempty"`
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// NotificationEvent is what a notification is about
+type NotificationEvent string
+
+// Notification event constants
+const (
+	NotificationEventDueCreated      NotificationEvent = "due_created"
+	NotificationEventReminder        NotificationEvent = "reminder"
+	NotificationEventOverdue         NotificationEvent = "overdue"
+	NotificationEventPaymentReceived NotificationEvent = "payment_received"
+	NotificationEventRefund          NotificationEvent = "refund"
+)
+
+// NotificationEvents lists every event in the order they are shown to admins
+var NotificationEvents = []NotificationEvent{
+	NotificationEventDueCreated,
+	NotificationEventReminder,
+	NotificationEventOverdue,
+	NotificationEventPaymentReceived,
+	NotificationEventRefund,
+}
+
+// TemplateChannels lists the channels that have their own message templates
+var TemplateChannels = []NotificationChannel{
+	NotificationChannelEmail,
+	NotificationChannelWhatsapp,
+	NotificationChannelTelegram,
+}
+
+// IsValid reports whether the event is known
+func (e NotificationEvent) IsValid() bool {
+	for _, event := range NotificationEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTemplateChannel reports whether messages sent through the channel use a template
+func IsTemplateChannel(channel NotificationChannel) bool {
+	for _, c := range TemplateChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationData is what a notification template can refer to, e.g. {{.Name}} or {{.Amount}}
+type NotificationData struct {
+	Name        string
+	Email       string
+	PlanName    string
+	Amount      Money
+	DueDate     string
+	PaymentLink string
+}
+
+// SampleNotificationData is used to preview and check templates
+var SampleNotificationData = NotificationData{
+	Name:        "Budi",
+	Email:       "budi@example.com",
+	PlanName:    "Netflix Keluarga",
+	Amount:      Money(45000),
+	DueDate:     "05 Jan 2026",
+	PaymentLink: "https://example.com/p/00000000-0000-0000-0000-000000000000",
+}
+
+// NotificationTemplate is an admin edited message for an event and channel.
+// The body is a Go template, rendered with html/template for email and text/template otherwise.
+type NotificationTemplate struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Event   NotificationEvent   `gorm:"type:varchar(50);uniqueIndex:idx_notification_template" json:"event"`
+	Channel NotificationChannel `gorm:"type:varchar(20);uniqueIndex:idx_notification_template" json:"channel"`
+	Subject string              `gorm:"type:varchar(255)" json:"subject"` // only used by email
+	Body    string              `gorm:"type:text" json:"body"`
+}
+
+// Render executes the subject and body templates with data
+func (t NotificationTemplate) Render(data NotificationData) (string, string, error) {
+	subjectTmpl, err := texttemplate.New("subject").Parse(t.Subject)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid subject: %w", err)
+	}
+	var subject bytes.Buffer
+	if err := subjectTmpl.Execute(&subject, data); err != nil {
+		return "", "", fmt.Errorf("invalid subject: %w", err)
+	}
+
+	var body bytes.Buffer
+	if t.Channel == NotificationChannelEmail {
+		bodyTmpl, err := htmltemplate.New("body").Parse(t.Body)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid body: %w", err)
+		}
+		if err := bodyTmpl.Execute(&body, data); err != nil {
+			return "", "", fmt.Errorf("invalid body: %w", err)
+		}
+	} else {
+		bodyTmpl, err := texttemplate.New("body").Parse(t.Body)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid body: %w", err)
+		}
+		if err := bodyTmpl.Execute(&body, data); err != nil {
+			return "", "", fmt.Errorf("invalid body: %w", err)
+		}
+	}
+
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+// Validate checks that the template renders with sample data
+func (t NotificationTemplate) Validate() error {
+	if strings.TrimSpace(t.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+	_, _, err := t.Render(SampleNotificationData)
+	return err
+}
+
+// defaultNotificationText holds the built-in subject and message of each event
+var defaultNotificationText = map[NotificationEvent]struct{ subject, text, html string }{
+	NotificationEventDueCreated: {
+		subject: "Tagihan Plan {{.PlanName}}",
+		text:    "Halo {{.Name}}, tagihan untuk plan {{.PlanName}} sebesar Rp {{.Amount}} jatuh tempo pada {{.DueDate}}. Yuk dibayar di {{.PaymentLink}}",
+		html:    `<p>Halo {{.Name}},</p><p>Tagihan untuk plan <b>{{.PlanName}}</b> sebesar Rp {{.Amount}} jatuh tempo pada {{.DueDate}}.</p><p><a href="{{.PaymentLink}}">Bayar sekarang</a></p>`,
+	},
+	NotificationEventReminder: {
+		subject: "Pengingat Tagihan Plan {{.PlanName}}",
+		text:    "Halo {{.Name}}, jangan lupa tagihan plan {{.PlanName}} sebesar Rp {{.Amount}} jatuh tempo pada {{.DueDate}}. Bayar di {{.PaymentLink}}",
+		html:    `<p>Halo {{.Name}},</p><p>Jangan lupa, tagihan plan <b>{{.PlanName}}</b> sebesar Rp {{.Amount}} jatuh tempo pada {{.DueDate}}.</p><p><a href="{{.PaymentLink}}">Bayar sekarang</a></p>`,
+	},
+	NotificationEventOverdue: {
+		subject: "Tagihan Plan {{.PlanName}} Lewat Jatuh Tempo",
+		text:    "Halo {{.Name}}, tagihan plan {{.PlanName}} sebesar Rp {{.Amount}} sudah lewat jatuh tempo sejak {{.DueDate}}. Yuk segera dibayar di {{.PaymentLink}}",
+		html:    `<p>Halo {{.Name}},</p><p>Tagihan plan <b>{{.PlanName}}</b> sebesar Rp {{.Amount}} sudah lewat jatuh tempo sejak {{.DueDate}}.</p><p><a href="{{.PaymentLink}}">Bayar sekarang</a></p>`,
+	},
+	NotificationEventPaymentReceived: {
+		subject: "Pembayaran Plan {{.PlanName}} Diterima",
+		text:    "Halo {{.Name}}, pembayaran plan {{.PlanName}} sebesar Rp {{.Amount}} sudah kami terima. Terima kasih!",
+		html:    `<p>Halo {{.Name}},</p><p>Pembayaran plan <b>{{.PlanName}}</b> sebesar Rp {{.Amount}} sudah kami terima. Terima kasih!</p>`,
+	},
+	NotificationEventRefund: {
+		subject: "Refund Plan {{.PlanName}}",
+		text:    "Halo {{.Name}}, refund plan {{.PlanName}} sebesar Rp {{.Amount}} sedang kami proses.",
+		html:    `<p>Halo {{.Name}},</p><p>Refund plan <b>{{.PlanName}}</b> sebesar Rp {{.Amount}} sedang kami proses.</p>`,
+	},
+}
+
+// DefaultNotificationTemplate returns the built-in template used when no template is stored for the event and channel
+func DefaultNotificationTemplate(event NotificationEvent, channel NotificationChannel) NotificationTemplate {
+	text := defaultNotificationText[event]
+	tmpl := NotificationTemplate{
+		Event:   event,
+		Channel: channel,
+		Subject: text.subject,
+		Body:    text.text,
+	}
+	if channel == NotificationChannelEmail {
+		tmpl.Body = text.html
+	}
+	return tmpl
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNotificationTemplateRender(t *testing.T) {
+	data := NotificationData{
+		Name:        "Sari & Co",
+		PlanName:    "Spotify",
+		Amount:      Money(1234567),
+		PaymentLink: "https://example.com/p/abc",
+	}
+
+	tests := []struct {
+		name        string
+		template    NotificationTemplate
+		wantSubject string
+		wantBody    string
+		wantErr     bool
+	}{
+		{
+			name:        "text template",
+			template:    NotificationTemplate{Channel: NotificationChannelWhatsapp, Subject: "Plan {{.PlanName}}", Body: "Halo {{.Name}}, Rp {{.Amount}}"},
+			wantSubject: "Plan Spotify",
+			wantBody:    "Halo Sari & Co, Rp 1234567",
+		},
+		{
+			name:     "email body is escaped",
+			template: NotificationTemplate{Channel: NotificationChannelEmail, Body: "<p>{{.Name}}</p>"},
+			wantBody: "<p>Sari &amp; Co</p>",
+		},
+		{
+			name:     "unknown field",
+			template: NotificationTemplate{Channel: NotificationChannelWhatsapp, Body: "Halo {{.Nama}}"},
+			wantErr:  true,
+		},
+		{
+			name:     "syntax error",
+			template: NotificationTemplate{Channel: NotificationChannelWhatsapp, Body: "Halo {{.Name"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subject, body, err := tt.template.Render(data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("Render() subject = %q, want %q", subject, tt.wantSubject)
+			}
+			if body != tt.wantBody {
+				t.Errorf("Render() body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestDefaultNotificationTemplatesAreValid(t *testing.T) {
+	for _, event := range NotificationEvents {
+		for _, channel := range TemplateChannels {
+			tmpl := DefaultNotificationTemplate(event, channel)
+			if err := tmpl.Validate(); err != nil {
+				t.Errorf("default %s template for %s is invalid: %v", event, channel, err)
+			}
+			if _, body, _ := tmpl.Render(SampleNotificationData); !strings.Contains(body, SampleNotificationData.Name) {
+				t.Errorf("default %s template for %s does not greet the user: %q", event, channel, body)
+			}
+		}
+	}
+}
@@ -21,17 +21,44 @@ type PaymentDue struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 
-	PlanID              uint      `gorm:"index" json:"plan_id"`
-	UserID              uint      `gorm:"index" json:"user_id"`
+	PlanID              uint      `gorm:"index;uniqueIndex:idx_payment_due_cycle,where:deleted_at IS NULL" json:"plan_id"`
+	UserID              uint      `gorm:"index;uniqueIndex:idx_payment_due_cycle,where:deleted_at IS NULL" json:"user_id"`
 	Portion             int       `json:"portion"`
 	DueDate             time.Time `json:"due_date"`
 	UUID                string    `gorm:"uniqueIndex;type:uuid;default:gen_random_uuid()" json:"uuid"`
-	CalculatedPayAmount float64   `gorm:"type:decimal(15,2)" json:"calculated_pay_amount"`
+	CalculatedPayAmount Money     `gorm:"type:bigint" json:"calculated_pay_amount"`
 	PaymentStatus       string    `gorm:"type:varchar(50)" json:"payment_status"` // e.g., "pending", "paid", "overdue"
 
+	// LateFee is the late fee currently owed on top of CalculatedPayAmount, kept up to date while the due is overdue
+	LateFee   Money      `gorm:"type:bigint;default:0" json:"late_fee"`
+	OverdueAt *time.Time `json:"overdue_at,omitempty"`
+
+	// CycleDate is the billing cycle the due belongs to, a plan has at most one due per user and cycle.
+	// Dues created before cycles were tracked have no cycle date.
+	CycleDate *time.Time `gorm:"type:date;uniqueIndex:idx_payment_due_cycle,where:deleted_at IS NULL" json:"cycle_date,omitempty"`
+
 	// Relationships
 	Plan        Plan         `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
 	User        User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
 	UserPayment *UserPayment `gorm:"foreignKey:PaymentDueID" json:"user_payment,omitempty"`
 	Refund      *Refund      `gorm:"foreignKey:PaymentDueID" json:"refund,omitempty"`
+
+	Reminders []PaymentDueReminder `gorm:"foreignKey:PaymentDueID" json:"reminders,omitempty"`
+}
+
+// PayableAmount returns what the user has to pay, including the late fee
+func (d PaymentDue) PayableAmount() Money {
+	return d.CalculatedPayAmount + d.LateFee
+}
+
+// LocalDueDate returns the due date in the plan timezone. Plan must be preloaded.
+func (d PaymentDue) LocalDueDate() time.Time {
+	return d.DueDate.In(d.Plan.Location())
+}
+
+// BillingCycleDate returns the calendar date of a plan occurrence in the plan timezone,
+// which identifies the billing cycle of the dues generated for it
+func BillingCycleDate(occurrence time.Time, loc *time.Location) time.Time {
+	local := occurrence.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
 }
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBillingCycleDate(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		occurrence time.Time
+		loc        *time.Location
+		expected   time.Time
+	}{
+		{
+			name:       "same day in plan timezone",
+			occurrence: time.Date(2025, 3, 5, 9, 0, 0, 0, jakarta),
+			loc:        jakarta,
+			expected:   time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "utc evening is the next day in jakarta",
+			occurrence: time.Date(2025, 3, 4, 20, 0, 0, 0, time.UTC),
+			loc:        jakarta,
+			expected:   time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "retries later the same day keep the cycle",
+			occurrence: time.Date(2025, 3, 5, 23, 59, 0, 0, jakarta),
+			loc:        jakarta,
+			expected:   time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := BillingCycleDate(tt.occurrence, tt.loc)
+			if !result.Equal(tt.expected) {
+				t.Errorf("BillingCycleDate(%s) = %s; want %s", tt.occurrence, result, tt.expected)
+			}
+		})
+	}
+}
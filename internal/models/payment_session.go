@@ -14,6 +14,7 @@ type PaymentSession struct {
 	UserID           uint            `json:"user_id"`
 	PaymentGateway   PaymentGateway  `gorm:"type:varchar(50);not null" json:"payment_gateway"`
 	OrderID          string          `gorm:"type:varchar(100);index" json:"order_id"`
+	Amount           Money           `gorm:"type:bigint;default:0" json:"amount"` // amount charged by this session
 	IsActive         bool            `gorm:"default:true" json:"is_active"`
 	RequestMetadata  json.RawMessage `gorm:"type:jsonb" json:"request_metadata"`
 	ResponseMetadata json.RawMessage `gorm:"type:jsonb" json:"response_metadata"`
@@ -16,13 +16,26 @@ type Plan struct {
 
 	Name              string    `gorm:"type:varchar(255)" json:"name"`
 	OwnerID           uint      `gorm:"index" json:"owner_id"`
-	TotalPrice        float64   `gorm:"type:decimal(15,2)" json:"total_price"`
+	TotalPrice        Money     `gorm:"type:bigint" json:"total_price"`
 	PlanStartDate     time.Time `json:"plan_start_date"`
 	PaymentType       string    `gorm:"type:varchar(50);default:'onetime'" json:"payment_type"` // 'onetime' or 'recurring'
 	RecurringInterval *string   `gorm:"type:text" json:"recurring_interval"`                    // RFC 5545 RRULE string
+	Timezone          string    `gorm:"type:varchar(64)" json:"timezone"`                       // IANA name, empty means the app timezone
+	SplitMode         SplitMode `gorm:"type:varchar(20);default:'shares'" json:"split_mode"`    // how TotalPrice is divided among participants
 
 	AllowInvitationAfterPay bool `gorm:"default:false" json:"allow_invitation_after_pay"`
 
+	// PaymentTermDays is how many days after the billing date a due has to be paid, 0 makes it due right away
+	PaymentTermDays int `gorm:"default:0" json:"payment_term_days"`
+
+	// GracePeriodDays is how many days after the due date a pending due turns overdue
+	GracePeriodDays int            `gorm:"default:0" json:"grace_period_days"`
+	LateFee         LateFeePolicy  `gorm:"embedded;embeddedPrefix:late_fee_" json:"late_fee"`
+	Reminders       ReminderPolicy `gorm:"embedded;embeddedPrefix:reminder_" json:"reminders"`
+
+	// GroupDigest posts the payment status of each cycle to the plan's WhatsApp group
+	GroupDigest GroupDigestPolicy `gorm:"embedded;embeddedPrefix:group_digest_" json:"group_digest"`
+
 	// Relationships
 	Owner        User              `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
 	Participants []PlanParticipant `gorm:"foreignKey:PlanID" json:"participants,omitempty"`
@@ -33,6 +46,21 @@ type Plan struct {
 	ScheduledTask   *ScheduledTask `gorm:"foreignKey:ScheduledTaskID;constraint:OnDelete:SET NULL" json:"scheduled_task,omitempty"`
 }
 
+// Location returns the timezone the plan is billed in
+func (p Plan) Location() *time.Location {
+	return LoadLocation(p.Timezone)
+}
+
+// DueDateFor returns the due date of the dues billed at the given occurrence
+func (p Plan) DueDateFor(occurrence time.Time) time.Time {
+	return occurrence.In(p.Location()).AddDate(0, 0, p.PaymentTermDays)
+}
+
+// OverdueAt returns when a due of this plan with the given due date becomes overdue
+func (p Plan) OverdueAt(dueDate time.Time) time.Time {
+	return dueDate.In(p.Location()).AddDate(0, 0, p.GracePeriodDays)
+}
+
 // NextDue calculates the next due date for the plan
 func (p Plan) NextDue() time.Time {
 	if p.PaymentType == "onetime" {
@@ -42,9 +70,13 @@ func (p Plan) NextDue() time.Time {
 	if p.RecurringInterval != nil && *p.RecurringInterval != "" {
 		rule, err := rrule.StrToRRule(*p.RecurringInterval)
 		if err == nil {
-			rule.DTStart(p.PlanStartDate)
+			// Expand the rule in the plan timezone, so occurrences land on the right local day
+			loc := p.Location()
+			rule.DTStart(p.PlanStartDate.In(loc))
 			// Find next occurrence after now (or include today)
-			next := rule.After(time.Now().Add(-24*time.Hour), true)
+			now := time.Now().In(loc)
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+			next := rule.After(today, true)
 			if !next.IsZero() {
 				return next
 			}
@@ -19,6 +19,13 @@ type PlanParticipant struct {
 	// Portion represents how many "shares" this user pays for. Default is 1.
 	Portion int `gorm:"default:1" json:"portion"`
 
+	// FixedAmount is what this user pays in the fixed and mixed split modes.
+	// In mixed mode, participants without a fixed amount split the rest by portion.
+	FixedAmount *Money `gorm:"type:bigint" json:"fixed_amount,omitempty"`
+
+	// Percentage is the share of the total this user pays in the percentage split mode, e.g. 12.5
+	Percentage float64 `gorm:"type:decimal(5,2)" json:"percentage"`
+
 	// Relationships
 	Plan Plan `gorm:"foreignKey:PlanID" json:"plan,omitempty"`
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
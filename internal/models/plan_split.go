@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// SplitMode defines how the price of a plan is divided among its participants
+type SplitMode string
+
+// Split mode constants
+const (
+	// SplitModeShares divides the total by each participant's portion count
+	SplitModeShares SplitMode = "shares"
+	// SplitModeFixed charges every participant a fixed amount, the amounts must add up to the total
+	SplitModeFixed SplitMode = "fixed"
+	// SplitModePercentage charges every participant a percentage of the total, adding up to 100%
+	SplitModePercentage SplitMode = "percentage"
+	// SplitModeMixed charges participants with a fixed amount first and splits the rest by portion
+	SplitModeMixed SplitMode = "mixed"
+)
+
+// IsValid reports whether the split mode is known
+func (m SplitMode) IsValid() bool {
+	switch m {
+	case SplitModeShares, SplitModeFixed, SplitModePercentage, SplitModeMixed:
+		return true
+	}
+	return false
+}
+
+// SplitAmounts returns what each participant pays, in the order of p.Participants.
+// The amounts always add up to TotalPrice, an error is returned when the split
+// configuration cannot cover the total exactly.
+func (p Plan) SplitAmounts() ([]Money, error) {
+	if len(p.Participants) == 0 {
+		return nil, fmt.Errorf("plan has no participants")
+	}
+
+	switch p.SplitMode {
+	case SplitModeShares, "":
+		portions := make([]int, len(p.Participants))
+		for i, participant := range p.Participants {
+			portions[i] = participant.Portion
+		}
+		return AllocateByPortions(p.TotalPrice, portions)
+
+	case SplitModeFixed:
+		amounts := make([]Money, len(p.Participants))
+		var sum Money
+		for i, participant := range p.Participants {
+			if participant.FixedAmount == nil || *participant.FixedAmount < 0 {
+				return nil, fmt.Errorf("every participant needs a fixed amount")
+			}
+			amounts[i] = *participant.FixedAmount
+			sum += amounts[i]
+		}
+		if sum != p.TotalPrice {
+			return nil, fmt.Errorf("fixed amounts add up to %s, but the total price is %s", sum, p.TotalPrice)
+		}
+		return amounts, nil
+
+	case SplitModePercentage:
+		// Work in basis points so the shares are split exactly, 12.5% is 1250
+		basisPoints := make([]int, len(p.Participants))
+		sum := 0
+		for i, participant := range p.Participants {
+			if participant.Percentage < 0 {
+				return nil, fmt.Errorf("percentage must not be negative")
+			}
+			basisPoints[i] = int(math.Round(participant.Percentage * 100))
+			sum += basisPoints[i]
+		}
+		if sum != 10000 {
+			return nil, fmt.Errorf("percentages add up to %.2f%%, they must add up to 100%%", float64(sum)/100)
+		}
+		return AllocateByPortions(p.TotalPrice, basisPoints)
+
+	case SplitModeMixed:
+		amounts := make([]Money, len(p.Participants))
+		var fixed Money
+		var sharers []int
+		var portions []int
+		for i, participant := range p.Participants {
+			if participant.FixedAmount != nil {
+				if *participant.FixedAmount < 0 {
+					return nil, fmt.Errorf("fixed amount must not be negative")
+				}
+				amounts[i] = *participant.FixedAmount
+				fixed += amounts[i]
+				continue
+			}
+			sharers = append(sharers, i)
+			portions = append(portions, participant.Portion)
+		}
+
+		rest := p.TotalPrice - fixed
+		if rest < 0 {
+			return nil, fmt.Errorf("fixed amounts add up to %s, more than the total price of %s", fixed, p.TotalPrice)
+		}
+		if len(sharers) == 0 {
+			if rest != 0 {
+				return nil, fmt.Errorf("fixed amounts add up to %s, but the total price is %s", fixed, p.TotalPrice)
+			}
+			return amounts, nil
+		}
+
+		shares, err := AllocateByPortions(rest, portions)
+		if err != nil {
+			return nil, fmt.Errorf("the remaining %s cannot be split: %w", rest, err)
+		}
+		for j, i := range sharers {
+			amounts[i] = shares[j]
+		}
+		return amounts, nil
+	}
+
+	return nil, fmt.Errorf("unknown split mode %q", p.SplitMode)
+}
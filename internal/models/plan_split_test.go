@@ -0,0 +1,135 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanSplitAmounts(t *testing.T) {
+	money := func(m Money) *Money { return &m }
+
+	tests := []struct {
+		name         string
+		mode         SplitMode
+		total        Money
+		participants []PlanParticipant
+		expected     []Money
+		wantErr      bool
+	}{
+		{
+			name:         "shares split with remainder",
+			mode:         SplitModeShares,
+			total:        100000,
+			participants: []PlanParticipant{{Portion: 1}, {Portion: 1}, {Portion: 1}},
+			expected:     []Money{33334, 33333, 33333},
+		},
+		{
+			name:         "empty mode behaves like shares",
+			total:        90000,
+			participants: []PlanParticipant{{Portion: 2}, {Portion: 1}},
+			expected:     []Money{60000, 30000},
+		},
+		{
+			name:         "fixed amounts covering the total",
+			mode:         SplitModeFixed,
+			total:        100000,
+			participants: []PlanParticipant{{FixedAmount: money(70000)}, {FixedAmount: money(30000)}},
+			expected:     []Money{70000, 30000},
+		},
+		{
+			name:         "fixed amounts short of the total",
+			mode:         SplitModeFixed,
+			total:        100000,
+			participants: []PlanParticipant{{FixedAmount: money(70000)}, {FixedAmount: money(20000)}},
+			wantErr:      true,
+		},
+		{
+			name:         "fixed mode requires every amount",
+			mode:         SplitModeFixed,
+			total:        100000,
+			participants: []PlanParticipant{{FixedAmount: money(100000)}, {Portion: 1}},
+			wantErr:      true,
+		},
+		{
+			name:         "percentages",
+			mode:         SplitModePercentage,
+			total:        100000,
+			participants: []PlanParticipant{{Percentage: 50}, {Percentage: 33.33}, {Percentage: 16.67}},
+			expected:     []Money{50000, 33330, 16670},
+		},
+		{
+			name:         "percentages with remainder",
+			mode:         SplitModePercentage,
+			total:        1001,
+			participants: []PlanParticipant{{Percentage: 50}, {Percentage: 50}},
+			expected:     []Money{501, 500},
+		},
+		{
+			name:         "percentages not adding up to 100",
+			mode:         SplitModePercentage,
+			total:        100000,
+			participants: []PlanParticipant{{Percentage: 50}, {Percentage: 40}},
+			wantErr:      true,
+		},
+		{
+			name:         "mixed fixed and even split",
+			mode:         SplitModeMixed,
+			total:        200000,
+			participants: []PlanParticipant{{FixedAmount: money(50000)}, {Portion: 1}, {Portion: 1}, {Portion: 1}},
+			expected:     []Money{50000, 50000, 50000, 50000},
+		},
+		{
+			name:         "mixed remainder split by portion",
+			mode:         SplitModeMixed,
+			total:        100000,
+			participants: []PlanParticipant{{Portion: 1}, {FixedAmount: money(50000)}, {Portion: 2}},
+			expected:     []Money{16667, 50000, 33333},
+		},
+		{
+			name:         "mixed fixed amounts above the total",
+			mode:         SplitModeMixed,
+			total:        100000,
+			participants: []PlanParticipant{{FixedAmount: money(120000)}, {Portion: 1}},
+			wantErr:      true,
+		},
+		{
+			name:         "mixed without sharers must be covered by fixed amounts",
+			mode:         SplitModeMixed,
+			total:        100000,
+			participants: []PlanParticipant{{FixedAmount: money(50000)}},
+			wantErr:      true,
+		},
+		{
+			name:    "no participants",
+			mode:    SplitModeShares,
+			total:   100000,
+			wantErr: true,
+		},
+		{
+			name:         "unknown mode",
+			mode:         SplitMode("lottery"),
+			total:        100000,
+			participants: []PlanParticipant{{Portion: 1}},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Plan{SplitMode: tt.mode, TotalPrice: tt.total, Participants: tt.participants}
+			result, err := plan.SplitAmounts()
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("SplitAmounts() = %v; want error", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SplitAmounts() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("SplitAmounts() = %v; want %v", result, tt.expected)
+			}
+		})
+	}
+}
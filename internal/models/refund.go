@@ -17,7 +17,7 @@ type Refund struct {
 	PaymentDueID   uint           `gorm:"index" json:"payment_due_id"`
 	UserPaymentID  uint           `gorm:"index" json:"user_payment_id"`
 	UserID         uint           `gorm:"index" json:"user_id"`
-	TotalRefund    float64        `gorm:"type:decimal(15,2)" json:"total_refund"`
+	TotalRefund    Money          `gorm:"type:bigint" json:"total_refund"`
 	PaymentGateway PaymentGateway `gorm:"type:varchar(50)" json:"payment_gateway"`
 	ChannelPayment string         `gorm:"type:varchar(100)" json:"channel_payment"`
 	RefundDate     time.Time      `json:"refund_date"`
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+)
+
+// ReminderKind tells at which point of the dunning schedule a reminder was sent
+type ReminderKind string
+
+// Reminder kind constants
+const (
+	ReminderKindBeforeDue ReminderKind = "before_due"
+	ReminderKindOnDue     ReminderKind = "on_due"
+	ReminderKindOverdue   ReminderKind = "overdue"
+)
+
+// ReminderPolicy is the reminder schedule of a plan, stored on the plan with a reminder_ prefix
+type ReminderPolicy struct {
+	Enabled             bool `gorm:"default:false" json:"enabled"`
+	DaysBeforeDue       int  `gorm:"default:0" json:"days_before_due"`       // 0 sends no reminder before the due date
+	OnDueDate           bool `gorm:"default:false" json:"on_due_date"`       // remind on the due date itself
+	OverdueIntervalDays int  `gorm:"default:0" json:"overdue_interval_days"` // 0 sends no overdue reminders
+	MaxOverdueReminders int  `gorm:"default:0" json:"max_overdue_reminders"`
+}
+
+// ReminderStep is one point of a reminder schedule
+type ReminderStep struct {
+	Kind     ReminderKind
+	Sequence int // counts overdue reminders from 1, 0 for the other kinds
+	At       time.Time
+}
+
+// IsValid reports whether the policy settings are usable
+func (p ReminderPolicy) IsValid() bool {
+	if p.DaysBeforeDue < 0 || p.OverdueIntervalDays < 0 || p.MaxOverdueReminders < 0 {
+		return false
+	}
+	if p.MaxOverdueReminders > 0 && p.OverdueIntervalDays == 0 {
+		return false
+	}
+	return true
+}
+
+// Steps returns the reminder schedule of a due in chronological order.
+// Overdue reminders start when the due turns overdue and repeat every OverdueIntervalDays.
+func (p ReminderPolicy) Steps(dueDate, overdueAt time.Time) []ReminderStep {
+	if !p.Enabled {
+		return nil
+	}
+
+	var steps []ReminderStep
+	if p.DaysBeforeDue > 0 {
+		steps = append(steps, ReminderStep{Kind: ReminderKindBeforeDue, At: dueDate.AddDate(0, 0, -p.DaysBeforeDue)})
+	}
+	if p.OnDueDate {
+		steps = append(steps, ReminderStep{Kind: ReminderKindOnDue, At: dueDate})
+	}
+	if p.OverdueIntervalDays > 0 {
+		for i := 0; i < p.MaxOverdueReminders; i++ {
+			steps = append(steps, ReminderStep{
+				Kind:     ReminderKindOverdue,
+				Sequence: i + 1,
+				At:       overdueAt.AddDate(0, 0, i*p.OverdueIntervalDays),
+			})
+		}
+	}
+	return steps
+}
+
+// LatestStep returns the most recent step that is due at now but not before notBefore.
+// Older steps are never sent late, so a stopped worker does not flood members with reminders.
+func (p ReminderPolicy) LatestStep(dueDate, overdueAt, notBefore, now time.Time) (ReminderStep, bool) {
+	var latest ReminderStep
+	found := false
+	for _, step := range p.Steps(dueDate, overdueAt) {
+		if step.At.After(now) {
+			break
+		}
+		if step.At.Before(notBefore) {
+			continue
+		}
+		latest = step
+		found = true
+	}
+	return latest, found
+}
+
+// PaymentDueReminder records a reminder sent for a payment due
+type PaymentDueReminder struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentDueID    uint         `gorm:"uniqueIndex:idx_payment_due_reminder" json:"payment_due_id"`
+	Kind            ReminderKind `gorm:"type:varchar(20);uniqueIndex:idx_payment_due_reminder" json:"kind"`
+	Sequence        int          `gorm:"uniqueIndex:idx_payment_due_reminder" json:"sequence"`
+	ScheduledFor    time.Time    `json:"scheduled_for"` // when the policy called for the reminder
+	ScheduledTaskID *uint        `json:"scheduled_task_id"`
+
+	// Relationships
+	PaymentDue    PaymentDue     `gorm:"foreignKey:PaymentDueID" json:"payment_due,omitempty"`
+	ScheduledTask *ScheduledTask `gorm:"foreignKey:ScheduledTaskID;constraint:OnDelete:SET NULL" json:"scheduled_task,omitempty"`
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReminderPolicyLatestStep(t *testing.T) {
+	dueDate := time.Date(2025, 3, 5, 0, 0, 0, 0, time.UTC)
+	overdueAt := dueDate.AddDate(0, 0, 3)
+	issuedAt := dueDate.AddDate(0, 0, -7)
+	day := 24 * time.Hour
+
+	policy := ReminderPolicy{
+		Enabled:             true,
+		DaysBeforeDue:       2,
+		OnDueDate:           true,
+		OverdueIntervalDays: 3,
+		MaxOverdueReminders: 2,
+	}
+
+	tests := []struct {
+		name      string
+		policy    ReminderPolicy
+		notBefore time.Time
+		now       time.Time
+		ok        bool
+		kind      ReminderKind
+		sequence  int
+	}{
+		{
+			name:      "disabled policy sends nothing",
+			policy:    ReminderPolicy{DaysBeforeDue: 2, OnDueDate: true},
+			notBefore: issuedAt,
+			now:       dueDate,
+			ok:        false,
+		},
+		{
+			name:      "nothing before the first step",
+			policy:    policy,
+			notBefore: issuedAt,
+			now:       dueDate.Add(-3 * day),
+			ok:        false,
+		},
+		{
+			name:      "before due reminder",
+			policy:    policy,
+			notBefore: issuedAt,
+			now:       dueDate.Add(-day),
+			ok:        true,
+			kind:      ReminderKindBeforeDue,
+		},
+		{
+			name:      "on due reminder replaces the earlier step",
+			policy:    policy,
+			notBefore: issuedAt,
+			now:       dueDate.Add(time.Hour),
+			ok:        true,
+			kind:      ReminderKindOnDue,
+		},
+		{
+			name:      "first overdue reminder",
+			policy:    policy,
+			notBefore: issuedAt,
+			now:       overdueAt,
+			ok:        true,
+			kind:      ReminderKindOverdue,
+			sequence:  1,
+		},
+		{
+			name:      "stops after the last overdue reminder",
+			policy:    policy,
+			notBefore: issuedAt,
+			now:       overdueAt.Add(30 * day),
+			ok:        true,
+			kind:      ReminderKindOverdue,
+			sequence:  2,
+		},
+		{
+			name:      "steps before the due was issued are skipped",
+			policy:    ReminderPolicy{Enabled: true, DaysBeforeDue: 2},
+			notBefore: dueDate.Add(-day),
+			now:       dueDate,
+			ok:        false,
+		},
+		{
+			name:      "overdue reminders need an interval",
+			policy:    ReminderPolicy{Enabled: true, MaxOverdueReminders: 3},
+			notBefore: issuedAt,
+			now:       overdueAt.Add(day),
+			ok:        false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := tt.policy.LatestStep(dueDate, overdueAt, tt.notBefore, tt.now)
+			if ok != tt.ok {
+				t.Fatalf("LatestStep() ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if step.Kind != tt.kind || step.Sequence != tt.sequence {
+				t.Errorf("LatestStep() = %s #%d, want %s #%d", step.Kind, step.Sequence, tt.kind, tt.sequence)
+			}
+		})
+	}
+}
+
+func TestReminderPolicyIsValid(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   ReminderPolicy
+		expected bool
+	}{
+		{"zero value", ReminderPolicy{}, true},
+		{"full schedule", ReminderPolicy{Enabled: true, DaysBeforeDue: 3, OnDueDate: true, OverdueIntervalDays: 2, MaxOverdueReminders: 5}, true},
+		{"negative days before due", ReminderPolicy{DaysBeforeDue: -1}, false},
+		{"overdue reminders without interval", ReminderPolicy{MaxOverdueReminders: 2}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.IsValid(); got != tt.expected {
+				t.Errorf("IsValid() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/teambition/rrule-go"
@@ -25,6 +27,19 @@ const (
 	ScheduledTaskTypeRecurring ScheduledTaskType = "recurring"
 )
 
+// MisfirePolicy decides what happens to the occurrences of a recurring task
+// that were missed, e.g. because the worker was down
+type MisfirePolicy string
+
+const (
+	// MisfirePolicyCatchUpAll runs every missed occurrence, oldest first
+	MisfirePolicyCatchUpAll MisfirePolicy = "catch_up_all"
+	// MisfirePolicyRunOnce runs only the latest missed occurrence
+	MisfirePolicyRunOnce MisfirePolicy = "run_once"
+	// MisfirePolicySkip drops missed occurrences and waits for the next one
+	MisfirePolicySkip MisfirePolicy = "skip"
+)
+
 // ScheduledTask tracks tasks that need to be run at a specific time
 type ScheduledTask struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -40,6 +55,100 @@ type ScheduledTask struct {
 	Status            ScheduledTaskStatus    `gorm:"type:varchar(20);index:idx_scheduled_tasks_status_due,priority:1,where:deleted_at IS NULL" json:"status"`
 	TaskType          ScheduledTaskType      `gorm:"type:varchar(20);default:'onetime'" json:"task_type"`
 	MaxAttempt        int                    `json:"max_attempt"`
+	MisfirePolicy     MisfirePolicy          `gorm:"type:varchar(20);default:'run_once'" json:"misfire_policy"`
+	// Timezone the RRULE is expanded in (IANA name), empty means the app timezone
+	Timezone string `gorm:"type:varchar(64)" json:"timezone"`
+
+	// Attempt counts the consecutive failed attempts of the current occurrence.
+	// It is reset to 0 once the task succeeds.
+	Attempt int `gorm:"default:0" json:"attempt"`
+	// OriginalDue keeps the due date of the occurrence being retried, since retries move Due forward.
+	// It is nil when the task is not in a retry cycle.
+	OriginalDue *time.Time `json:"original_due"`
+
+	// Lease held by the worker currently executing the task.
+	// A task whose lease has expired can be claimed again by another worker.
+	ClaimedBy  *string    `gorm:"type:varchar(255)" json:"claimed_by"`
+	LeaseUntil *time.Time `gorm:"index" json:"lease_until"`
+}
+
+// ScheduledTaskNotifyChannel is the Postgres NOTIFY channel workers listen on
+// to pick up tasks that become due without waiting for their next poll
+const ScheduledTaskNotifyChannel = "scheduled_task_due"
+
+// AfterCreate wakes up listening workers when an active task is created already due.
+// A failed NOTIFY does not fail the insert, the task is still picked up on the next poll.
+func (t *ScheduledTask) AfterCreate(tx *gorm.DB) error {
+	if t.Status != ScheduledTaskStatusActive || t.Due.After(time.Now()) {
+		return nil
+	}
+
+	// A failed statement aborts the transaction the insert runs in, the savepoint keeps it usable
+	savepoint := tx.SavePoint("notify_scheduled_task").Error == nil
+	if err := NotifyScheduledTaskDue(tx, t.ID); err != nil {
+		log.Printf("Failed to notify workers of task %d: %v", t.ID, err)
+		if savepoint {
+			tx.RollbackTo("notify_scheduled_task")
+		}
+	}
+	return nil
+}
+
+// NotifyScheduledTaskDue sends a NOTIFY for the given task. Inside a transaction
+// the notification is only delivered once the transaction commits.
+func NotifyScheduledTaskDue(db *gorm.DB, taskID uint) error {
+	return db.Exec("SELECT pg_notify(?, ?)", ScheduledTaskNotifyChannel, strconv.FormatUint(uint64(taskID), 10)).Error
+}
+
+// Leased reports whether a worker is running the task, holding a lease that has not expired
+func (t ScheduledTask) Leased(now time.Time) bool {
+	return t.ClaimedBy != nil && t.LeaseUntil != nil && t.LeaseUntil.After(now)
+}
+
+// OccurrenceDue returns the scheduled date of the current occurrence,
+// which differs from Due while the task is waiting for a retry
+func (t ScheduledTask) OccurrenceDue() time.Time {
+	if t.OriginalDue != nil {
+		return *t.OriginalDue
+	}
+	return t.Due
+}
+
+// Location returns the timezone the task is scheduled in
+func (t ScheduledTask) Location() *time.Location {
+	return LoadLocation(t.Timezone)
+}
+
+// MoveToTimezone changes the timezone the task is scheduled in. The pending occurrence keeps its
+// local date and clock time, so the schedule continues at the same local time in the new timezone.
+func (t *ScheduledTask) MoveToTimezone(name string) {
+	from, to := t.Location(), LoadLocation(name)
+	rebase := func(v time.Time) time.Time {
+		local := v.In(from)
+		return time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), to)
+	}
+	if t.OriginalDue != nil {
+		// The retry keeps its time, only the occurrence it retries moves
+		occurrence := rebase(*t.OriginalDue)
+		t.OriginalDue = &occurrence
+	} else {
+		t.Due = rebase(t.Due)
+	}
+	t.Timezone = name
+}
+
+// recurrenceRule parses the RRULE of a recurring task, anchored at the current occurrence
+func (t ScheduledTask) recurrenceRule() (*rrule.RRule, bool) {
+	if t.TaskType != ScheduledTaskTypeRecurring || t.RecurringInterval == nil || *t.RecurringInterval == "" {
+		return nil, false
+	}
+	rule, err := rrule.StrToRRule(*t.RecurringInterval)
+	if err != nil {
+		return nil, false
+	}
+	// Expand in the task timezone, so e.g. monthly occurrences keep their local day and hour
+	rule.DTStart(t.OccurrenceDue().In(t.Location()))
+	return rule, true
 }
 
 // NextDue calculates the next due date for the scheduled task
@@ -48,20 +157,116 @@ func (t ScheduledTask) NextDue() time.Time {
 		return t.Due
 	}
 
-	if t.RecurringInterval != nil && *t.RecurringInterval != "" {
-		rule, err := rrule.StrToRRule(*t.RecurringInterval)
-		if err == nil {
-			rule.DTStart(t.Due)
-			next := rule.After(time.Now(), true)
-			if !next.IsZero() {
-				return next
-			}
+	if rule, ok := t.recurrenceRule(); ok {
+		next := rule.After(time.Now(), true)
+		if !next.IsZero() {
+			return next
 		}
 	}
 	// Fallback to current Due if parsing fails
 	return t.Due
 }
 
+// NextDueAfterRun returns the due date following a successful run of the current occurrence.
+// Tasks that catch up on missed occurrences continue with the next occurrence even if it
+// is already past, the others continue with the first occurrence after now. Either way it is
+// after the occurrence that ran, which matters when an occurrence is run ahead of its date.
+// A rule without occurrences left returns the occurrence itself.
+func (t ScheduledTask) NextDueAfterRun() time.Time {
+	rule, ok := t.recurrenceRule()
+	if !ok {
+		return t.NextDue()
+	}
+
+	occurrence := t.OccurrenceDue()
+	from := occurrence
+	if now := time.Now(); t.MisfirePolicy != MisfirePolicyCatchUpAll && now.After(from) {
+		from = now
+	}
+	next := rule.After(from, false)
+	if next.IsZero() {
+		return occurrence
+	}
+	return next
+}
+
+// Occurrences lists the occurrences of a recurring task in [from, until]
+func (t ScheduledTask) Occurrences(from, until time.Time) []time.Time {
+	rule, ok := t.recurrenceRule()
+	if !ok {
+		return nil
+	}
+	return rule.Between(from, until, true)
+}
+
+// MisfireDecision tells the worker how to handle a recurring task that is picked up late
+type MisfireDecision struct {
+	// Run is false when the task should only be rescheduled to NextDue
+	Run bool
+	// Occurrence is the occurrence date the run is for
+	Occurrence time.Time
+	// NextDue is where to move the task when it does not run. Zero means no occurrences are left.
+	NextDue time.Time
+	// Skipped lists the missed occurrences that will never run
+	Skipped []time.Time
+}
+
+// ResolveMisfire applies the task's misfire policy at time now. An occurrence counts as
+// missed once it is more than threshold overdue. Tasks in a retry cycle always run their
+// current occurrence.
+func (t ScheduledTask) ResolveMisfire(now time.Time, threshold time.Duration) MisfireDecision {
+	occurrence := t.OccurrenceDue()
+	decision := MisfireDecision{Run: true, Occurrence: occurrence}
+
+	rule, ok := t.recurrenceRule()
+	if !ok || t.Attempt > 0 || now.Sub(occurrence) <= threshold {
+		return decision
+	}
+
+	switch t.MisfirePolicy {
+	case MisfirePolicyCatchUpAll:
+		// Run the oldest occurrence now, NextDueAfterRun moves on to the next missed one
+		return decision
+
+	case MisfirePolicySkip:
+		cutoff := now.Add(-threshold)
+		decision.Skipped = rule.Between(occurrence, cutoff, true)
+		if len(decision.Skipped) > 0 && !decision.Skipped[len(decision.Skipped)-1].Before(cutoff) {
+			decision.Skipped = decision.Skipped[:len(decision.Skipped)-1]
+		}
+
+		next := rule.After(cutoff, true)
+		if !next.IsZero() && !next.After(now) {
+			// An occurrence that is due but not yet missed still runs
+			decision.Occurrence = next
+			return decision
+		}
+		decision.Run = false
+		decision.NextDue = next
+		return decision
+
+	default:
+		// MisfirePolicyRunOnce: a single run for the latest occurrence
+		latest := rule.Before(now, true)
+		if latest.IsZero() || !latest.After(occurrence) {
+			return decision
+		}
+		decision.Skipped = rule.Between(occurrence, latest, false)
+		decision.Skipped = append([]time.Time{occurrence}, decision.Skipped...)
+		decision.Occurrence = latest
+		return decision
+	}
+}
+
+// ScheduledTaskHistory status values
+const (
+	ScheduledTaskHistoryStatusSuccess         = "success"
+	ScheduledTaskHistoryStatusFailure         = "failure"
+	ScheduledTaskHistoryStatusHandlerNotFound = "handler_not_found"
+	ScheduledTaskHistoryStatusTimeout         = "timeout"
+	ScheduledTaskHistoryStatusSkipped         = "skipped"
+)
+
 // ScheduledTaskHistory tracks the execution history of scheduled tasks
 type ScheduledTaskHistory struct {
 	ID              uint           `gorm:"primarykey" json:"id"`
@@ -77,4 +282,5 @@ type ScheduledTaskHistory struct {
 	AttemptNumber int                    `json:"attempt_number"`
 	Arguments     map[string]interface{} `gorm:"serializer:json" json:"arguments"`
 	Result        map[string]interface{} `gorm:"serializer:json" json:"result"`
+	NextRetryAt   *time.Time             `json:"next_retry_at"` // set when a failed attempt was rescheduled
 }
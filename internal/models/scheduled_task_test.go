@@ -0,0 +1,181 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduledTaskResolveMisfire(t *testing.T) {
+	monthly := "FREQ=MONTHLY"
+	jan5 := time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC)
+	feb5 := time.Date(2025, 2, 5, 9, 0, 0, 0, time.UTC)
+	mar5 := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+	apr5 := time.Date(2025, 4, 5, 9, 0, 0, 0, time.UTC)
+	threshold := 5 * time.Minute
+
+	newTask := func(policy MisfirePolicy) ScheduledTask {
+		return ScheduledTask{
+			TaskType:          ScheduledTaskTypeRecurring,
+			RecurringInterval: &monthly,
+			Due:               jan5,
+			MisfirePolicy:     policy,
+		}
+	}
+
+	tests := []struct {
+		name           string
+		task           ScheduledTask
+		now            time.Time
+		wantRun        bool
+		wantOccurrence time.Time
+		wantNextDue    time.Time
+		wantSkipped    []time.Time
+	}{
+		{
+			name:           "on time run is not a misfire",
+			task:           newTask(MisfirePolicySkip),
+			now:            jan5.Add(time.Minute),
+			wantRun:        true,
+			wantOccurrence: jan5,
+		},
+		{
+			name:           "catch up all runs the oldest occurrence",
+			task:           newTask(MisfirePolicyCatchUpAll),
+			now:            mar5.Add(time.Hour),
+			wantRun:        true,
+			wantOccurrence: jan5,
+		},
+		{
+			name:           "run once runs the latest occurrence",
+			task:           newTask(MisfirePolicyRunOnce),
+			now:            mar5.Add(time.Hour),
+			wantRun:        true,
+			wantOccurrence: mar5,
+			wantSkipped:    []time.Time{jan5, feb5},
+		},
+		{
+			name:           "run once with a single late occurrence",
+			task:           newTask(MisfirePolicyRunOnce),
+			now:            jan5.Add(time.Hour),
+			wantRun:        true,
+			wantOccurrence: jan5,
+		},
+		{
+			name:        "skip drops every missed occurrence",
+			task:        newTask(MisfirePolicySkip),
+			now:         mar5.Add(time.Hour),
+			wantRun:     false,
+			wantNextDue: apr5,
+			wantSkipped: []time.Time{jan5, feb5, mar5},
+		},
+		{
+			name:           "skip still runs an occurrence that is due but not missed",
+			task:           newTask(MisfirePolicySkip),
+			now:            mar5.Add(time.Minute),
+			wantRun:        true,
+			wantOccurrence: mar5,
+			wantSkipped:    []time.Time{jan5, feb5},
+		},
+		{
+			name: "retries keep their occurrence",
+			task: func() ScheduledTask {
+				task := newTask(MisfirePolicySkip)
+				task.Attempt = 1
+				return task
+			}(),
+			now:            mar5.Add(time.Hour),
+			wantRun:        true,
+			wantOccurrence: jan5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision := tt.task.ResolveMisfire(tt.now, threshold)
+			if decision.Run != tt.wantRun {
+				t.Errorf("Run = %v; want %v", decision.Run, tt.wantRun)
+			}
+			if tt.wantRun && !decision.Occurrence.Equal(tt.wantOccurrence) {
+				t.Errorf("Occurrence = %s; want %s", decision.Occurrence, tt.wantOccurrence)
+			}
+			if !tt.wantRun && !decision.NextDue.Equal(tt.wantNextDue) {
+				t.Errorf("NextDue = %s; want %s", decision.NextDue, tt.wantNextDue)
+			}
+			if len(decision.Skipped) != len(tt.wantSkipped) {
+				t.Fatalf("Skipped = %v; want %v", decision.Skipped, tt.wantSkipped)
+			}
+			for i := range tt.wantSkipped {
+				if !decision.Skipped[i].Equal(tt.wantSkipped[i]) {
+					t.Errorf("Skipped[%d] = %s; want %s", i, decision.Skipped[i], tt.wantSkipped[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScheduledTaskNextDueAfterRun(t *testing.T) {
+	monthly := "FREQ=MONTHLY"
+	jan5 := time.Date(2020, 1, 5, 9, 0, 0, 0, time.UTC)
+	feb5 := time.Date(2020, 2, 5, 9, 0, 0, 0, time.UTC)
+
+	task := ScheduledTask{
+		TaskType:          ScheduledTaskTypeRecurring,
+		RecurringInterval: &monthly,
+		Due:               jan5,
+		MisfirePolicy:     MisfirePolicyCatchUpAll,
+	}
+
+	if next := task.NextDueAfterRun(); !next.Equal(feb5) {
+		t.Errorf("catch_up_all NextDueAfterRun() = %s; want %s", next, feb5)
+	}
+
+	task.MisfirePolicy = MisfirePolicyRunOnce
+	if next := task.NextDueAfterRun(); !next.After(time.Now()) {
+		t.Errorf("run_once NextDueAfterRun() = %s; want a date after now", next)
+	}
+}
+
+func TestScheduledTaskNextDueAfterRunAhead(t *testing.T) {
+	// A task run now for an occurrence that is still ahead continues after that occurrence
+	daily := "FREQ=DAILY"
+	now := time.Now()
+	occurrence := now.Add(6 * time.Hour).Truncate(time.Second)
+
+	for _, policy := range []MisfirePolicy{MisfirePolicyCatchUpAll, MisfirePolicyRunOnce, MisfirePolicySkip} {
+		t.Run(string(policy), func(t *testing.T) {
+			task := ScheduledTask{
+				TaskType:          ScheduledTaskTypeRecurring,
+				RecurringInterval: &daily,
+				Due:               now,
+				OriginalDue:       &occurrence,
+				MisfirePolicy:     policy,
+			}
+			next := task.NextDueAfterRun()
+			if want := occurrence.AddDate(0, 0, 1); !next.Equal(want) {
+				t.Errorf("NextDueAfterRun() = %s; want %s", next, want)
+			}
+		})
+	}
+}
+
+func TestScheduledTaskMoveToTimezone(t *testing.T) {
+	jakarta, _ := time.LoadLocation("Asia/Jakarta")
+	makassar, _ := time.LoadLocation("Asia/Makassar")
+	occurrence := time.Date(2026, 4, 1, 9, 0, 0, 0, jakarta)
+	retry := time.Date(2026, 4, 1, 9, 5, 0, 0, jakarta)
+
+	task := ScheduledTask{Due: occurrence, Timezone: "Asia/Jakarta"}
+	task.MoveToTimezone("Asia/Makassar")
+	if want := time.Date(2026, 4, 1, 9, 0, 0, 0, makassar); !task.Due.Equal(want) || task.Timezone != "Asia/Makassar" {
+		t.Errorf("Due = %s in %q; want %s in Asia/Makassar", task.Due, task.Timezone, want)
+	}
+
+	retrying := ScheduledTask{Due: retry, OriginalDue: &occurrence, Timezone: "Asia/Jakarta"}
+	retrying.MoveToTimezone("Asia/Makassar")
+	if want := time.Date(2026, 4, 1, 9, 0, 0, 0, makassar); !retrying.OriginalDue.Equal(want) {
+		t.Errorf("OriginalDue = %s; want %s", retrying.OriginalDue, want)
+	}
+	if !retrying.Due.Equal(retry) {
+		t.Errorf("Due = %s; want the retry to stay at %s", retrying.Due, retry)
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// TelegramStartTTL is how long a "/start <code>" message can be used to link a chat
+const TelegramStartTTL = time.Hour
+
+// TelegramStart is a "/start <code>" message received by the Telegram bot. Users send it by opening
+// the link in their notification settings, which then look up the chat by the code.
+type TelegramStart struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	UpdateID int64  `gorm:"uniqueIndex" json:"update_id"` // Telegram resends an update until it is acknowledged
+	Code     string `gorm:"type:varchar(64);index" json:"code"`
+	ChatID   string `gorm:"type:varchar(64)" json:"chat_id"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultAppTimezone is used when APP_TIMEZONE is not set
+const DefaultAppTimezone = "Asia/Jakarta"
+
+// SupportedTimezones are the timezones offered when editing a plan
+var SupportedTimezones = []string{
+	"Asia/Jakarta",
+	"Asia/Makassar",
+	"Asia/Jayapura",
+	"Asia/Singapore",
+	"UTC",
+}
+
+var (
+	appLocation     *time.Location
+	appLocationOnce sync.Once
+)
+
+// AppLocation returns the configured application timezone (APP_TIMEZONE, default Asia/Jakarta)
+func AppLocation() *time.Location {
+	appLocationOnce.Do(func() {
+		name := os.Getenv("APP_TIMEZONE")
+		if name == "" {
+			name = DefaultAppTimezone
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			log.Printf("Invalid APP_TIMEZONE %q, using UTC: %v", name, err)
+			loc = time.UTC
+		}
+		appLocation = loc
+	})
+	return appLocation
+}
+
+// LoadLocation returns the named timezone, falling back to the application timezone
+// when the name is empty or unknown
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return AppLocation()
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Unknown timezone %q, using %s: %v", name, AppLocation(), err)
+		return AppLocation()
+	}
+	return loc
+}
+
+// IsValidTimezone reports whether name is a timezone known to the system
+func IsValidTimezone(name string) bool {
+	if name == "" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
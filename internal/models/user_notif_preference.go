@@ -11,6 +11,7 @@ type NotificationChannel string
 const (
 	NotificationChannelEmail    NotificationChannel = "email"
 	NotificationChannelWhatsapp NotificationChannel = "whatsapp"
+	NotificationChannelTelegram NotificationChannel = "telegram"
 	NotificationChannelNone     NotificationChannel = "none"
 )
 
@@ -34,4 +35,7 @@ type UserNotifPreference struct {
 	// WhatsApp specific options
 	WhatsappTargetType string `gorm:"type:varchar(20);default:'personal'" json:"whatsapp_target_type"` // 'personal' or 'group'
 	WhatsappGroupID    string `gorm:"type:varchar(100)" json:"whatsapp_group_id"`                      // Group ID if target type is group
+
+	// Telegram specific options
+	TelegramChatID string `gorm:"type:varchar(50)" json:"telegram_chat_id"` // Chat the bot sends to, the user must have started the bot
 }
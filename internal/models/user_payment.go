@@ -16,7 +16,7 @@ type UserPayment struct {
 	PlanID         uint           `gorm:"index" json:"plan_id"`
 	PaymentDueID   uint           `gorm:"index" json:"payment_due_id"`
 	UserID         uint           `gorm:"index" json:"user_id"`
-	TotalPay       float64        `gorm:"type:decimal(15,2)" json:"total_pay"`
+	TotalPay       Money          `gorm:"type:bigint" json:"total_pay"`
 	PaymentGateway PaymentGateway `gorm:"type:varchar(50)" json:"payment_gateway"`  // e.g., "midtrans", "manual"
 	ChannelPayment string         `gorm:"type:varchar(100)" json:"channel_payment"` // e.g., "bank_transfer", "e-wallet"
 	PaymentDate    time.Time      `json:"payment_date"`
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// WhatsappMessageRetention is how long incoming message IDs are kept to drop redelivered webhooks
+const WhatsappMessageRetention = 24 * time.Hour
+
+// WhatsappInboundMessage is a WhatsApp message received through the WAHA webhook. WAHA retries a
+// webhook that fails or times out, the message ID makes sure the bot answers each message once.
+type WhatsappInboundMessage struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	MessageID       string `gorm:"type:varchar(255);uniqueIndex" json:"message_id"`
+	ChatID          string `gorm:"type:varchar(100)" json:"chat_id"`
+	ScheduledTaskID *uint  `json:"scheduled_task_id"`
+}
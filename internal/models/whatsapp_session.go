@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Statuses of a WAHA session, see https://waha.devlike.pro/docs/how-to/sessions/
+const (
+	WhatsappSessionStatusStopped     = "STOPPED"
+	WhatsappSessionStatusStarting    = "STARTING"
+	WhatsappSessionStatusScanQR      = "SCAN_QR_CODE"
+	WhatsappSessionStatusWorking     = "WORKING"
+	WhatsappSessionStatusFailed      = "FAILED"
+	WhatsappSessionStatusUnreachable = "UNREACHABLE" // WAHA did not answer, not reported by WAHA itself
+)
+
+// Alerts sent to admins about the WhatsApp session
+const (
+	WhatsappSessionAlertDown      = "down"
+	WhatsappSessionAlertRecovered = "recovered"
+)
+
+// WhatsappSessionCheck is the outcome of one health check of the WAHA session
+type WhatsappSessionCheck struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Session string `gorm:"type:varchar(100);index" json:"session"`
+	Status  string `gorm:"type:varchar(30)" json:"status"`
+	Account string `gorm:"type:varchar(255)" json:"account"`
+	Error   string `gorm:"type:text" json:"error"`
+
+	// Alert is the alert sent to admins after this check, empty when none was sent
+	Alert string `gorm:"type:varchar(20)" json:"alert"`
+}
+
+// Working reports whether the session could send messages at the time of the check
+func (c WhatsappSessionCheck) Working() bool {
+	return c.Status == WhatsappSessionStatusWorking
+}
@@ -48,9 +48,17 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.PlanParticipant{},
 		&models.ScheduledTask{},
 		&models.ScheduledTaskHistory{},
+		&models.PaymentDueReminder{},
 		&models.PaymentCallbackHistory{},
 		&models.PaymentSession{},
 		&models.UserNotifPreference{},
+		&models.NotificationTemplate{},
+		&models.NotificationDelivery{},
+		&models.NotificationDeliveryAttempt{},
+		&models.PlanGroupDigest{},
+		&models.WhatsappSessionCheck{},
+		&models.TelegramStart{},
+		&models.WhatsappInboundMessage{},
 	)
 	if err != nil {
 		return err
@@ -1,9 +1,32 @@
 package services
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
 	"fmt"
+	"log"
+	"net"
 	"net/smtp"
 	"os"
+	"strings"
+	"time"
+)
+
+// SMTPSecurity is how the connection to the SMTP server is secured
+type SMTPSecurity string
+
+// SMTP security modes, set with SMTP_SECURITY
+const (
+	// SMTPSecurityAuto uses implicit TLS on port 465 and STARTTLS when the server offers it otherwise
+	SMTPSecurityAuto SMTPSecurity = "auto"
+	// SMTPSecuritySTARTTLS upgrades a plain connection and fails when the server cannot
+	SMTPSecuritySTARTTLS SMTPSecurity = "starttls"
+	// SMTPSecurityTLS connects over TLS from the start, usually on port 465
+	SMTPSecurityTLS SMTPSecurity = "tls"
+	// SMTPSecurityNone never uses TLS, only meant for local mail catchers
+	SMTPSecurityNone SMTPSecurity = "none"
 )
 
 type EmailService struct {
@@ -12,6 +35,10 @@ type EmailService struct {
 	user     string
 	password string
 	from     string
+	security SMTPSecurity
+
+	// tlsConfig overrides the TLS settings, used by tests to trust their own server
+	tlsConfig *tls.Config
 }
 
 func NewEmailService() *EmailService {
@@ -21,28 +48,167 @@ func NewEmailService() *EmailService {
 		user:     os.Getenv("SMTP_USER"),
 		password: os.Getenv("SMTP_PASS"),
 		from:     os.Getenv("EMAIL_FROM"),
+		security: smtpSecurityFromEnv(),
+	}
+}
+
+// smtpSecurityFromEnv reads SMTP_SECURITY, falling back to auto
+func smtpSecurityFromEnv() SMTPSecurity {
+	val := SMTPSecurity(strings.ToLower(os.Getenv("SMTP_SECURITY")))
+	switch val {
+	case SMTPSecurityAuto, SMTPSecuritySTARTTLS, SMTPSecurityTLS, SMTPSecurityNone:
+		return val
+	case "":
+		return SMTPSecurityAuto
+	}
+	log.Printf("Invalid SMTP_SECURITY %q, using %s", val, SMTPSecurityAuto)
+	return SMTPSecurityAuto
+}
+
+// SendEmail sends a plain text email and returns its Message-ID. The SMTP conversation is aborted when ctx is done.
+func (s *EmailService) SendEmail(ctx context.Context, to []string, subject, body string) (string, error) {
+	return s.Send(ctx, EmailMessage{To: to, Subject: subject, Text: body})
+}
+
+// SendHTMLEmail sends an HTML email with a plain text alternative and returns its Message-ID
+func (s *EmailService) SendHTMLEmail(ctx context.Context, to []string, subject, body string) (string, error) {
+	return s.Send(ctx, EmailMessage{To: to, Subject: subject, Text: HTMLToText(body), HTML: body})
+}
+
+// Send delivers a message and returns its Message-ID. From, Date and Message-ID are filled in when empty.
+func (s *EmailService) Send(ctx context.Context, msg EmailMessage) (string, error) {
+	if s.host == "" || s.port == "" {
+		return "", fmt.Errorf("SMTP server not configured")
+	}
+	if s.user != "" && s.password == "" {
+		return "", fmt.Errorf("SMTP credentials not fully configured")
+	}
+
+	if msg.From == "" {
+		msg.From = s.from
+	}
+	if msg.Date.IsZero() {
+		msg.Date = time.Now()
+	}
+	if msg.MessageID == "" {
+		msg.MessageID = newMessageID(msg.From)
+	}
+
+	message, err := msg.Bytes()
+	if err != nil {
+		return "", fmt.Errorf("failed to build email: %w", err)
+	}
+
+	if err := s.sendMail(ctx, msg.To, message); err != nil {
+		return "", fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return msg.MessageID, nil
+}
+
+// newMessageID returns a unique Message-ID in the domain of the sender address
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.Trim(from[at+1:], "> ")
 	}
+
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(buf), domain)
 }
 
-func (s *EmailService) SendEmail(to []string, subject, body string) error {
-	if s.host == "" || s.port == "" || s.user == "" || s.password == "" {
-		return fmt.Errorf("SMTP credentials not fully configured")
+// tlsClientConfig returns the TLS settings for the SMTP server
+func (s *EmailService) tlsClientConfig() *tls.Config {
+	if s.tlsConfig != nil {
+		return s.tlsConfig
 	}
+	return &tls.Config{ServerName: s.host}
+}
 
-	auth := smtp.PlainAuth("", s.user, s.password, s.host)
+// sendMail does what smtp.SendMail does, over a connection bound to ctx and secured as configured
+func (s *EmailService) sendMail(ctx context.Context, to []string, message []byte) error {
+	addr := net.JoinHostPort(s.host, s.port)
+	implicitTLS := s.security == SMTPSecurityTLS || (s.security == SMTPSecurityAuto && s.port == "465")
 
-	// Build the message
-	message := []byte(fmt.Sprintf("To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"\r\n"+
-		"%s\r\n", to[0], subject, body))
+	var conn net.Conn
+	var err error
+	if implicitTLS {
+		dialer := &tls.Dialer{Config: s.tlsClientConfig()}
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	} else {
+		var dialer net.Dialer
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
 
-	addr := fmt.Sprintf("%s:%s", s.host, s.port)
+	// Closing the connection unblocks any pending read or write once ctx is done
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
 
-	err := smtp.SendMail(addr, auth, s.from, to, message)
+	client, err := smtp.NewClient(conn, s.host)
 	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+		conn.Close()
+		return contextError(ctx, err)
 	}
+	defer client.Close()
 
-	return nil
+	if !implicitTLS && s.security != SMTPSecurityNone {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(s.tlsClientConfig()); err != nil {
+				return contextError(ctx, err)
+			}
+		} else if s.security == SMTPSecuritySTARTTLS {
+			return fmt.Errorf("server does not support STARTTLS")
+		}
+	}
+	if s.user != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.user, s.password, s.host)); err != nil {
+			return contextError(ctx, err)
+		}
+	}
+	if err := client.Mail(smtpAddress(s.from)); err != nil {
+		return contextError(ctx, err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(smtpAddress(rcpt)); err != nil {
+			return contextError(ctx, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return contextError(ctx, err)
+	}
+	if _, err := w.Write(message); err != nil {
+		return contextError(ctx, err)
+	}
+	if err := w.Close(); err != nil {
+		return contextError(ctx, err)
+	}
+
+	return client.Quit()
+}
+
+// smtpAddress returns the bare address of "Name <user@example.com>", as MAIL FROM and RCPT TO expect
+func smtpAddress(address string) string {
+	if start := strings.LastIndex(address, "<"); start >= 0 {
+		if end := strings.LastIndex(address, ">"); end > start {
+			return address[start+1 : end]
+		}
+	}
+	return strings.TrimSpace(address)
+}
+
+// contextError reports the context error instead of the network error it caused
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
 }
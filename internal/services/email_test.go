@@ -0,0 +1,376 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSMTPMessage is a message received by fakeSMTPServer
+type fakeSMTPMessage struct {
+	From string
+	To   []string
+	Data []byte
+	TLS  bool
+	Auth bool
+}
+
+// fakeSMTPServer is a minimal SMTP server on localhost that records the messages it receives
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	starttls  bool // offer STARTTLS on plain connections
+
+	mu       sync.Mutex
+	messages []fakeSMTPMessage
+}
+
+// newFakeSMTPServer starts a server, over TLS from the start when implicitTLS is set
+func newFakeSMTPServer(t *testing.T, tlsConfig *tls.Config, implicitTLS, starttls bool) *fakeSMTPServer {
+	t.Helper()
+
+	var listener net.Listener
+	var err error
+	if implicitTLS {
+		listener, err = tls.Listen("tcp", "127.0.0.1:0", tlsConfig)
+	} else {
+		listener, err = net.Listen("tcp", "127.0.0.1:0")
+	}
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	server := &fakeSMTPServer{listener: listener, tlsConfig: tlsConfig, starttls: starttls}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handle(conn, implicitTLS)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTPServer) port() string {
+	return s.listener.Addr().(*net.TCPAddr).String()[len("127.0.0.1:"):]
+}
+
+func (s *fakeSMTPServer) received() []fakeSMTPMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeSMTPMessage(nil), s.messages...)
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn, isTLS bool) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	msg := fakeSMTPMessage{TLS: isTLS}
+
+	tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			if s.starttls && !msg.TLS {
+				tp.PrintfLine("250-fake")
+				tp.PrintfLine("250-STARTTLS")
+			} else {
+				tp.PrintfLine("250-fake")
+			}
+			tp.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			tp.PrintfLine("220 ready")
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			tp = textproto.NewConn(conn)
+			msg.TLS = true
+		case "AUTH":
+			msg.Auth = true
+			tp.PrintfLine("235 accepted")
+		case "MAIL":
+			msg.From = strings.TrimSuffix(strings.TrimPrefix(line[len("MAIL FROM:"):], "<"), ">")
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.To = append(msg.To, strings.TrimSuffix(strings.TrimPrefix(line[len("RCPT TO:"):], "<"), ">"))
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// testTLSConfigs returns a server config with a self-signed certificate for 127.0.0.1 and a client config trusting it
+func testTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client := &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"}
+	return server, client
+}
+
+func TestEmailServiceSend(t *testing.T) {
+	serverTLS, clientTLS := testTLSConfigs(t)
+
+	tests := []struct {
+		name        string
+		security    SMTPSecurity
+		implicitTLS bool
+		starttls    bool
+		wantTLS     bool
+		wantErr     bool
+	}{
+		{name: "plain", security: SMTPSecurityNone, starttls: true, wantTLS: false},
+		{name: "auto upgrades with STARTTLS", security: SMTPSecurityAuto, starttls: true, wantTLS: true},
+		{name: "auto without STARTTLS", security: SMTPSecurityAuto, wantTLS: false},
+		{name: "STARTTLS required", security: SMTPSecuritySTARTTLS, starttls: true, wantTLS: true},
+		{name: "STARTTLS required but not offered", security: SMTPSecuritySTARTTLS, wantErr: true},
+		{name: "implicit TLS", security: SMTPSecurityTLS, implicitTLS: true, wantTLS: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSMTPServer(t, serverTLS, tt.implicitTLS, tt.starttls)
+			service := &EmailService{
+				host:      "127.0.0.1",
+				port:      server.port(),
+				user:      "user",
+				password:  "secret",
+				from:      "Patungan <noreply@patungan.test>",
+				security:  tt.security,
+				tlsConfig: clientTLS,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			messageID, err := service.SendHTMLEmail(ctx, []string{"budi@example.com"}, "Tagihan", "<p>Halo Budi</p>")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SendHTMLEmail() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			received := server.received()
+			if len(received) != 1 {
+				t.Fatalf("server received %d messages, want 1", len(received))
+			}
+			msg := received[0]
+			if msg.TLS != tt.wantTLS {
+				t.Errorf("TLS = %v, want %v", msg.TLS, tt.wantTLS)
+			}
+			if !msg.Auth {
+				t.Errorf("client did not authenticate")
+			}
+			if msg.From != "noreply@patungan.test" {
+				t.Errorf("MAIL FROM = %q, want noreply@patungan.test", msg.From)
+			}
+			if len(msg.To) != 1 || msg.To[0] != "budi@example.com" {
+				t.Errorf("RCPT TO = %v, want [budi@example.com]", msg.To)
+			}
+
+			parsed, err := mail.ReadMessage(strings.NewReader(string(msg.Data)))
+			if err != nil {
+				t.Fatalf("failed to parse message: %v", err)
+			}
+			if got := parsed.Header.Get("Message-ID"); got != messageID || !strings.HasSuffix(got, "@patungan.test>") {
+				t.Errorf("Message-ID = %q, returned %q", got, messageID)
+			}
+			if _, err := parsed.Header.Date(); err != nil {
+				t.Errorf("invalid Date header: %v", err)
+			}
+		})
+	}
+}
+
+func TestEmailMessageBytes(t *testing.T) {
+	msg := EmailMessage{
+		From:    "noreply@patungan.test",
+		To:      []string{"budi@example.com"},
+		Subject: "Tagihan Rp 50.000 — Netflix",
+		Text:    "Halo Budi, tagihan kamu Rp 50.000",
+		HTML:    "<p>Halo Budi, tagihan kamu <b>Rp 50.000</b></p>",
+		Attachments: []EmailAttachment{
+			{Filename: "kwitansi.pdf", ContentType: "application/pdf", Data: []byte(strings.Repeat("%PDF-1.4 receipt ", 20))},
+		},
+	}
+
+	data, err := msg.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q, want multipart/mixed", parsed.Header.Get("Content-Type"))
+	}
+	mixed := multipart.NewReader(parsed.Body, params["boundary"])
+
+	// The first part holds the text and HTML alternatives
+	bodyPart, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("missing body part: %v", err)
+	}
+	mediaType, params, _ = mime.ParseMediaType(bodyPart.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("body Content-Type = %q, want multipart/alternative", mediaType)
+	}
+	alternative := multipart.NewReader(bodyPart, params["boundary"])
+	for _, want := range []struct{ contentType, content string }{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	} {
+		part, err := alternative.NextPart()
+		if err != nil {
+			t.Fatalf("missing %s part: %v", want.contentType, err)
+		}
+		if mediaType, params, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); mediaType != want.contentType || params["charset"] != "UTF-8" {
+			t.Errorf("part Content-Type = %q, want %s; charset=UTF-8", part.Header.Get("Content-Type"), want.contentType)
+		}
+		content, _ := io.ReadAll(part)
+		if string(content) != want.content {
+			t.Errorf("%s part = %q, want %q", want.contentType, content, want.content)
+		}
+	}
+
+	attachment, err := mixed.NextPart()
+	if err != nil {
+		t.Fatalf("missing attachment: %v", err)
+	}
+	if attachment.FileName() != "kwitansi.pdf" {
+		t.Errorf("attachment filename = %q, want kwitansi.pdf", attachment.FileName())
+	}
+	encoded, _ := io.ReadAll(attachment)
+	for _, line := range strings.Split(strings.TrimSpace(string(encoded)), "\r\n") {
+		if len(line) > 76 {
+			t.Errorf("base64 line is %d characters long, want at most 76", len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+	if err != nil || string(decoded) != string(msg.Attachments[0].Data) {
+		t.Errorf("attachment content does not round trip (%v)", err)
+	}
+}
+
+func TestEmailMessageBytesSinglePart(t *testing.T) {
+	data, err := EmailMessage{To: []string{"budi@example.com"}, Subject: "Hi", Text: "Halo\nBudi"}.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes() error = %v", err)
+	}
+	parsed, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(data))))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	if got := parsed.Header.Get("Content-Type"); got != "text/plain; charset=UTF-8" {
+		t.Errorf("Content-Type = %q, want text/plain; charset=UTF-8", got)
+	}
+	if got := parsed.Header.Get("Content-Transfer-Encoding"); got != "quoted-printable" {
+		t.Errorf("Content-Transfer-Encoding = %q, want quoted-printable", got)
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		expected string
+	}{
+		{
+			name:     "paragraphs and link",
+			html:     `<p>Halo Budi,</p><p>Tagihan <b>Netflix</b> Rp 50.000.</p><p><a href="https://example.com/p/abc">Bayar sekarang</a></p>`,
+			expected: "Halo Budi,\nTagihan Netflix Rp 50.000.\nBayar sekarang (https://example.com/p/abc)",
+		},
+		{
+			name:     "entities are decoded",
+			html:     `Sari &amp; Co<br>Rp&nbsp;1`,
+			expected: "Sari & Co\nRp 1",
+		},
+		{
+			name:     "link labelled with its url",
+			html:     `<a href="https://example.com">https://example.com</a>`,
+			expected: "https://example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HTMLToText(tt.html); got != tt.expected {
+				t.Errorf("HTMLToText() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -1,11 +1,16 @@
 package services
 
 import (
+	"context"
 	"crypto/sha512"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 
+	"patungan_app_echo/internal/models"
+
 	"github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/coreapi"
 	"github.com/midtrans/midtrans-go/snap"
@@ -46,7 +51,7 @@ func NewMidtransService() *MidtransService {
 }
 
 // CreateTransaction creates a Snap transaction and returns the redirect URL and token
-func (s *MidtransService) CreateTransaction(orderID string, amount int64, param *snap.Request) (*snap.Response, error) {
+func (s *MidtransService) CreateTransaction(ctx context.Context, orderID string, amount int64, param *snap.Request) (*snap.Response, error) {
 	// If param is nil, create a basic request
 	if param == nil {
 		param = &snap.Request{
@@ -65,7 +70,9 @@ func (s *MidtransService) CreateTransaction(orderID string, amount int64, param
 		}
 	}
 
-	resp, err := s.SnapClient.CreateTransaction(param)
+	resp, err := callWithContext(ctx, func() (*snap.Response, *midtrans.Error) {
+		return s.SnapClient.CreateTransaction(param)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("midtrans create transaction error: %v", err)
 	}
@@ -84,8 +91,10 @@ func (s *MidtransService) VerifySignature(signatureKey, orderID, statusCode, gro
 }
 
 // CheckTransaction checks the status of a transaction using Core API
-func (s *MidtransService) CheckTransaction(orderID string) (*coreapi.TransactionStatusResponse, error) {
-	resp, err := s.CoreClient.CheckTransaction(orderID)
+func (s *MidtransService) CheckTransaction(ctx context.Context, orderID string) (*coreapi.TransactionStatusResponse, error) {
+	resp, err := callWithContext(ctx, func() (*coreapi.TransactionStatusResponse, *midtrans.Error) {
+		return s.CoreClient.CheckTransaction(orderID)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("midtrans check transaction error: %v", err)
 	}
@@ -93,10 +102,185 @@ func (s *MidtransService) CheckTransaction(orderID string) (*coreapi.Transaction
 }
 
 // CancelTransaction cancels a pending transaction
-func (s *MidtransService) CancelTransaction(orderID string) (*coreapi.CancelResponse, error) {
-	resp, err := s.CoreClient.CancelTransaction(orderID)
+func (s *MidtransService) CancelTransaction(ctx context.Context, orderID string) (*coreapi.CancelResponse, error) {
+	resp, err := callWithContext(ctx, func() (*coreapi.CancelResponse, *midtrans.Error) {
+		return s.CoreClient.CancelTransaction(orderID)
+	})
 	if err != nil {
 		return nil, fmt.Errorf("midtrans cancel transaction error: %v", err)
 	}
 	return resp, nil
 }
+
+// midtransResult carries the outcome of a Midtrans API call
+type midtransResult[T any] struct {
+	resp T
+	err  *midtrans.Error
+}
+
+// callWithContext runs a Midtrans API call and stops waiting for it once ctx is done.
+// midtrans-go does not pass contexts on to its HTTP requests, so an abandoned call
+// keeps running in the background until the client's own timeout.
+func callWithContext[T any](ctx context.Context, call func() (T, *midtrans.Error)) (T, error) {
+	done := make(chan midtransResult[T], 1)
+	go func() {
+		resp, err := call()
+		done <- midtransResult[T]{resp: resp, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return result.resp, result.err
+		}
+		return result.resp, nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// RefundTransaction refunds an amount of a settled transaction
+func (s *MidtransService) RefundTransaction(ctx context.Context, orderID string, req *coreapi.RefundReq) (*coreapi.RefundResponse, error) {
+	resp, err := callWithContext(ctx, func() (*coreapi.RefundResponse, *midtrans.Error) {
+		return s.CoreClient.RefundTransaction(orderID, req)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("midtrans refund transaction error: %v", err)
+	}
+	return resp, nil
+}
+
+// Gateway reports that Midtrans sessions are recorded as the midtrans gateway
+func (s *MidtransService) Gateway() models.PaymentGateway {
+	return models.PaymentGatewayMidtrans
+}
+
+// CreateCheckout creates a Snap transaction, paid through the Snap popup with its token
+func (s *MidtransService) CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error) {
+	param := &snap.Request{
+		TransactionDetails: midtrans.TransactionDetails{
+			OrderID:  req.OrderID,
+			GrossAmt: req.Amount.Int64(),
+		},
+		CustomerDetail: &midtrans.CustomerDetails{
+			FName: req.CustomerName,
+			Email: req.CustomerEmail,
+		},
+		Items: &[]midtrans.ItemDetails{
+			{
+				ID:    req.ItemID,
+				Name:  req.ItemName,
+				Price: req.Amount.Int64(),
+				Qty:   1,
+			},
+		},
+		Callbacks: &snap.Callbacks{
+			Finish: req.FinishURL,
+		},
+	}
+
+	resp, err := s.CreateTransaction(ctx, req.OrderID, req.Amount.Int64(), param)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBytes, _ := json.Marshal(param)
+	respBytes, _ := json.Marshal(resp)
+	return &Checkout{
+		Token:       resp.Token,
+		RedirectURL: resp.RedirectURL,
+		Request:     reqBytes,
+		Response:    respBytes,
+	}, nil
+}
+
+// ResumeCheckout restores a Snap transaction from its stored response
+func (s *MidtransService) ResumeCheckout(response json.RawMessage) (*Checkout, error) {
+	var resp snap.Response
+	if err := json.Unmarshal(response, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse snap response: %w", err)
+	}
+	if resp.Token == "" {
+		return nil, fmt.Errorf("snap response has no token")
+	}
+	return &Checkout{Token: resp.Token, RedirectURL: resp.RedirectURL, Response: response}, nil
+}
+
+// CheckStatus checks a transaction with the Core API
+func (s *MidtransService) CheckStatus(ctx context.Context, orderID string) (*PaymentResult, error) {
+	resp, err := s.CheckTransaction(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return midtransPaymentResult(resp.OrderID, resp.TransactionStatus, resp.FraudStatus, resp.PaymentType, resp.GrossAmount), nil
+}
+
+// Cancel cancels a pending transaction
+func (s *MidtransService) Cancel(ctx context.Context, orderID string) error {
+	_, err := s.CancelTransaction(ctx, orderID)
+	return err
+}
+
+// Refund refunds an amount of a settled transaction
+func (s *MidtransService) Refund(ctx context.Context, orderID string, amount models.Money, reason string) error {
+	_, err := s.RefundTransaction(ctx, orderID, &coreapi.RefundReq{
+		// The key makes a retried request not refund twice
+		RefundKey: fmt.Sprintf("%s-refund-%s", orderID, amount),
+		Amount:    amount.Int64(),
+		Reason:    reason,
+	})
+	return err
+}
+
+// ParseWebhook reads an HTTP notification and verifies its signature
+func (s *MidtransService) ParseWebhook(body []byte, header http.Header) (*PaymentResult, error) {
+	var notification struct {
+		OrderID           string `json:"order_id"`
+		TransactionStatus string `json:"transaction_status"`
+		FraudStatus       string `json:"fraud_status"`
+		PaymentType       string `json:"payment_type"`
+		SignatureKey      string `json:"signature_key"`
+		StatusCode        string `json:"status_code"`
+		GrossAmount       string `json:"gross_amount"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, fmt.Errorf("failed to parse notification: %w", err)
+	}
+
+	if !s.VerifySignature(notification.SignatureKey, notification.OrderID, notification.StatusCode, notification.GrossAmount) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	return midtransPaymentResult(notification.OrderID, notification.TransactionStatus, notification.FraudStatus, notification.PaymentType, notification.GrossAmount), nil
+}
+
+// midtransPaymentResult maps the transaction status of Midtrans to a PaymentResult
+func midtransPaymentResult(orderID, transactionStatus, fraudStatus, paymentType, grossAmount string) *PaymentResult {
+	status := CheckoutStatusPending
+	switch transactionStatus {
+	case "capture":
+		// Card payments are only paid once fraud detection accepts them
+		switch fraudStatus {
+		case "accept":
+			status = CheckoutStatusPaid
+		case "deny":
+			status = CheckoutStatusFailed
+		}
+	case "settlement":
+		status = CheckoutStatusPaid
+	case "deny", "expire", "cancel", "failure":
+		status = CheckoutStatusFailed
+	case "refund", "partial_refund", "chargeback", "partial_chargeback":
+		status = CheckoutStatusRefunded
+	}
+
+	// Midtrans reports the amount as a decimal string, e.g. "100000.00"
+	amount, _ := models.ParseMoney(grossAmount)
+	return &PaymentResult{
+		OrderID:     orderID,
+		Status:      status,
+		PaymentType: paymentType,
+		Amount:      amount,
+	}
+}
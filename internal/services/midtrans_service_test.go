@@ -0,0 +1,106 @@
+package services
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestMidtransPaymentResult(t *testing.T) {
+	tests := []struct {
+		name              string
+		transactionStatus string
+		fraudStatus       string
+		expected          CheckoutStatus
+	}{
+		{name: "settled", transactionStatus: "settlement", expected: CheckoutStatusPaid},
+		{name: "captured and accepted", transactionStatus: "capture", fraudStatus: "accept", expected: CheckoutStatusPaid},
+		{name: "captured and challenged", transactionStatus: "capture", fraudStatus: "challenge", expected: CheckoutStatusPending},
+		{name: "captured and denied", transactionStatus: "capture", fraudStatus: "deny", expected: CheckoutStatusFailed},
+		{name: "pending", transactionStatus: "pending", expected: CheckoutStatusPending},
+		{name: "denied", transactionStatus: "deny", expected: CheckoutStatusFailed},
+		{name: "expired", transactionStatus: "expire", expected: CheckoutStatusFailed},
+		{name: "canceled", transactionStatus: "cancel", expected: CheckoutStatusFailed},
+		{name: "refunded", transactionStatus: "partial_refund", expected: CheckoutStatusRefunded},
+		{name: "charged back", transactionStatus: "chargeback", expected: CheckoutStatusRefunded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := midtransPaymentResult("payment-due-1-1700000000", tt.transactionStatus, tt.fraudStatus, "bank_transfer", "150000.00")
+			if result.Status != tt.expected {
+				t.Errorf("status = %q, want %q", result.Status, tt.expected)
+			}
+			if result.Amount != models.Money(150000) {
+				t.Errorf("amount = %d, want 150000", result.Amount)
+			}
+			if result.OrderID != "payment-due-1-1700000000" || result.PaymentType != "bank_transfer" {
+				t.Errorf("result = %+v, want the order and payment type of the notification", result)
+			}
+		})
+	}
+}
+
+func TestMidtransParseWebhook(t *testing.T) {
+	service := &MidtransService{ServerKey: "server-key"}
+	sign := func(orderID, statusCode, grossAmount, serverKey string) string {
+		hash := sha512.Sum512([]byte(orderID + statusCode + grossAmount + serverKey))
+		return hex.EncodeToString(hash[:])
+	}
+	notification := func(signature string) []byte {
+		return []byte(fmt.Sprintf(`{"order_id":"payment-due-7-1700000000","status_code":"200","gross_amount":"50000.00","transaction_status":"settlement","payment_type":"qris","signature_key":%q}`, signature))
+	}
+
+	tests := []struct {
+		name       string
+		body       []byte
+		wantStatus CheckoutStatus
+		wantError  error
+	}{
+		{
+			name:       "valid signature",
+			body:       notification(sign("payment-due-7-1700000000", "200", "50000.00", "server-key")),
+			wantStatus: CheckoutStatusPaid,
+		},
+		{
+			name:      "signed with another key",
+			body:      notification(sign("payment-due-7-1700000000", "200", "50000.00", "other-key")),
+			wantError: ErrInvalidWebhookSignature,
+		},
+		{
+			name:      "signature of another amount",
+			body:      notification(sign("payment-due-7-1700000000", "200", "5000.00", "server-key")),
+			wantError: ErrInvalidWebhookSignature,
+		},
+		{
+			name:      "not an object",
+			body:      []byte(`["settlement"]`),
+			wantError: errors.New("failed to parse notification"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := service.ParseWebhook(tt.body, nil)
+			if tt.wantError != nil {
+				if err == nil {
+					t.Fatalf("ParseWebhook() error = nil, want %v", tt.wantError)
+				}
+				if errors.Is(tt.wantError, ErrInvalidWebhookSignature) != errors.Is(err, ErrInvalidWebhookSignature) {
+					t.Errorf("ParseWebhook() error = %v, want %v", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if result.Status != tt.wantStatus || result.OrderID != "payment-due-7-1700000000" || result.Amount != models.Money(50000) {
+				t.Errorf("ParseWebhook() = %+v, want a paid order of 50000", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// EmailMessage is an email to be sent. With both Text and HTML set the body is
+// multipart/alternative, attachments wrap it in multipart/mixed.
+type EmailMessage struct {
+	From        string
+	To          []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []EmailAttachment
+	MessageID   string    // set by EmailService.Send when empty
+	Date        time.Time // set by EmailService.Send when zero
+}
+
+// EmailAttachment is a file attached to an email
+type EmailAttachment struct {
+	Filename    string
+	ContentType string // defaults to application/octet-stream
+	Data        []byte
+}
+
+// Bytes renders the message with its headers, ready to be written to an SMTP DATA command
+func (m EmailMessage) Bytes() ([]byte, error) {
+	if len(m.To) == 0 {
+		return nil, fmt.Errorf("email has no recipients")
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, fmt.Errorf("email has no body")
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", m.From)
+	writeHeader(&buf, "To", strings.Join(m.To, ", "))
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	if !m.Date.IsZero() {
+		writeHeader(&buf, "Date", m.Date.Format(time.RFC1123Z))
+	}
+	if m.MessageID != "" {
+		writeHeader(&buf, "Message-ID", m.MessageID)
+	}
+	writeHeader(&buf, "MIME-Version", "1.0")
+
+	bodyHeader, body, err := m.bodyPart()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(m.Attachments) == 0 {
+		for _, key := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+			if value := bodyHeader.Get(key); value != "" {
+				writeHeader(&buf, key, value)
+			}
+		}
+		buf.WriteString("\r\n")
+		buf.Write(body)
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	writeHeader(&buf, "Content-Type", mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()}))
+	buf.WriteString("\r\n")
+
+	part, err := mixed.CreatePart(bodyHeader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(body); err != nil {
+		return nil, err
+	}
+	for _, attachment := range m.Attachments {
+		if err := writeAttachment(mixed, attachment); err != nil {
+			return nil, err
+		}
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// bodyPart returns the headers and encoded content of the message body, a single
+// text or HTML part, or a multipart/alternative part when the message has both
+func (m EmailMessage) bodyPart() (textproto.MIMEHeader, []byte, error) {
+	var body bytes.Buffer
+
+	if m.Text == "" || m.HTML == "" {
+		contentType, content := "text/plain", m.Text
+		if m.HTML != "" {
+			contentType, content = "text/html", m.HTML
+		}
+		if err := writeQuotedPrintable(&body, content); err != nil {
+			return nil, nil, err
+		}
+		return textPartHeader(contentType), body.Bytes(), nil
+	}
+
+	alternative := multipart.NewWriter(&body)
+	// Clients show the last part they support, so the HTML part goes last
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", m.Text},
+		{"text/html", m.HTML},
+	} {
+		w, err := alternative.CreatePart(textPartHeader(part.contentType))
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, nil, err
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": alternative.Boundary()}))
+	return header, body.Bytes(), nil
+}
+
+// textPartHeader is the header of a quoted-printable UTF-8 text part
+func textPartHeader(contentType string) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=UTF-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return header
+}
+
+// writeAttachment adds a base64 encoded attachment part
+func writeAttachment(w *multipart.Writer, attachment EmailAttachment) error {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(contentType, map[string]string{"name": attachment.Filename})},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+
+	// RFC 2045 limits encoded lines to 76 characters
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		if _, err := fmt.Fprintf(part, "%s\r\n", encoded[:76]); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = fmt.Fprintf(part, "%s\r\n", encoded)
+	return err
+}
+
+// writeHeader writes a single header line, dropping line breaks that would inject headers
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	value = strings.NewReplacer("\r", "", "\n", "").Replace(value)
+	fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+}
+
+// writeQuotedPrintable writes content with quoted-printable encoding and CRLF line endings
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if _, err := qp.Write([]byte(strings.ReplaceAll(content, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+var (
+	htmlLinkPattern  = regexp.MustCompile(`(?is)<a\s[^>]*href="([^"]*)"[^>]*>(.*?)</a>`)
+	htmlBreakPattern = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</h[1-6]>|</li>|</tr>`)
+	htmlTagPattern   = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinePattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// HTMLToText turns an HTML email body into the plain text alternative, keeping link targets
+func HTMLToText(body string) string {
+	text := htmlLinkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		match := htmlLinkPattern.FindStringSubmatch(link)
+		label := strings.TrimSpace(htmlTagPattern.ReplaceAllString(match[2], ""))
+		if label == "" || label == match[1] {
+			return match[1]
+		}
+		return fmt.Sprintf("%s (%s)", label, match[1])
+	})
+	text = htmlBreakPattern.ReplaceAllString(text, "\n")
+	text = htmlTagPattern.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.Join(lines, "\n")
+	return strings.TrimSpace(blankLinePattern.ReplaceAllString(text, "\n\n"))
+}
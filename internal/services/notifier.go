@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+
+	"patungan_app_echo/internal/models"
+)
+
+// NotifierMessage is a rendered notification ready to be sent
+type NotifierMessage struct {
+	Subject string // only used by channels that support subjects
+	Body    string
+	IsHTML  bool // Body is HTML, only set for channels that support it
+}
+
+// NotifierCapabilities describes what a channel can carry besides a plain text body
+type NotifierCapabilities struct {
+	Subject bool
+	HTML    bool
+}
+
+// Notifier sends messages through one notification channel
+type Notifier interface {
+	// Notify sends a message to a recipient and returns the provider message ID
+	Notify(ctx context.Context, recipient string, msg NotifierMessage) (string, error)
+	// Capabilities describes the messages the channel supports
+	Capabilities() NotifierCapabilities
+}
+
+// NotifierRegistry maps notification channels to their notifiers
+type NotifierRegistry struct {
+	mu        sync.RWMutex
+	notifiers map[models.NotificationChannel]Notifier
+}
+
+// NewNotifierRegistry returns an empty registry
+func NewNotifierRegistry() *NotifierRegistry {
+	return &NotifierRegistry{notifiers: make(map[models.NotificationChannel]Notifier)}
+}
+
+// Register sets the notifier of a channel, replacing any earlier one
+func (r *NotifierRegistry) Register(channel models.NotificationChannel, notifier Notifier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.notifiers[channel] = notifier
+}
+
+// Get returns the notifier of a channel
+func (r *NotifierRegistry) Get(channel models.NotificationChannel) (Notifier, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	notifier, ok := r.notifiers[channel]
+	return notifier, ok
+}
+
+// Send sends a message through the notifier of a channel
+func (r *NotifierRegistry) Send(ctx context.Context, channel models.NotificationChannel, recipient string, msg NotifierMessage) (string, error) {
+	notifier, ok := r.Get(channel)
+	if !ok {
+		return "", fmt.Errorf("unsupported channel %s", channel)
+	}
+	return notifier.Notify(ctx, recipient, msg)
+}
+
+// NewNotifierRegistryFromEnv registers the email, WhatsApp and Telegram notifiers. With
+// NOTIFICATIONS_DRY_RUN set, every channel logs its messages instead of sending them.
+// WhatsApp messages are rate limited through the cache, unless it is nil.
+func NewNotifierRegistryFromEnv(cache *RedisCache) *NotifierRegistry {
+	registry := NewNotifierRegistry()
+
+	if dryRun, _ := strconv.ParseBool(os.Getenv("NOTIFICATIONS_DRY_RUN")); dryRun {
+		log.Println("NOTIFICATIONS_DRY_RUN is set, notifications are logged instead of sent")
+		registry.Register(models.NotificationChannelEmail, LogNotifier{Channel: models.NotificationChannelEmail, Caps: NotifierCapabilities{Subject: true, HTML: true}})
+		registry.Register(models.NotificationChannelWhatsapp, LogNotifier{Channel: models.NotificationChannelWhatsapp})
+		registry.Register(models.NotificationChannelTelegram, LogNotifier{Channel: models.NotificationChannelTelegram})
+		return registry
+	}
+
+	registry.Register(models.NotificationChannelEmail, NewEmailService())
+	waha := NewWahaService()
+	if cache != nil {
+		waha.SetRateLimiter(NewWahaRateLimiterFromEnv(cache.Client(), waha.Session()))
+	} else {
+		log.Println("Redis is not available, WhatsApp messages are not rate limited")
+	}
+	registry.Register(models.NotificationChannelWhatsapp, waha)
+	registry.Register(models.NotificationChannelTelegram, NewTelegramService())
+	return registry
+}
+
+// Notify sends an email, as HTML with a plain text alternative when the message is HTML
+func (s *EmailService) Notify(ctx context.Context, recipient string, msg NotifierMessage) (string, error) {
+	if msg.IsHTML {
+		return s.SendHTMLEmail(ctx, []string{recipient}, msg.Subject, msg.Body)
+	}
+	return s.SendEmail(ctx, []string{recipient}, msg.Subject, msg.Body)
+}
+
+// Capabilities reports that emails have a subject and may be HTML
+func (s *EmailService) Capabilities() NotifierCapabilities {
+	return NotifierCapabilities{Subject: true, HTML: true}
+}
+
+// Notify sends a WhatsApp message to a number or group chat ID
+func (s *WahaService) Notify(ctx context.Context, recipient string, msg NotifierMessage) (string, error) {
+	return s.SendMessage(ctx, recipient, msg.Body)
+}
+
+// Capabilities reports that WhatsApp messages are plain text
+func (s *WahaService) Capabilities() NotifierCapabilities {
+	return NotifierCapabilities{}
+}
+
+// Notify sends a Telegram message to a chat ID
+func (s *TelegramService) Notify(ctx context.Context, recipient string, msg NotifierMessage) (string, error) {
+	return s.SendMessage(ctx, recipient, msg.Body)
+}
+
+// Capabilities reports that Telegram messages are plain text
+func (s *TelegramService) Capabilities() NotifierCapabilities {
+	return NotifierCapabilities{}
+}
+
+// LogNotifier logs messages instead of sending them, for development and dry runs
+type LogNotifier struct {
+	Channel models.NotificationChannel
+	Caps    NotifierCapabilities
+}
+
+// Notify logs the message and reports it as sent
+func (n LogNotifier) Notify(ctx context.Context, recipient string, msg NotifierMessage) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if msg.Subject != "" {
+		log.Printf("[%s] to %s: %s\n%s", n.Channel, recipient, msg.Subject, msg.Body)
+	} else {
+		log.Printf("[%s] to %s: %s", n.Channel, recipient, msg.Body)
+	}
+	return "", nil
+}
+
+// Capabilities returns the capabilities of the channel the notifier stands in for
+func (n LogNotifier) Capabilities() NotifierCapabilities {
+	return n.Caps
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestNotifierRegistryFromEnv(t *testing.T) {
+	tests := []struct {
+		name     string
+		dryRun   string
+		channel  models.NotificationChannel
+		wantCaps NotifierCapabilities
+		wantLog  bool
+	}{
+		{name: "email", channel: models.NotificationChannelEmail, wantCaps: NotifierCapabilities{Subject: true, HTML: true}},
+		{name: "whatsapp", channel: models.NotificationChannelWhatsapp},
+		{name: "telegram", channel: models.NotificationChannelTelegram},
+		{name: "dry run email", dryRun: "true", channel: models.NotificationChannelEmail, wantCaps: NotifierCapabilities{Subject: true, HTML: true}, wantLog: true},
+		{name: "dry run telegram", dryRun: "1", channel: models.NotificationChannelTelegram, wantLog: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOTIFICATIONS_DRY_RUN", tt.dryRun)
+			notifier, ok := NewNotifierRegistryFromEnv(nil).Get(tt.channel)
+			if !ok {
+				t.Fatalf("no notifier registered for %s", tt.channel)
+			}
+			if got := notifier.Capabilities(); got != tt.wantCaps {
+				t.Errorf("Capabilities() = %+v, want %+v", got, tt.wantCaps)
+			}
+			if _, isLog := notifier.(LogNotifier); isLog != tt.wantLog {
+				t.Errorf("notifier is %T, want log notifier %v", notifier, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestNotifierRegistrySend(t *testing.T) {
+	registry := NewNotifierRegistry()
+	registry.Register(models.NotificationChannelWhatsapp, LogNotifier{Channel: models.NotificationChannelWhatsapp})
+
+	if _, err := registry.Send(context.Background(), models.NotificationChannelWhatsapp, "0812", NotifierMessage{Body: "Halo"}); err != nil {
+		t.Errorf("Send() through a registered notifier failed: %v", err)
+	}
+	if _, err := registry.Send(context.Background(), models.NotificationChannelEmail, "budi@example.com", NotifierMessage{Body: "Halo"}); err == nil {
+		t.Errorf("Send() without a notifier for the channel succeeded")
+	}
+}
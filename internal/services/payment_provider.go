@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"patungan_app_echo/internal/models"
+)
+
+// Errors of payment webhooks, telling the gateway whether the notification is worth sending again
+var (
+	ErrUnknownPaymentProvider  = errors.New("unknown payment provider")
+	ErrInvalidWebhookPayload   = errors.New("invalid webhook payload")
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+)
+
+// CheckoutStatus is the state of a payment at its gateway, the same for every provider
+type CheckoutStatus string
+
+const (
+	CheckoutStatusPending  CheckoutStatus = "pending"  // not paid yet, the checkout can still be paid
+	CheckoutStatusPaid     CheckoutStatus = "paid"     // paid, or captured and accepted by fraud detection
+	CheckoutStatusFailed   CheckoutStatus = "failed"   // denied, expired or canceled, a new checkout is needed
+	CheckoutStatusRefunded CheckoutStatus = "refunded" // paid and then refunded or charged back
+)
+
+// CheckoutRequest describes the payment of a due to create at a gateway
+type CheckoutRequest struct {
+	OrderID       string
+	Amount        models.Money
+	CustomerName  string
+	CustomerEmail string
+	ItemID        string
+	ItemName      string
+	FinishURL     string // where the payer returns after paying
+}
+
+// Checkout is a payment page created at a gateway. The payer opens it with Token through the
+// gateway's client script, or by following RedirectURL.
+type Checkout struct {
+	Token       string
+	RedirectURL string
+	Request     json.RawMessage // as sent to the gateway, kept on the payment session
+	Response    json.RawMessage // as returned by the gateway, kept on the payment session
+}
+
+// PaymentResult is the state of a payment as reported by its gateway
+type PaymentResult struct {
+	OrderID     string
+	Status      CheckoutStatus
+	PaymentType string // how it was paid, e.g. "bank_transfer"
+	Amount      models.Money
+}
+
+// NewPaymentProvidersFromEnv returns the payment gateways configured in the environment, the
+// first one is used for new checkouts
+func NewPaymentProvidersFromEnv() []PaymentProvider {
+	return []PaymentProvider{NewMidtransService()}
+}
+
+// PaymentProvider creates and manages payments at one payment gateway
+type PaymentProvider interface {
+	// Gateway is the gateway recorded on the payment sessions of the provider
+	Gateway() models.PaymentGateway
+	// CreateCheckout creates a payment page for an order
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (*Checkout, error)
+	// ResumeCheckout restores the checkout of a stored Checkout.Response, to pay a pending order
+	ResumeCheckout(response json.RawMessage) (*Checkout, error)
+	// CheckStatus asks the gateway for the state of an order
+	CheckStatus(ctx context.Context, orderID string) (*PaymentResult, error)
+	// Cancel cancels the checkout of an order that is not paid
+	Cancel(ctx context.Context, orderID string) error
+	// Refund refunds an amount of a paid order
+	Refund(ctx context.Context, orderID string, amount models.Money, reason string) error
+	// ParseWebhook verifies a payment notification and returns the payment it reports. An
+	// unverified notification returns ErrInvalidWebhookSignature.
+	ParseWebhook(body []byte, header http.Header) (*PaymentResult, error)
+}
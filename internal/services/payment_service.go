@@ -1,29 +1,56 @@
 package services
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"strconv"
+	"net/http"
 	"time"
 
 	"gorm.io/gorm"
 
 	"patungan_app_echo/internal/models"
-
-	"github.com/midtrans/midtrans-go"
-	"github.com/midtrans/midtrans-go/snap"
 )
 
+// ErrPaymentAlreadyMade is returned when initiating the payment of a due whose checkout is already paid
+var ErrPaymentAlreadyMade = errors.New("payment already made")
+
+// PaymentListener is called with every payment MarkAsPaid records, in the transaction recording it.
+// Returning an error rolls the payment back.
+type PaymentListener func(tx *gorm.DB, due *models.PaymentDue, payment *models.UserPayment) error
+
 type PaymentService struct {
 	db             *gorm.DB
-	midtransClient *MidtransService
+	providers      map[models.PaymentGateway]PaymentProvider
+	defaultGateway models.PaymentGateway
+	listeners      []PaymentListener
 }
 
-func NewPaymentService(db *gorm.DB, midtransClient *MidtransService) *PaymentService {
-	return &PaymentService{
-		db:             db,
-		midtransClient: midtransClient,
+// NewPaymentService returns a service paying through the given providers. New checkouts are
+// created with the first one, existing sessions use the provider of their gateway.
+func NewPaymentService(db *gorm.DB, providers ...PaymentProvider) *PaymentService {
+	s := &PaymentService{
+		db:        db,
+		providers: make(map[models.PaymentGateway]PaymentProvider),
+	}
+	for i, provider := range providers {
+		if i == 0 {
+			s.defaultGateway = provider.Gateway()
+		}
+		s.providers[provider.Gateway()] = provider
 	}
+	return s
+}
+
+// OnPayment adds a listener of the payments recorded by MarkAsPaid
+func (s *PaymentService) OnPayment(listener PaymentListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+// Provider returns the provider of a gateway
+func (s *PaymentService) Provider(gateway models.PaymentGateway) (PaymentProvider, bool) {
+	provider, ok := s.providers[gateway]
+	return provider, ok
 }
 
 // CheckActiveSession checks if there is an active session for the given due ID
@@ -40,15 +67,33 @@ func (s *PaymentService) CheckActiveSession(paymentDueID uint) (*models.PaymentS
 	return &existingSession, nil
 }
 
+// ActiveCheckout returns the checkout of the active session of a due, nil when there is none
+func (s *PaymentService) ActiveCheckout(paymentDueID uint) (*Checkout, error) {
+	session, err := s.CheckActiveSession(paymentDueID)
+	if err != nil || session == nil {
+		return nil, err
+	}
+	provider, ok := s.Provider(session.PaymentGateway)
+	if !ok {
+		return nil, nil
+	}
+	checkout, err := provider.ResumeCheckout(session.ResponseMetadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore the checkout of session %d: %w", session.ID, err)
+	}
+	return checkout, nil
+}
+
 // InitiatePaymentResult holds the result of an initiation attempt
 type InitiatePaymentResult struct {
+	Gateway     models.PaymentGateway
 	Token       string
 	RedirectURL string
 	IsExisting  bool
 }
 
 // InitiatePayment handles the logic for starting or resuming a payment session
-func (s *PaymentService) InitiatePayment(due *models.PaymentDue, forceNew bool, callbackURL string) (*InitiatePaymentResult, error) {
+func (s *PaymentService) InitiatePayment(ctx context.Context, due *models.PaymentDue, forceNew bool, callbackURL string) (*InitiatePaymentResult, error) {
 	// 1. Check for existing active session
 	existingSession, err := s.CheckActiveSession(due.ID)
 	if err != nil {
@@ -56,105 +101,108 @@ func (s *PaymentService) InitiatePayment(due *models.PaymentDue, forceNew bool,
 	}
 
 	if existingSession != nil {
-		// active session exists, check status with Midtrans
-		statusResp, err := s.midtransClient.CheckTransaction(existingSession.OrderID)
-		if err == nil {
-			// Case 1: Payment already successful
-			if statusResp.TransactionStatus == "settlement" || statusResp.TransactionStatus == "capture" {
-				return nil, fmt.Errorf("payment already made")
-			}
-
-			// Case 2: Payment failed/expired/canceled
-			if statusResp.TransactionStatus == "deny" || statusResp.TransactionStatus == "expire" || statusResp.TransactionStatus == "cancel" || statusResp.TransactionStatus == "failure" {
-				// Deactivate local session
-				existingSession.IsActive = false
-				s.db.Save(existingSession)
-				// Proceed to create new
-			} else {
-				// Case 3: Payment is Pending
-				if forceNew {
-					// Cancel at Midtrans
-					s.midtransClient.CancelTransaction(existingSession.OrderID)
-					existingSession.IsActive = false
-					s.db.Save(existingSession)
-					// Proceed to create new
-				} else {
-					// Reuse existing
-					var midtransResp snap.Response
-					if err := json.Unmarshal(existingSession.ResponseMetadata, &midtransResp); err == nil {
-						return &InitiatePaymentResult{
-							Token:       midtransResp.Token,
-							RedirectURL: midtransResp.RedirectURL,
-							IsExisting:  true,
-						}, nil
-					}
-					// If unmarshal fails, treat as broken
-					existingSession.IsActive = false
-					s.db.Save(existingSession)
-				}
-			}
-		} else {
-			// Check failed, assume session is invalid/broken locally
-			existingSession.IsActive = false
-			s.db.Save(existingSession)
+		checkout, err := s.resumeSession(ctx, existingSession, due, forceNew)
+		if err != nil {
+			return nil, err
 		}
+		if checkout != nil {
+			return &InitiatePaymentResult{
+				Gateway:     existingSession.PaymentGateway,
+				Token:       checkout.Token,
+				RedirectURL: checkout.RedirectURL,
+				IsExisting:  true,
+			}, nil
+		}
+	}
+
+	provider, ok := s.Provider(s.defaultGateway)
+	if !ok {
+		return nil, fmt.Errorf("no payment provider configured")
 	}
 
-	// 2. Create New Transaction
+	// 2. Create New Checkout, charging the late fee on top of the due amount
 	orderID := fmt.Sprintf("payment-due-%d-%d", due.ID, time.Now().Unix())
+	amount := due.PayableAmount()
 
-	req := &snap.Request{
-		TransactionDetails: midtrans.TransactionDetails{
-			OrderID:  orderID,
-			GrossAmt: int64(due.CalculatedPayAmount),
-		},
-		CustomerDetail: &midtrans.CustomerDetails{
-			FName: due.User.Name,
-			Email: due.User.Email,
-		},
-		Items: &[]midtrans.ItemDetails{
-			{
-				ID:    fmt.Sprintf("plan-%d", due.PlanID),
-				Name:  fmt.Sprintf("Payment for %s", due.Plan.Name),
-				Price: int64(due.CalculatedPayAmount),
-				Qty:   1,
-			},
-		},
-		Callbacks: &snap.Callbacks{
-			Finish: callbackURL,
-		},
-	}
-
-	resp, err := s.midtransClient.CreateTransaction(orderID, int64(due.CalculatedPayAmount), req)
+	checkout, err := provider.CreateCheckout(ctx, CheckoutRequest{
+		OrderID:       orderID,
+		Amount:        amount,
+		CustomerName:  due.User.Name,
+		CustomerEmail: due.User.Email,
+		ItemID:        fmt.Sprintf("plan-%d", due.PlanID),
+		ItemName:      fmt.Sprintf("Payment for %s", due.Plan.Name),
+		FinishURL:     callbackURL,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// 3. Create Session Record
-	reqBytes, _ := json.Marshal(req)
-	respBytes, _ := json.Marshal(resp)
-
 	session := models.PaymentSession{
 		PlanID:           due.PlanID,
 		PaymentDueID:     due.ID,
 		UserID:           due.UserID,
-		PaymentGateway:   models.PaymentGatewayMidtrans,
+		PaymentGateway:   provider.Gateway(),
 		OrderID:          orderID,
+		Amount:           amount,
 		IsActive:         true,
-		RequestMetadata:  reqBytes,
-		ResponseMetadata: respBytes,
+		RequestMetadata:  checkout.Request,
+		ResponseMetadata: checkout.Response,
 	}
 	s.db.Create(&session)
 
 	return &InitiatePaymentResult{
-		Token:       resp.Token,
-		RedirectURL: resp.RedirectURL,
+		Gateway:     provider.Gateway(),
+		Token:       checkout.Token,
+		RedirectURL: checkout.RedirectURL,
 		IsExisting:  false,
 	}, nil
 }
 
-// VerifyPaymentStatus checks the status of a payment due with Midtrans and updates local state
-func (s *PaymentService) VerifyPaymentStatus(dueID uint) error {
+// resumeSession returns the checkout of an active session that can still be paid. A session that
+// cannot is deactivated and nil is returned, so that a new one is created.
+func (s *PaymentService) resumeSession(ctx context.Context, session *models.PaymentSession, due *models.PaymentDue, forceNew bool) (*Checkout, error) {
+	deactivate := func() (*Checkout, error) {
+		session.IsActive = false
+		s.db.Save(session)
+		return nil, nil
+	}
+
+	provider, ok := s.Provider(session.PaymentGateway)
+	if !ok {
+		// The gateway of the session is no longer configured
+		return deactivate()
+	}
+
+	result, err := provider.CheckStatus(ctx, session.OrderID)
+	if err != nil {
+		// Check failed, assume session is invalid/broken locally
+		return deactivate()
+	}
+
+	switch result.Status {
+	case CheckoutStatusPaid:
+		return nil, ErrPaymentAlreadyMade
+	case CheckoutStatusPending:
+		// A session charging a different amount, e.g. from before a late fee was added, is replaced
+		if forceNew || session.Amount != due.PayableAmount() {
+			provider.Cancel(ctx, session.OrderID)
+			return deactivate()
+		}
+		checkout, err := provider.ResumeCheckout(session.ResponseMetadata)
+		if err != nil {
+			// If the stored checkout cannot be restored, treat as broken
+			return deactivate()
+		}
+		return checkout, nil
+	}
+
+	// Failed or refunded, proceed to create new
+	return deactivate()
+}
+
+// VerifyPaymentStatus checks the status of a payment due with its gateway and updates local state
+func (s *PaymentService) VerifyPaymentStatus(ctx context.Context, dueID uint) error {
 	// 1. Find latest active session for this due
 	var session models.PaymentSession
 	if err := s.db.Where("payment_due_id = ? AND is_active = ?", dueID, true).Order("created_at desc").First(&session).Error; err != nil {
@@ -164,8 +212,12 @@ func (s *PaymentService) VerifyPaymentStatus(dueID uint) error {
 		return err
 	}
 
-	// 2. Call Midtrans Check Transaction
-	resp, err := s.midtransClient.CheckTransaction(session.OrderID)
+	// 2. Ask the gateway of the session
+	provider, ok := s.Provider(session.PaymentGateway)
+	if !ok {
+		return fmt.Errorf("no payment provider for gateway %s", session.PaymentGateway)
+	}
+	result, err := provider.CheckStatus(ctx, session.OrderID)
 	if err != nil {
 		return err
 	}
@@ -176,47 +228,65 @@ func (s *PaymentService) VerifyPaymentStatus(dueID uint) error {
 		return err
 	}
 
-	s.HandleTransactionStatus(&due, session.OrderID, resp.TransactionStatus, resp.FraudStatus, resp.PaymentType, resp.GrossAmount)
-
-	return nil
+	return s.ApplyPaymentResult(&due, session.PaymentGateway, session.OrderID, *result)
 }
 
-func (s *PaymentService) HandleTransactionStatus(due *models.PaymentDue, orderID, transactionStatus, fraudStatus, paymentType, grossAmount string) {
-	switch transactionStatus {
-	case "capture":
-		switch fraudStatus {
-		case "accept":
-			s.MarkAsPaid(due, map[string]interface{}{
-				"payment_type": paymentType,
-				"gross_amount": grossAmount,
-			})
-		case "deny", "challenge":
-			// do nothing
+// HandleWebhook verifies a payment notification sent by a gateway and applies the payment it reports.
+// The order is looked up by its payment session, so it does not matter how the gateway formats it.
+func (s *PaymentService) HandleWebhook(gateway models.PaymentGateway, body []byte, header http.Header) error {
+	provider, ok := s.Provider(gateway)
+	if !ok {
+		return ErrUnknownPaymentProvider
+	}
+
+	result, err := provider.ParseWebhook(body, header)
+	if err != nil {
+		if errors.Is(err, ErrInvalidWebhookSignature) {
+			return err
 		}
-	case "settlement":
-		s.MarkAsPaid(due, map[string]interface{}{
-			"payment_type": paymentType,
-			"gross_amount": grossAmount,
+		return fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	var session models.PaymentSession
+	if err := s.db.Where("order_id = ? AND payment_gateway = ?", result.OrderID, gateway).First(&session).Error; err != nil {
+		return fmt.Errorf("payment session of order %s: %w", result.OrderID, err)
+	}
+
+	var due models.PaymentDue
+	if err := s.db.First(&due, session.PaymentDueID).Error; err != nil {
+		return fmt.Errorf("payment due %d: %w", session.PaymentDueID, err)
+	}
+
+	return s.ApplyPaymentResult(&due, gateway, session.OrderID, *result)
+}
+
+// ApplyPaymentResult marks a due paid once its order is paid, and deactivates the session of a failed order
+func (s *PaymentService) ApplyPaymentResult(due *models.PaymentDue, gateway models.PaymentGateway, orderID string, result PaymentResult) error {
+	switch result.Status {
+	case CheckoutStatusPaid:
+		return s.MarkAsPaid(due, map[string]interface{}{
+			"payment_type":    result.PaymentType,
+			"gross_amount":    result.Amount,
+			"payment_gateway": string(gateway),
 		})
-	case "deny", "expire", "cancel", "failure":
+	case CheckoutStatusFailed:
 		var session models.PaymentSession
 		if err := s.db.Where("order_id = ?", orderID).First(&session).Error; err == nil {
 			session.IsActive = false
 			s.db.Save(&session)
 		}
 	}
+	return nil
 }
 
-func (s *PaymentService) MarkAsPaid(due *models.PaymentDue, payload map[string]interface{}) {
+// MarkAsPaid records the payment of a due and tells the payment listeners, all in one transaction.
+// The webhook, status checks and the bot can report the same payment at once, only the first to
+// mark the due paid records it.
+func (s *PaymentService) MarkAsPaid(due *models.PaymentDue, payload map[string]interface{}) error {
 	if due.PaymentStatus == models.PaymentStatusPaid {
-		return
+		return nil
 	}
 
-	// 1. Update PaymentDue status
-	due.PaymentStatus = models.PaymentStatusPaid
-	s.db.Save(due)
-
-	// 2. Create UserPayment record
 	paymentType, _ := payload["payment_type"].(string)
 	paymentGatewayStr, ok := payload["payment_gateway"].(string)
 	var paymentGateway models.PaymentGateway
@@ -226,22 +296,52 @@ func (s *PaymentService) MarkAsPaid(due *models.PaymentDue, payload map[string]i
 		paymentGateway = models.PaymentGatewayMidtrans // Default to midtrans for existing calls
 	}
 
-	// Helper to get float from interface safely
-	var grossAmt float64
+	// Gateways report the amount as Money, older callers pass a decimal string
+	var grossAmt models.Money
 	if val, ok := payload["gross_amount"].(string); ok {
-		grossAmt, _ = strconv.ParseFloat(val, 64)
-	} else if val, ok := payload["gross_amount"].(float64); ok {
+		grossAmt, _ = models.ParseMoney(val)
+	} else if val, ok := payload["gross_amount"].(models.Money); ok {
 		grossAmt = val
 	}
 
-	userPayment := models.UserPayment{
-		PlanID:         due.PlanID,
-		PaymentDueID:   due.ID,
-		UserID:         due.UserID,
-		TotalPay:       grossAmt,
-		ChannelPayment: paymentType,
-		PaymentGateway: paymentGateway,
-		PaymentDate:    time.Now(),
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// 1. Update PaymentDue status, only the status so that e.g. a late fee set meanwhile is kept
+		result := tx.Model(&models.PaymentDue{}).
+			Where("id = ? AND payment_status <> ?", due.ID, models.PaymentStatusPaid).
+			Update("payment_status", models.PaymentStatusPaid)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Already recorded by another caller
+			return nil
+		}
+
+		// 2. Create UserPayment record
+		userPayment := models.UserPayment{
+			PlanID:         due.PlanID,
+			PaymentDueID:   due.ID,
+			UserID:         due.UserID,
+			TotalPay:       grossAmt,
+			ChannelPayment: paymentType,
+			PaymentGateway: paymentGateway,
+			PaymentDate:    time.Now(),
+		}
+		if err := tx.Create(&userPayment).Error; err != nil {
+			return err
+		}
+
+		// 3. Tell the listeners, e.g. to thank the payer
+		for _, listener := range s.listeners {
+			if err := listener(tx, due, &userPayment); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record payment of due %d: %w", due.ID, err)
 	}
-	s.db.Create(&userPayment)
+	due.PaymentStatus = models.PaymentStatusPaid
+	return nil
 }
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"patungan_app_echo/internal/models"
+)
+
+// newMockDB returns a gorm connection whose statements are checked against the expectations of mock
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+func TestMarkAsPaidTwice(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	// The first call marks the due paid and records the payment
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_dues" SET "payment_status"=\$1,"updated_at"=\$2 WHERE \(id = \$3 AND payment_status <> \$4\)`).
+		WithArgs(models.PaymentStatusPaid, sqlmock.AnyArg(), 7, models.PaymentStatusPaid).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO "user_payments"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+	// The second finds it paid already and records nothing
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "payment_dues" SET "payment_status"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	service := NewPaymentService(db)
+	notified := 0
+	service.OnPayment(func(tx *gorm.DB, due *models.PaymentDue, payment *models.UserPayment) error {
+		notified++
+		return nil
+	})
+
+	// Both callers loaded the due before either marked it paid
+	for i := 0; i < 2; i++ {
+		due := models.PaymentDue{ID: 7, PlanID: 3, UserID: 5, PaymentStatus: models.PaymentStatusPending}
+		if err := service.MarkAsPaid(&due, map[string]interface{}{"gross_amount": models.Money(50000)}); err != nil {
+			t.Fatalf("MarkAsPaid() #%d error = %v", i+1, err)
+		}
+		if due.PaymentStatus != models.PaymentStatusPaid {
+			t.Errorf("MarkAsPaid() #%d left status %q, want paid", i+1, due.PaymentStatus)
+		}
+	}
+
+	if notified != 1 {
+		t.Errorf("listeners called %d times, want once", notified)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Default WhatsApp send limits, overridable via WAHA_RATE_LIMIT_PER_MINUTE, WAHA_RATE_LIMIT_PER_HOUR
+// and WAHA_RATE_LIMIT_MAX_WAIT
+const (
+	DefaultWahaRateLimitPerMinute = 20
+	DefaultWahaRateLimitPerHour   = 300
+	DefaultWahaRateLimitMaxWait   = 30 * time.Second
+)
+
+// rateLimiterUnavailableRetry is how long messages are deferred while the limiter cannot reach Redis
+const rateLimiterUnavailableRetry = time.Minute
+
+// RateLimit allows Limit events per Per, refilled continuously so that a full bucket allows a burst of Limit
+type RateLimit struct {
+	Limit int
+	Per   time.Duration
+}
+
+// RateLimitError is returned when a message is over the limit, or the limit could not be checked,
+// it should be sent again after RetryAfter
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error // why the limit could not be checked, nil when over the limit
+}
+
+func (e *RateLimitError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("rate limit unavailable, retry after %s: %v", e.RetryAfter.Round(time.Second), e.Err)
+	}
+	return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimiter is a token bucket kept in Redis, so that every worker goroutine and process sending
+// under the same key shares it. An event takes a token from each of its limits.
+type RateLimiter struct {
+	client  *redis.Client
+	key     string
+	limits  []RateLimit
+	maxWait time.Duration
+
+	// failOpen sends without a token while Redis is unavailable instead of deferring
+	failOpen bool
+}
+
+// NewRateLimiter returns a limiter of the events under key. Wait blocks up to maxWait for a token.
+func NewRateLimiter(client *redis.Client, key string, maxWait time.Duration, limits ...RateLimit) *RateLimiter {
+	return &RateLimiter{client: client, key: key, limits: limits, maxWait: maxWait}
+}
+
+// NewWahaRateLimiterFromEnv returns the limiter of the messages sent from a WAHA session, nil when
+// every limit is turned off with 0. Messages are deferred while Redis is unavailable, unless
+// WAHA_RATE_LIMIT_FAIL_OPEN=true sends them without a limit.
+func NewWahaRateLimiterFromEnv(client *redis.Client, session string) *RateLimiter {
+	var limits []RateLimit
+	if perMinute := rateLimitFromEnv("WAHA_RATE_LIMIT_PER_MINUTE", DefaultWahaRateLimitPerMinute); perMinute > 0 {
+		limits = append(limits, RateLimit{Limit: perMinute, Per: time.Minute})
+	}
+	if perHour := rateLimitFromEnv("WAHA_RATE_LIMIT_PER_HOUR", DefaultWahaRateLimitPerHour); perHour > 0 {
+		limits = append(limits, RateLimit{Limit: perHour, Per: time.Hour})
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	maxWait := DefaultWahaRateLimitMaxWait
+	if val := os.Getenv("WAHA_RATE_LIMIT_MAX_WAIT"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d >= 0 {
+			maxWait = d
+		} else {
+			log.Printf("Invalid WAHA_RATE_LIMIT_MAX_WAIT %q, using default %s", val, DefaultWahaRateLimitMaxWait)
+		}
+	}
+
+	limiter := NewRateLimiter(client, "ratelimit:waha:"+session, maxWait, limits...)
+	limiter.failOpen = os.Getenv("WAHA_RATE_LIMIT_FAIL_OPEN") == "true"
+	return limiter
+}
+
+// rateLimitFromEnv reads a limit from an environment variable, using the default when unset or invalid
+func rateLimitFromEnv(name string, defaultLimit int) int {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultLimit
+	}
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 0 {
+		log.Printf("Invalid %s %q, using default %d", name, val, defaultLimit)
+		return defaultLimit
+	}
+	return limit
+}
+
+// takeScript takes a token from the bucket of each limit when all have one, in one step so that
+// workers taking tokens at the same time cannot both take the last one. KEYS[1] holds the buckets
+// as a hash, ARGV[1] is how long in milliseconds they are kept, followed by the size and the period
+// in milliseconds of each limit. It returns how long in milliseconds until every bucket has a
+// token, "0" when one was taken. Buckets refill by the Redis clock, the clocks of the workers may differ.
+var takeScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local count = (#ARGV - 1) / 2
+local limits = table.concat(ARGV, ',', 2)
+
+if redis.call('TYPE', KEYS[1]).ok ~= 'hash' then
+  redis.call('DEL', KEYS[1])
+end
+local fields = {'limits', 'updated_at'}
+for i = 1, count do
+  fields[#fields + 1] = 'tokens' .. i
+end
+local state = redis.call('HMGET', KEYS[1], unpack(fields))
+
+-- New or changed limits start with full buckets
+local fresh = state[1] ~= limits
+local elapsed = 0
+if not fresh then
+  elapsed = math.max(now - tonumber(state[2]), 0)
+end
+
+local tokens = {}
+local wait = 0
+for i = 1, count do
+  local limit = tonumber(ARGV[2 * i])
+  local per_token = tonumber(ARGV[2 * i + 1]) / limit
+  if fresh then
+    tokens[i] = limit
+  else
+    tokens[i] = math.min(tonumber(state[2 + i]) + elapsed / per_token, limit)
+  end
+  if tokens[i] < 1 then
+    wait = math.max(wait, (1 - tokens[i]) * per_token)
+  end
+end
+if wait > 0 then
+  return string.format('%.17g', wait)
+end
+
+local values = {'limits', limits, 'updated_at', string.format('%d', now)}
+for i = 1, count do
+  values[#values + 1] = 'tokens' .. i
+  values[#values + 1] = string.format('%.17g', tokens[i] - 1)
+end
+redis.call('HSET', KEYS[1], unpack(values))
+-- An untouched bucket is full again after its longest period, it need not be kept
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return '0'
+`)
+
+// Take takes a token if one is available in every limit. Otherwise it returns how long until
+// there is, without taking anything.
+func (l *RateLimiter) Take(ctx context.Context) (time.Duration, error) {
+	args := []interface{}{l.ttl().Milliseconds()}
+	for _, limit := range l.limits {
+		args = append(args, limit.Limit, limit.Per.Milliseconds())
+	}
+
+	reply, err := takeScript.Run(ctx, l.client, []string{l.key}, args...).Text()
+	if err != nil {
+		return 0, fmt.Errorf("failed to take a token of %s: %w", l.key, err)
+	}
+	wait, err := strconv.ParseFloat(reply, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected reply %q taking a token of %s", reply, l.key)
+	}
+	return time.Duration(wait * float64(time.Millisecond)), nil
+}
+
+// Wait takes a token, waiting for one when it is available within the max wait. A longer
+// wait returns a *RateLimitError instead.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	var waited time.Duration
+	for {
+		wait, err := l.Take(ctx)
+		if err != nil {
+			return err
+		}
+		if wait == 0 {
+			return nil
+		}
+		if waited+wait > l.maxWait {
+			return &RateLimitError{RetryAfter: wait}
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return err
+		}
+		waited += wait
+	}
+}
+
+// ttl returns the longest period of the limits
+func (l *RateLimiter) ttl() time.Duration {
+	var ttl time.Duration
+	for _, limit := range l.limits {
+		if limit.Per > ttl {
+			ttl = limit.Per
+		}
+	}
+	return ttl
+}
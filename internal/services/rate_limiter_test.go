@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestRedis starts an in-memory Redis for the test and returns a client of it
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return server, client
+}
+
+func TestRateLimiterTake(t *testing.T) {
+	server, client := newTestRedis(t)
+	limiter := NewRateLimiter(client, "ratelimit:test", 0,
+		RateLimit{Limit: 3, Per: time.Minute},
+		RateLimit{Limit: 5, Per: time.Hour},
+	)
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		name     string
+		at       time.Duration // since start
+		wantWait time.Duration // 0 when a token is taken
+	}{
+		{name: "full bucket", at: 0},
+		{name: "burst", at: 0},
+		{name: "last of the minute", at: time.Second},
+		{name: "minute empty", at: 2 * time.Second, wantWait: 18 * time.Second},
+		{name: "minute refilled one", at: 20 * time.Second},
+		{name: "minute refilled another", at: 40 * time.Second},
+		{name: "hour empty", at: 10 * time.Minute, wantWait: 2 * time.Minute},
+		{name: "hour refilled one", at: 12 * time.Minute},
+	}
+
+	for _, step := range steps {
+		server.SetTime(start.Add(step.at))
+		wait, err := limiter.Take(context.Background())
+		if err != nil {
+			t.Fatalf("%s: Take() error = %v", step.name, err)
+		}
+		if wait.Round(time.Millisecond) != step.wantWait {
+			t.Fatalf("%s: Take() = %s, want %s", step.name, wait, step.wantWait)
+		}
+	}
+	if ttl := server.TTL("ratelimit:test"); ttl != time.Hour {
+		t.Errorf("TTL = %s, want the longest period", ttl)
+	}
+}
+
+func TestRateLimiterTakeChangedLimits(t *testing.T) {
+	server, client := newTestRedis(t)
+	server.SetTime(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+
+	one := NewRateLimiter(client, "ratelimit:test", 0, RateLimit{Limit: 1, Per: time.Minute})
+	if wait, err := one.Take(context.Background()); err != nil || wait != 0 {
+		t.Fatalf("Take() = %s, %v, want a token", wait, err)
+	}
+
+	// State of other limits starts over with full buckets
+	two := NewRateLimiter(client, "ratelimit:test", 0, RateLimit{Limit: 1, Per: time.Minute}, RateLimit{Limit: 1, Per: time.Hour})
+	if wait, err := two.Take(context.Background()); err != nil || wait != 0 {
+		t.Errorf("Take() with changed limits = %s, %v, want full buckets", wait, err)
+	}
+}
+
+func TestRateLimiterTakeOldState(t *testing.T) {
+	server, client := newTestRedis(t)
+	// Buckets used to be stored as JSON, that state is dropped
+	if err := server.Set("ratelimit:test", `{"tokens":[0],"updated_at":"2026-03-10T12:00:00Z"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := NewRateLimiter(client, "ratelimit:test", 0, RateLimit{Limit: 1, Per: time.Minute})
+	if wait, err := limiter.Take(context.Background()); err != nil || wait != 0 {
+		t.Errorf("Take() = %s, %v, want full buckets", wait, err)
+	}
+}
+
+func TestRateLimiterTakeConcurrent(t *testing.T) {
+	_, client := newTestRedis(t)
+	limiter := NewRateLimiter(client, "ratelimit:test", 0, RateLimit{Limit: 20, Per: time.Hour})
+
+	var taken atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			wait, err := limiter.Take(context.Background())
+			if err != nil {
+				t.Errorf("Take() error = %v", err)
+				return
+			}
+			if wait == 0 {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := taken.Load(); got != 20 {
+		t.Errorf("%d tokens taken, want 20", got)
+	}
+}
+
+func TestRateLimiterWait(t *testing.T) {
+	_, client := newTestRedis(t)
+	limits := []RateLimit{{Limit: 2, Per: 200 * time.Millisecond}}
+
+	waiting := NewRateLimiter(client, "ratelimit:waiting", time.Second, limits...)
+	for i := 0; i < 3; i++ {
+		// The third waits about 100ms for a token
+		if err := waiting.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() #%d error = %v", i+1, err)
+		}
+	}
+
+	impatient := NewRateLimiter(client, "ratelimit:impatient", 0, limits...)
+	for i := 0; i < 2; i++ {
+		if err := impatient.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() #%d error = %v", i+1, err)
+		}
+	}
+	var limited *RateLimitError
+	if err := impatient.Wait(context.Background()); !errors.As(err, &limited) || limited.Err != nil {
+		t.Fatalf("Wait() over the limit error = %v, want a *RateLimitError", err)
+	}
+	if limited.RetryAfter <= 0 || limited.RetryAfter > 100*time.Millisecond {
+		t.Errorf("RetryAfter = %s, want at most 100ms", limited.RetryAfter)
+	}
+}
+
+func TestWahaSendMessageRedisUnavailable(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1})
+	defer client.Close()
+	redisServer.Close()
+
+	tests := []struct {
+		name     string
+		failOpen bool
+		wantSent bool
+	}{
+		{name: "deferred", failOpen: false},
+		{name: "sent without a limit", failOpen: true, wantSent: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// Failing the first request ends the send without its typing delays
+				requests.Add(1)
+				w.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer server.Close()
+			t.Setenv("WAHA_BASE_URL", server.URL)
+
+			limiter := NewRateLimiter(client, "ratelimit:test", 0, RateLimit{Limit: 1, Per: time.Minute})
+			limiter.failOpen = tt.failOpen
+			waha := NewWahaService()
+			waha.SetRateLimiter(limiter)
+
+			_, err := waha.SendMessage(context.Background(), "6281246361829", "Halo")
+			var limited *RateLimitError
+			if tt.wantSent {
+				if errors.As(err, &limited) || requests.Load() == 0 {
+					t.Fatalf("SendMessage() error = %v after %d requests, want it sent to WAHA", err, requests.Load())
+				}
+				return
+			}
+			if !errors.As(err, &limited) || limited.Err == nil {
+				t.Fatalf("SendMessage() error = %v, want a *RateLimitError with the Redis error", err)
+			}
+			if limited.RetryAfter != rateLimiterUnavailableRetry || requests.Load() != 0 {
+				t.Errorf("RetryAfter = %s after %d requests, want %s and none sent", limited.RetryAfter, requests.Load(), rateLimiterUnavailableRetry)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// telegramRequestTimeout bounds a single request to the Bot API, on top of any deadline set by the caller's context
+const telegramRequestTimeout = 30 * time.Second
+
+// TelegramService sends messages through the Telegram Bot API
+type TelegramService struct {
+	baseURL     string
+	token       string
+	botUsername string
+	client      *http.Client
+}
+
+// NewTelegramService reads the bot settings from TELEGRAM_BOT_TOKEN, TELEGRAM_BOT_USERNAME
+// and TELEGRAM_API_BASE_URL, which points at a local stub in tests
+func NewTelegramService() *TelegramService {
+	baseURL := os.Getenv("TELEGRAM_API_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.telegram.org"
+	}
+	return &TelegramService{
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		token:       os.Getenv("TELEGRAM_BOT_TOKEN"),
+		botUsername: strings.TrimPrefix(os.Getenv("TELEGRAM_BOT_USERNAME"), "@"),
+		client:      &http.Client{Timeout: telegramRequestTimeout},
+	}
+}
+
+// telegramResponse is the envelope of every Bot API response
+type telegramResponse struct {
+	OK          bool            `json:"ok"`
+	Result      json.RawMessage `json:"result"`
+	ErrorCode   int             `json:"error_code"`
+	Description string          `json:"description"`
+}
+
+// call invokes a Bot API method and returns its result
+func (s *TelegramService) call(ctx context.Context, method string, payload interface{}) (json.RawMessage, error) {
+	if s.token == "" {
+		return nil, fmt.Errorf("telegram bot token not configured")
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/bot%s/%s", s.baseURL, s.token, method), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The URL holds the bot token, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var result telegramResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("%s failed with error %d: %s", method, result.ErrorCode, result.Description)
+	}
+
+	return result.Result, nil
+}
+
+// SendMessage sends a plain text message to a chat and returns the Telegram message ID
+func (s *TelegramService) SendMessage(ctx context.Context, chatID, text string) (string, error) {
+	chatID = strings.TrimSpace(chatID)
+	if chatID == "" {
+		return "", fmt.Errorf("telegram chat ID is empty")
+	}
+
+	result, err := s.call(ctx, "sendMessage", map[string]string{
+		"chat_id": chatID,
+		"text":    text,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var message struct {
+		MessageID int64 `json:"message_id"`
+	}
+	if err := json.Unmarshal(result, &message); err != nil {
+		return "", fmt.Errorf("failed to parse sent message: %w", err)
+	}
+	return strconv.FormatInt(message.MessageID, 10), nil
+}
+
+// TelegramUpdate is an incoming update posted to the bot webhook, only messages are used
+type TelegramUpdate struct {
+	UpdateID int64            `json:"update_id"`
+	Message  *TelegramMessage `json:"message"`
+}
+
+// TelegramMessage is a message sent to the bot
+type TelegramMessage struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	Chat      struct {
+		ID        int64  `json:"id"`
+		Username  string `json:"username"`
+		FirstName string `json:"first_name"`
+	} `json:"chat"`
+}
+
+// StartCode returns the code of a "/start <code>" message, sent when a user opens the StartURL link
+func (u TelegramUpdate) StartCode() (string, bool) {
+	if u.Message == nil {
+		return "", false
+	}
+	code, ok := strings.CutPrefix(strings.TrimSpace(u.Message.Text), "/start ")
+	if !ok {
+		return "", false
+	}
+	code = strings.TrimSpace(code)
+	return code, code != ""
+}
+
+// StartURL returns the deep link that opens the bot and sends "/start <code>", empty when the bot username is not set.
+// The bot can only message users who started a chat with it.
+func (s *TelegramService) StartURL(code string) string {
+	if s.botUsername == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/%s?start=%s", s.botUsername, code)
+}
+
+// ParseTelegramUpdate reads an update posted to the bot webhook
+func ParseTelegramUpdate(body []byte) (TelegramUpdate, error) {
+	var update TelegramUpdate
+	if err := json.Unmarshal(body, &update); err != nil {
+		return update, fmt.Errorf("failed to parse update: %w", err)
+	}
+	return update, nil
+}
+
+// VerifyTelegramSecret checks the X-Telegram-Bot-Api-Secret-Token header Telegram sends with the
+// secret_token set on the webhook
+func VerifyTelegramSecret(header, secret string) bool {
+	if secret == "" || header == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(header), []byte(secret)) == 1
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTelegramSendMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		token     string
+		chatID    string
+		response  string
+		status    int
+		wantID    string
+		wantError string
+	}{
+		{
+			name:     "sent",
+			token:    "123:abc",
+			chatID:   "987654321",
+			response: `{"ok":true,"result":{"message_id":42,"chat":{"id":987654321}}}`,
+			status:   http.StatusOK,
+			wantID:   "42",
+		},
+		{
+			name:      "chat not found",
+			token:     "123:abc",
+			chatID:    "1",
+			response:  `{"ok":false,"error_code":400,"description":"Bad Request: chat not found"}`,
+			status:    http.StatusBadRequest,
+			wantError: "chat not found",
+		},
+		{
+			name:      "unexpected response",
+			token:     "123:abc",
+			chatID:    "1",
+			response:  `<html>Bad Gateway</html>`,
+			status:    http.StatusBadGateway,
+			wantError: "status 502",
+		},
+		{
+			name:      "empty chat ID",
+			token:     "123:abc",
+			wantError: "chat ID is empty",
+		},
+		{
+			name:      "no token",
+			chatID:    "1",
+			wantError: "token not configured",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath string
+			var gotPayload map[string]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				json.NewDecoder(r.Body).Decode(&gotPayload)
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			t.Setenv("TELEGRAM_API_BASE_URL", server.URL+"/")
+			t.Setenv("TELEGRAM_BOT_TOKEN", tt.token)
+			messageID, err := NewTelegramService().SendMessage(context.Background(), tt.chatID, "Halo")
+
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("SendMessage() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SendMessage() error = %v", err)
+			}
+			if messageID != tt.wantID {
+				t.Errorf("message ID = %q, want %q", messageID, tt.wantID)
+			}
+			if gotPath != "/bot"+tt.token+"/sendMessage" {
+				t.Errorf("path = %q, want /bot%s/sendMessage", gotPath, tt.token)
+			}
+			if gotPayload["chat_id"] != tt.chatID || gotPayload["text"] != "Halo" {
+				t.Errorf("payload = %v", gotPayload)
+			}
+		})
+	}
+}
+
+func TestTelegramStartURL(t *testing.T) {
+	t.Setenv("TELEGRAM_BOT_USERNAME", "@patungan_bot")
+	if got := NewTelegramService().StartURL("abc123"); got != "https://t.me/patungan_bot?start=abc123" {
+		t.Errorf("StartURL() = %q, want https://t.me/patungan_bot?start=abc123", got)
+	}
+
+	t.Setenv("TELEGRAM_BOT_USERNAME", "")
+	if got := NewTelegramService().StartURL("abc123"); got != "" {
+		t.Errorf("StartURL() = %q, want empty", got)
+	}
+}
+
+func TestTelegramStartCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantCode string
+		wantOK   bool
+	}{
+		{name: "start from the link", body: `{"update_id":10,"message":{"message_id":1,"text":"/start abc123","chat":{"id":111}}}`, wantCode: "abc123", wantOK: true},
+		{name: "start without code", body: `{"update_id":11,"message":{"message_id":2,"text":"/start","chat":{"id":222}}}`},
+		{name: "other message", body: `{"update_id":12,"message":{"message_id":3,"text":"halo","chat":{"id":333}}}`},
+		{name: "not a message", body: `{"update_id":13,"edited_message":{"message_id":4,"text":"/start abc123","chat":{"id":444}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			update, err := ParseTelegramUpdate([]byte(tt.body))
+			if err != nil {
+				t.Fatalf("ParseTelegramUpdate() error = %v", err)
+			}
+			code, ok := update.StartCode()
+			if code != tt.wantCode || ok != tt.wantOK {
+				t.Errorf("StartCode() = %q, %v, want %q, %v", code, ok, tt.wantCode, tt.wantOK)
+			}
+		})
+	}
+
+	if _, err := ParseTelegramUpdate([]byte(`not json`)); err == nil {
+		t.Error("ParseTelegramUpdate() error = nil, want an error for invalid JSON")
+	}
+}
+
+func TestVerifyTelegramSecret(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		secret string
+		want   bool
+	}{
+		{name: "matching", header: "s3cret", secret: "s3cret", want: true},
+		{name: "wrong", header: "guess", secret: "s3cret"},
+		{name: "missing header", secret: "s3cret"},
+		{name: "no secret configured", header: "s3cret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyTelegramSecret(tt.header, tt.secret); got != tt.want {
+				t.Errorf("VerifyTelegramSecret() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
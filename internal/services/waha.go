@@ -2,46 +2,87 @@ package services
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
+	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
 	"time"
+	"unicode/utf8"
+)
+
+// wahaRequestTimeout bounds a single request to WAHA, on top of any deadline set by the caller's context
+const wahaRequestTimeout = 30 * time.Second
+
+// Pacing of a message, randomized so that messages do not go out at a machine-like rhythm
+const (
+	wahaReadDelay     = 500 * time.Millisecond // pause after marking the chat seen, up to twice as long
+	wahaTypingPerChar = 40 * time.Millisecond
+	wahaMinTyping     = 1 * time.Second
+	wahaMaxTyping     = 8 * time.Second
+	wahaSendDelay     = 100 * time.Millisecond // pause between typing and sending, up to three times as long
 )
 
 type WahaService struct {
 	baseURL string
 	apiKey  string
+	session string
+	limiter *RateLimiter
 	client  *http.Client
 }
 
+// NewWahaService reads the WAHA settings from WAHA_BASE_URL, WAHA_API_KEY and WAHA_SESSION,
+// the session messages are sent from
 func NewWahaService() *WahaService {
-	url := os.Getenv("WAHA_BASE_URL")
-	if url == "" {
-		url = "http://waha:3000"
+	baseURL := os.Getenv("WAHA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://waha:3000"
+	}
+	session := strings.TrimSpace(os.Getenv("WAHA_SESSION"))
+	if session == "" {
+		session = "default"
 	}
 	return &WahaService{
-		baseURL: url,
+		baseURL: baseURL,
 		apiKey:  os.Getenv("WAHA_API_KEY"),
-		client:  &http.Client{},
+		session: session,
+		client:  &http.Client{Timeout: wahaRequestTimeout},
 	}
 }
 
-func (s *WahaService) makeRequest(method, endpoint string, payload interface{}) error {
+// SetRateLimiter limits the messages sent from the session, shared by everything using the limiter's key
+func (s *WahaService) SetRateLimiter(limiter *RateLimiter) {
+	s.limiter = limiter
+}
+
+// Session returns the name of the WAHA session messages are sent from
+func (s *WahaService) Session() string {
+	return s.session
+}
+
+// makeRequest sends a request to WAHA and returns the response body
+func (s *WahaService) makeRequest(ctx context.Context, method, endpoint string, payload interface{}) ([]byte, error) {
 	var bodyReader io.Reader
 	if payload != nil {
 		data, err := json.Marshal(payload)
 		if err != nil {
-			return fmt.Errorf("failed to marshal payload: %w", err)
+			return nil, fmt.Errorf("failed to marshal payload: %w", err)
 		}
 		bodyReader = bytes.NewBuffer(data)
 	}
 
-	req, err := http.NewRequest(method, fmt.Sprintf("%s%s", s.baseURL, endpoint), bodyReader)
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", s.baseURL, endpoint), bodyReader)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -49,45 +90,76 @@ func (s *WahaService) makeRequest(method, endpoint string, payload interface{})
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("request failed with status %d: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	return body, nil
 }
 
-func (s *WahaService) sendSeen(chatId string) error {
-	return s.makeRequest("POST", "/api/sendSeen", map[string]string{
+func (s *WahaService) sendSeen(ctx context.Context, chatId string) error {
+	_, err := s.makeRequest(ctx, "POST", "/api/sendSeen", map[string]string{
 		"chatId":  chatId,
-		"session": "default",
+		"session": s.session,
 	})
+	return err
 }
 
-func (s *WahaService) startTyping(chatId string) error {
-	return s.makeRequest("POST", "/api/startTyping", map[string]string{
+func (s *WahaService) startTyping(ctx context.Context, chatId string) error {
+	_, err := s.makeRequest(ctx, "POST", "/api/startTyping", map[string]string{
 		"chatId":  chatId,
-		"session": "default",
+		"session": s.session,
 	})
+	return err
 }
 
-func (s *WahaService) stopTyping(chatId string) error {
-	return s.makeRequest("POST", "/api/stopTyping", map[string]string{
+func (s *WahaService) stopTyping(ctx context.Context, chatId string) error {
+	_, err := s.makeRequest(ctx, "POST", "/api/stopTyping", map[string]string{
 		"chatId":  chatId,
-		"session": "default",
+		"session": s.session,
 	})
+	return err
 }
 
-func (s *WahaService) sendText(chatId, text string) error {
-	return s.makeRequest("POST", "/api/sendText", map[string]string{
+// sendText sends a text message and returns the WhatsApp message ID
+func (s *WahaService) sendText(ctx context.Context, chatId, text string) (string, error) {
+	body, err := s.makeRequest(ctx, "POST", "/api/sendText", map[string]string{
 		"chatId":  chatId,
 		"text":    text,
-		"session": "default",
+		"session": s.session,
 	})
+	if err != nil {
+		return "", err
+	}
+	return parseMessageID(body), nil
+}
+
+// parseMessageID reads the message ID from a sent message. Depending on the engine WAHA
+// returns the ID as a string or as an object, an empty ID is returned when there is none.
+func parseMessageID(body []byte) string {
+	var message struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if err := json.Unmarshal(body, &message); err != nil || len(message.ID) == 0 {
+		return ""
+	}
+
+	var id string
+	if err := json.Unmarshal(message.ID, &id); err == nil {
+		return id
+	}
+	var idObject struct {
+		Serialized string `json:"_serialized"`
+	}
+	if err := json.Unmarshal(message.ID, &idObject); err == nil {
+		return idObject.Serialized
+	}
+	return ""
 }
 
 // NormalizeChatID normalizes WhatsApp chat IDs by adding required suffixes and standardizing country codes
@@ -111,32 +183,220 @@ func NormalizeChatID(chatId string) string {
 	return chatId + "@c.us"
 }
 
+// ChatIDPhones returns the phone numbers NormalizeChatID turns into the given chat, to look up
+// the user of a chat by phone number, e.g. "6281@c.us", "6281" and "081" for "081@c.us"
+func ChatIDPhones(chatID string) []string {
+	chatID = NormalizeChatID(chatID)
+	if strings.HasSuffix(chatID, "@g.us") {
+		return []string{chatID}
+	}
+
+	number := strings.TrimSuffix(chatID, "@c.us")
+	phones := []string{chatID, number}
+	if local, ok := strings.CutPrefix(number, "62"); ok {
+		phones = append(phones, "0"+local+"@c.us", "0"+local)
+	}
+	return phones
+}
+
 // SendMessage sends a message with authentic behavior (seen -> typing -> stop typing -> send)
-func (s *WahaService) SendMessage(chatId, text string) error {
+// and returns the WhatsApp message ID. Over the rate limit it returns a *RateLimitError.
+func (s *WahaService) SendMessage(ctx context.Context, chatId, text string) (string, error) {
 	chatId = NormalizeChatID(chatId)
 
-	// a. sendSeen request, wait for 100ms
-	if err := s.sendSeen(chatId); err != nil {
-		return fmt.Errorf("failed to send seen: %w", err)
+	if s.limiter != nil {
+		if err := s.limiter.Wait(ctx); err != nil {
+			var limited *RateLimitError
+			if errors.As(err, &limited) || ctx.Err() != nil {
+				return "", err
+			}
+			if !s.limiter.failOpen {
+				// Without Redis there is no telling how many messages were sent, send later
+				return "", &RateLimitError{RetryAfter: rateLimiterUnavailableRetry, Err: err}
+			}
+			log.Printf("WhatsApp rate limiter unavailable, sending without it: %v", err)
+		}
+	}
+
+	// a. sendSeen request, wait as if reading the chat
+	if err := s.sendSeen(ctx, chatId); err != nil {
+		return "", fmt.Errorf("failed to send seen: %w", err)
+	}
+	if err := sleepContext(ctx, wahaReadDelay+time.Duration(rand.Int64N(int64(wahaReadDelay)))); err != nil {
+		return "", err
 	}
-	time.Sleep(100 * time.Millisecond)
 
-	// b. send startTyping request, wait for 150ms
-	if err := s.startTyping(chatId); err != nil {
-		return fmt.Errorf("failed to start typing: %w", err)
+	// b. send startTyping request, wait as long as typing the message takes
+	if err := s.startTyping(ctx, chatId); err != nil {
+		return "", fmt.Errorf("failed to start typing: %w", err)
+	}
+	if err := sleepContext(ctx, typingDelay(text, rand.Float64())); err != nil {
+		return "", err
 	}
-	time.Sleep(150 * time.Millisecond)
 
-	// c. send stopTyping request, wait for 50ms
-	if err := s.stopTyping(chatId); err != nil {
-		return fmt.Errorf("failed to stop typing: %w", err)
+	// c. send stopTyping request, wait a moment
+	if err := s.stopTyping(ctx, chatId); err != nil {
+		return "", fmt.Errorf("failed to stop typing: %w", err)
+	}
+	if err := sleepContext(ctx, wahaSendDelay+time.Duration(rand.Int64N(int64(2*wahaSendDelay)))); err != nil {
+		return "", err
 	}
-	time.Sleep(50 * time.Millisecond)
 
 	// d. send sendText request
-	if err := s.sendText(chatId, text); err != nil {
-		return fmt.Errorf("failed to send text: %w", err)
+	messageID, err := s.sendText(ctx, chatId, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to send text: %w", err)
 	}
 
-	return nil
+	return messageID, nil
+}
+
+// typingDelay returns how long typing a message takes, in proportion to its length within
+// wahaMinTyping and wahaMaxTyping, and randomized by +/- 25% for a sample in [0, 1)
+func typingDelay(text string, sample float64) time.Duration {
+	delay := time.Duration(utf8.RuneCountInString(text)) * wahaTypingPerChar
+	if delay < wahaMinTyping {
+		delay = wahaMinTyping
+	}
+	if delay > wahaMaxTyping {
+		delay = wahaMaxTyping
+	}
+	return time.Duration(float64(delay) * (0.75 + 0.5*sample))
+}
+
+// sleepContext waits for d, returning early with the context error if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WahaMessage is an incoming message delivered by a WAHA webhook
+type WahaMessage struct {
+	ID          string `json:"id"`
+	From        string `json:"from"`        // the chat, a number or a group
+	Participant string `json:"participant"` // the sender in group chats
+	FromMe      bool   `json:"fromMe"`
+	Body        string `json:"body"`
+}
+
+// Sender returns who wrote the message, the participant in groups and the chat otherwise
+func (m WahaMessage) Sender() string {
+	if m.Participant != "" {
+		return m.Participant
+	}
+	return m.From
+}
+
+// ParseWahaMessageEvent reads a webhook event and returns the incoming text message it carries.
+// Other events, our own messages, status updates and messages without an ID, which could not be
+// told apart from a redelivery, are reported as not being a message.
+func ParseWahaMessageEvent(body []byte) (WahaMessage, bool, error) {
+	var event struct {
+		Event   string      `json:"event"`
+		Payload WahaMessage `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return WahaMessage{}, false, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+
+	msg := event.Payload
+	if event.Event != "message" || msg.ID == "" || msg.FromMe || msg.From == "" || msg.From == "status@broadcast" || strings.TrimSpace(msg.Body) == "" {
+		return WahaMessage{}, false, nil
+	}
+	return msg, true, nil
+}
+
+// VerifyWebhookSignature checks the X-Webhook-Hmac header WAHA sends when a webhook HMAC key
+// is configured, a hex encoded HMAC-SHA512 of the request body
+func VerifyWebhookSignature(body []byte, signature, key string) bool {
+	if key == "" || signature == "" {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha512.New, []byte(key))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// WahaSession is the state of a WAHA session
+type WahaSession struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // one of the models.WhatsappSessionStatus values
+	Me     *struct {
+		ID       string `json:"id"`
+		PushName string `json:"pushName"`
+	} `json:"me"` // the logged in account, only set when the session is working
+}
+
+// Account returns the logged in phone number and name, empty when the session is not logged in
+func (s WahaSession) Account() string {
+	if s.Me == nil {
+		return ""
+	}
+	account := strings.TrimSuffix(s.Me.ID, "@c.us")
+	if s.Me.PushName != "" {
+		account += " (" + s.Me.PushName + ")"
+	}
+	return account
+}
+
+// sessionEndpoint returns the path of an action on the session
+func (s *WahaService) sessionEndpoint(action string) string {
+	endpoint := "/api/sessions/" + url.PathEscape(s.session)
+	if action != "" {
+		endpoint += "/" + action
+	}
+	return endpoint
+}
+
+// SessionStatus returns the current state of the session
+func (s *WahaService) SessionStatus(ctx context.Context) (WahaSession, error) {
+	body, err := s.makeRequest(ctx, "GET", s.sessionEndpoint(""), nil)
+	if err != nil {
+		return WahaSession{}, err
+	}
+
+	var session WahaSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return WahaSession{}, fmt.Errorf("failed to parse session: %w", err)
+	}
+	return session, nil
+}
+
+// LoginQR returns the PNG of the QR code to scan with the phone, only available while the
+// session status is SCAN_QR_CODE
+func (s *WahaService) LoginQR(ctx context.Context) ([]byte, error) {
+	body, err := s.makeRequest(ctx, "GET", "/api/"+url.PathEscape(s.session)+"/auth/qr?format=image", nil)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(body, []byte("\x89PNG")) {
+		return nil, fmt.Errorf("unexpected QR response: %s", string(body))
+	}
+	return body, nil
+}
+
+// RestartSession stops and starts the session again, which is enough to recover most failures
+func (s *WahaService) RestartSession(ctx context.Context) error {
+	_, err := s.makeRequest(ctx, "POST", s.sessionEndpoint("restart"), nil)
+	return err
+}
+
+// LogoutSession logs the WhatsApp account out and restarts the session, which then waits for
+// a new QR code to be scanned
+func (s *WahaService) LogoutSession(ctx context.Context) error {
+	if _, err := s.makeRequest(ctx, "POST", s.sessionEndpoint("logout"), nil); err != nil {
+		return err
+	}
+	return s.RestartSession(ctx)
 }
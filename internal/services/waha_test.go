@@ -1,7 +1,16 @@
 package services
 
 import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNormalizeChatID(t *testing.T) {
@@ -46,3 +55,322 @@ func TestNormalizeChatID(t *testing.T) {
 		})
 	}
 }
+
+func TestChatIDPhones(t *testing.T) {
+	tests := []struct {
+		chatID string
+		want   []string
+	}{
+		{chatID: "6281246361829@c.us", want: []string{"6281246361829@c.us", "6281246361829", "081246361829@c.us", "081246361829"}},
+		{chatID: "081246361829", want: []string{"6281246361829@c.us", "6281246361829", "081246361829@c.us", "081246361829"}},
+		{chatID: "14155550100@c.us", want: []string{"14155550100@c.us", "14155550100"}},
+		{chatID: "120363407813232111@g.us", want: []string{"120363407813232111@g.us"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.chatID, func(t *testing.T) {
+			phones := ChatIDPhones(tt.chatID)
+			if !reflect.DeepEqual(phones, tt.want) {
+				t.Fatalf("ChatIDPhones(%q) = %v, want %v", tt.chatID, phones, tt.want)
+			}
+			for _, phone := range phones {
+				if NormalizeChatID(phone) != NormalizeChatID(tt.chatID) {
+					t.Errorf("NormalizeChatID(%q) = %q, want %q", phone, NormalizeChatID(phone), NormalizeChatID(tt.chatID))
+				}
+			}
+		})
+	}
+}
+
+func TestParseMessageID(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{
+			name:     "string id",
+			body:     `{"id": "true_6281246361829@c.us_3EB0B430B6F8F1D0E053", "body": "Halo"}`,
+			expected: "true_6281246361829@c.us_3EB0B430B6F8F1D0E053",
+		},
+		{
+			name:     "object id",
+			body:     `{"id": {"fromMe": true, "remote": "6281246361829@c.us", "id": "3EB0", "_serialized": "true_6281246361829@c.us_3EB0"}}`,
+			expected: "true_6281246361829@c.us_3EB0",
+		},
+		{
+			name:     "no id",
+			body:     `{"body": "Halo"}`,
+			expected: "",
+		},
+		{
+			name:     "not json",
+			body:     `ok`,
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMessageID([]byte(tt.body)); got != tt.expected {
+				t.Errorf("parseMessageID(%s) = %q; want %q", tt.body, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseWahaMessageEvent(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantOK     bool
+		wantSender string
+		wantErr    bool
+	}{
+		{
+			name:       "personal message",
+			body:       `{"event":"message","session":"default","payload":{"id":"false_6281@c.us_AAA","from":"6281246361829@c.us","fromMe":false,"body":"tagihan"}}`,
+			wantOK:     true,
+			wantSender: "6281246361829@c.us",
+		},
+		{
+			name:       "group message",
+			body:       `{"event":"message","payload":{"id":"x","from":"120363407813232111@g.us","participant":"6281246361829@c.us","body":"tagihan"}}`,
+			wantOK:     true,
+			wantSender: "6281246361829@c.us",
+		},
+		{
+			name: "own message",
+			body: `{"event":"message","payload":{"from":"6281246361829@c.us","fromMe":true,"body":"tagihan"}}`,
+		},
+		{
+			name: "status update",
+			body: `{"event":"message","payload":{"from":"status@broadcast","body":"hi"}}`,
+		},
+		{
+			name: "other event",
+			body: `{"event":"session.status","payload":{"status":"WORKING"}}`,
+		},
+		{
+			name: "without message ID",
+			body: `{"event":"message","payload":{"from":"6281246361829@c.us","body":"tagihan"}}`,
+		},
+		{
+			name: "media without caption",
+			body: `{"event":"message","payload":{"from":"6281246361829@c.us","body":""}}`,
+		},
+		{
+			name:    "not json",
+			body:    `ok`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, ok, err := ParseWahaMessageEvent([]byte(tt.body))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWahaMessageEvent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if ok != tt.wantOK {
+				t.Fatalf("ParseWahaMessageEvent() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && msg.Sender() != tt.wantSender {
+				t.Errorf("Sender() = %q, want %q", msg.Sender(), tt.wantSender)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"event":"message"}`)
+	mac := hmac.New(sha512.New, []byte("secret"))
+	mac.Write(body)
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	tests := []struct {
+		name      string
+		body      []byte
+		signature string
+		key       string
+		expected  bool
+	}{
+		{name: "valid", body: body, signature: signature, key: "secret", expected: true},
+		{name: "wrong key", body: body, signature: signature, key: "other", expected: false},
+		{name: "tampered body", body: []byte(`{"event":"message "}`), signature: signature, key: "secret", expected: false},
+		{name: "missing signature", body: body, key: "secret", expected: false},
+		{name: "not hex", body: body, signature: "zz", key: "secret", expected: false},
+		{name: "no key configured", body: body, signature: signature, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature(tt.body, tt.signature, tt.key); got != tt.expected {
+				t.Errorf("VerifyWebhookSignature() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWahaSessionStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		session     string
+		response    string
+		status      int
+		wantPath    string
+		wantStatus  string
+		wantAccount string
+		wantError   string
+	}{
+		{
+			name:        "working",
+			session:     "patungan",
+			response:    `{"name":"patungan","status":"WORKING","me":{"id":"6281234567890@c.us","pushName":"Patungan"}}`,
+			status:      http.StatusOK,
+			wantPath:    "/api/sessions/patungan",
+			wantStatus:  "WORKING",
+			wantAccount: "6281234567890 (Patungan)",
+		},
+		{
+			name:       "default session waiting for QR",
+			response:   `{"name":"default","status":"SCAN_QR_CODE","me":null}`,
+			status:     http.StatusOK,
+			wantPath:   "/api/sessions/default",
+			wantStatus: "SCAN_QR_CODE",
+		},
+		{
+			name:      "session not found",
+			session:   "missing",
+			response:  `{"statusCode":404,"message":"Session not found"}`,
+			status:    http.StatusNotFound,
+			wantPath:  "/api/sessions/missing",
+			wantError: "status 404",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotKey string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotKey = r.Header.Get("X-Api-Key")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			t.Setenv("WAHA_BASE_URL", server.URL)
+			t.Setenv("WAHA_API_KEY", "secret")
+			t.Setenv("WAHA_SESSION", tt.session)
+			session, err := NewWahaService().SessionStatus(context.Background())
+
+			if gotPath != tt.wantPath {
+				t.Errorf("path = %q, want %q", gotPath, tt.wantPath)
+			}
+			if gotKey != "secret" {
+				t.Errorf("X-Api-Key = %q, want secret", gotKey)
+			}
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("SessionStatus() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SessionStatus() error = %v", err)
+			}
+			if session.Status != tt.wantStatus || session.Account() != tt.wantAccount {
+				t.Errorf("SessionStatus() = %q, %q, want %q, %q", session.Status, session.Account(), tt.wantStatus, tt.wantAccount)
+			}
+		})
+	}
+}
+
+func TestWahaLoginQR(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nimage")
+
+	tests := []struct {
+		name      string
+		response  []byte
+		wantError string
+	}{
+		{name: "png", response: png},
+		{name: "not waiting for a scan", response: []byte(`{"status":"WORKING"}`), wantError: "unexpected QR response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotURL string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotURL = r.URL.String()
+				w.Write(tt.response)
+			}))
+			defer server.Close()
+
+			t.Setenv("WAHA_BASE_URL", server.URL)
+			t.Setenv("WAHA_SESSION", "patungan")
+			image, err := NewWahaService().LoginQR(context.Background())
+
+			if gotURL != "/api/patungan/auth/qr?format=image" {
+				t.Errorf("URL = %q, want /api/patungan/auth/qr?format=image", gotURL)
+			}
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("LoginQR() error = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoginQR() error = %v", err)
+			}
+			if string(image) != string(png) {
+				t.Errorf("LoginQR() = %q, want %q", image, png)
+			}
+		})
+	}
+}
+
+func TestWahaLogoutSession(t *testing.T) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("WAHA_BASE_URL", server.URL)
+	t.Setenv("WAHA_SESSION", "")
+	if err := NewWahaService().LogoutSession(context.Background()); err != nil {
+		t.Fatalf("LogoutSession() error = %v", err)
+	}
+
+	expected := []string{"POST /api/sessions/default/logout", "POST /api/sessions/default/restart"}
+	if strings.Join(calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("calls = %v, want %v", calls, expected)
+	}
+}
+
+func TestTypingDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		sample   float64
+		expected time.Duration
+	}{
+		{name: "short message is typed for the minimum", text: "ok", sample: 0.5, expected: wahaMinTyping},
+		{name: "in proportion to length", text: strings.Repeat("a", 100), sample: 0.5, expected: 4 * time.Second},
+		{name: "counts characters, not bytes", text: strings.Repeat("é", 100), sample: 0.5, expected: 4 * time.Second},
+		{name: "long message is capped", text: strings.Repeat("a", 1000), sample: 0.5, expected: wahaMaxTyping},
+		{name: "randomized down", text: strings.Repeat("a", 100), sample: 0, expected: 3 * time.Second},
+		{name: "randomized up", text: strings.Repeat("a", 100), sample: 0.999, expected: 4998 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := typingDelay(tt.text, tt.sample).Round(time.Millisecond); got != tt.expected {
+				t.Errorf("typingDelay() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
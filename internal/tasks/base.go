@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"time"
 
+	"gorm.io/gorm"
+
 	"patungan_app_echo/internal/models"
 )
 
@@ -30,3 +32,17 @@ func BuildScheduledTask(taskName string, args interface{}, due time.Time, recurr
 		MaxAttempt:        maxAttempt,
 	}, nil
 }
+
+// deferTask enqueues another run of a one-time task with the same arguments, for handlers that
+// cannot do their work before due
+func deferTask(db *gorm.DB, task models.ScheduledTask, args interface{}, due time.Time) (*models.ScheduledTask, error) {
+	deferred, err := BuildScheduledTask(task.TaskName, args, due, nil, models.ScheduledTaskTypeOneTime, task.MaxAttempt)
+	if err != nil {
+		return nil, err
+	}
+	deferred.MisfirePolicy = task.MisfirePolicy
+	if err := db.Create(deferred).Error; err != nil {
+		return nil, fmt.Errorf("failed to defer %s task: %w", task.TaskName, err)
+	}
+	return deferred, nil
+}
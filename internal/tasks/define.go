@@ -0,0 +1,219 @@
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+)
+
+// DefaultMaxAttempt is the number of attempts a task gets when its definition does not set one
+const DefaultMaxAttempt = 3
+
+// Validator is implemented by task arguments that check their own values
+type Validator interface {
+	Validate() error
+}
+
+// TypedHandler is a task handler that receives its arguments already decoded
+type TypedHandler[Args any] func(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args Args) (map[string]interface{}, error)
+
+// TypedRequeueHook prepares the decoded arguments of a task before it is requeued.
+// Changes made to args are written back to the task.
+type TypedRequeueHook[Args any] func(db *gorm.DB, task *models.ScheduledTask, args *Args) error
+
+// TaskOptions holds the optional settings of a task definition
+type TaskOptions[Args any] struct {
+	// Timeout is the execution deadline, DefaultTaskTimeout when zero
+	Timeout time.Duration
+	// MaxAttempt is the number of attempts for new tasks, DefaultMaxAttempt when zero
+	MaxAttempt int
+	// MisfirePolicy decides how recurring tasks handle missed occurrences, run_once when empty
+	MisfirePolicy models.MisfirePolicy
+	// Requeue runs before a task is put back in the queue from the admin pages
+	Requeue TypedRequeueHook[Args]
+}
+
+// Definition is a task whose arguments are (de)serialized as Args
+type Definition[Args any] struct {
+	name    string
+	handler TypedHandler[Args]
+	options TaskOptions[Args]
+}
+
+// Define declares a task with typed arguments. Call Register to make it available to the worker.
+func Define[Args any](name string, handler TypedHandler[Args], options TaskOptions[Args]) *Definition[Args] {
+	if options.MaxAttempt <= 0 {
+		options.MaxAttempt = DefaultMaxAttempt
+	}
+	if options.MisfirePolicy == "" {
+		options.MisfirePolicy = models.MisfirePolicyRunOnce
+	}
+	return &Definition[Args]{name: name, handler: handler, options: options}
+}
+
+// TaskID returns the unique identifier for this task
+func (d *Definition[Args]) TaskID() string {
+	return d.name
+}
+
+// Register adds the task, its argument validation and requeue hook to the global registry
+func (d *Definition[Args]) Register() {
+	GlobalRegistry.add(d.name, registeredTask{
+		handler:       d.execute,
+		timeout:       d.options.Timeout,
+		validate:      d.validateArguments,
+		maxAttempt:    d.options.MaxAttempt,
+		misfirePolicy: d.options.MisfirePolicy,
+	})
+	if d.options.Requeue != nil {
+		GlobalRegistry.RegisterRequeueHook(d.name, d.prepareRequeue)
+	}
+}
+
+// Encode converts args into the map stored on the task row
+func (d *Definition[Args]) Encode(args Args) (map[string]interface{}, error) {
+	argsBytes, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal args: %w", err)
+	}
+
+	var mapArgs map[string]interface{}
+	if err := json.Unmarshal(argsBytes, &mapArgs); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal into map: %w", err)
+	}
+	return mapArgs, nil
+}
+
+// Decode reads the arguments of a stored task. Unknown keys are ignored,
+// so rows written by older versions of the task still run.
+func (d *Definition[Args]) Decode(arguments map[string]interface{}) (Args, error) {
+	var args Args
+	argsBytes, err := json.Marshal(arguments)
+	if err != nil {
+		return args, fmt.Errorf("failed to marshal args: %w", err)
+	}
+	if err := json.Unmarshal(argsBytes, &args); err != nil {
+		return args, fmt.Errorf("failed to unmarshal args: %w", err)
+	}
+	return args, nil
+}
+
+// NewTask validates args and builds a ScheduledTask record for this task.
+// A non-empty recurringInterval makes it a recurring task.
+func (d *Definition[Args]) NewTask(args Args, due time.Time, recurringInterval *string) (*models.ScheduledTask, error) {
+	if err := validate(args); err != nil {
+		return nil, fmt.Errorf("invalid arguments for %s: %w", d.name, err)
+	}
+
+	taskType := models.ScheduledTaskTypeOneTime
+	if recurringInterval != nil && *recurringInterval != "" {
+		taskType = models.ScheduledTaskTypeRecurring
+	}
+	task, err := BuildScheduledTask(d.name, args, due, recurringInterval, taskType, d.options.MaxAttempt)
+	if err != nil {
+		return nil, err
+	}
+	task.MisfirePolicy = d.options.MisfirePolicy
+	return task, nil
+}
+
+// Enqueue builds a task with NewTask and inserts it
+func (d *Definition[Args]) Enqueue(db *gorm.DB, args Args, due time.Time, recurringInterval *string) (*models.ScheduledTask, error) {
+	task, err := d.NewTask(args, due, recurringInterval)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Create(task).Error; err != nil {
+		return nil, fmt.Errorf("failed to create %s task: %w", d.name, err)
+	}
+	return task, nil
+}
+
+// EnsureRecurring schedules a recurring run of this task unless one already exists.
+// Used for system tasks that must always be scheduled; a disabled or failed task is left alone.
+// The check and create hold an advisory lock on the task name, so workers starting together
+// schedule the task only once.
+func (d *Definition[Args]) EnsureRecurring(db *gorm.DB, args Args, recurringInterval string) (*models.ScheduledTask, error) {
+	var task *models.ScheduledTask
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "ensure_recurring:"+d.name).Error; err != nil {
+			return fmt.Errorf("failed to lock %s task: %w", d.name, err)
+		}
+
+		var existing models.ScheduledTask
+		err := tx.Where("task_name = ? AND task_type = ? AND status <> ?", d.name, models.ScheduledTaskTypeRecurring, models.ScheduledTaskStatusDone).
+			First(&existing).Error
+		if err == nil {
+			task = &existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to look up %s task: %w", d.name, err)
+		}
+
+		task, err = d.Enqueue(tx, args, time.Now(), &recurringInterval)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// execute decodes the task arguments and runs the typed handler
+func (d *Definition[Args]) execute(ctx context.Context, db *gorm.DB, task models.ScheduledTask) (map[string]interface{}, error) {
+	args, err := d.Decode(task.Arguments)
+	if err != nil {
+		return nil, err
+	}
+	return d.handler(ctx, db, task, args)
+}
+
+// validateArguments strictly checks raw arguments, e.g. JSON typed in by an admin
+func (d *Definition[Args]) validateArguments(arguments map[string]interface{}) error {
+	argsBytes, err := json.Marshal(arguments)
+	if err != nil {
+		return fmt.Errorf("failed to marshal args: %w", err)
+	}
+
+	var args Args
+	decoder := json.NewDecoder(bytes.NewReader(argsBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&args); err != nil {
+		return err
+	}
+	return validate(args)
+}
+
+// prepareRequeue runs the typed requeue hook and stores the updated arguments on the task
+func (d *Definition[Args]) prepareRequeue(db *gorm.DB, task *models.ScheduledTask) error {
+	args, err := d.Decode(task.Arguments)
+	if err != nil {
+		return err
+	}
+	if err := d.options.Requeue(db, task, &args); err != nil {
+		return err
+	}
+
+	arguments, err := d.Encode(args)
+	if err != nil {
+		return err
+	}
+	task.Arguments = arguments
+	return nil
+}
+
+// validate calls Validate on args that implement Validator
+func validate(args any) error {
+	if v, ok := args.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
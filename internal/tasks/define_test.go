@@ -0,0 +1,113 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+)
+
+var testDue = time.Date(2025, 1, 5, 9, 0, 0, 0, time.UTC)
+
+func TestDefinitionValidateArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		arguments map[string]interface{}
+		wantErr   bool
+	}{
+		{
+			name:      "valid arguments",
+			arguments: map[string]interface{}{"plan_id": 12},
+			wantErr:   false,
+		},
+		{
+			name:      "missing required field",
+			arguments: map[string]interface{}{},
+			wantErr:   true,
+		},
+		{
+			name:      "unknown field",
+			arguments: map[string]interface{}{"plan_id": 12, "planId": 12},
+			wantErr:   true,
+		},
+		{
+			name:      "wrong type",
+			arguments: map[string]interface{}{"plan_id": "twelve"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ProcessPlanScheduleTask.validateArguments(tt.arguments)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateArguments(%v) error = %v; wantErr %v", tt.arguments, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryTaskDefaults(t *testing.T) {
+	registry := &Registry{handlers: make(map[string]registeredTask), requeueHooks: make(map[string]RequeueHook)}
+	registry.add("catch_up", registeredTask{maxAttempt: 5, misfirePolicy: models.MisfirePolicyCatchUpAll})
+	registry.Register("plain", nil, 0)
+
+	tests := []struct {
+		name        string
+		wantAttempt int
+		wantPolicy  models.MisfirePolicy
+	}{
+		{name: "catch_up", wantAttempt: 5, wantPolicy: models.MisfirePolicyCatchUpAll},
+		{name: "plain", wantAttempt: DefaultMaxAttempt, wantPolicy: models.MisfirePolicyRunOnce},
+		{name: "unknown", wantAttempt: DefaultMaxAttempt, wantPolicy: models.MisfirePolicyRunOnce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt, policy := registry.TaskDefaults(tt.name)
+			if attempt != tt.wantAttempt || policy != tt.wantPolicy {
+				t.Errorf("TaskDefaults(%q) = %d, %q, want %d, %q", tt.name, attempt, policy, tt.wantAttempt, tt.wantPolicy)
+			}
+		})
+	}
+}
+
+func TestDefinitionNewTask(t *testing.T) {
+	interval := "FREQ=MONTHLY"
+
+	tests := []struct {
+		name              string
+		args              ProcessPlanScheduleArgs
+		recurringInterval *string
+		wantErr           bool
+		wantRecurring     bool
+	}{
+		{name: "one-time task", args: ProcessPlanScheduleArgs{PlanID: 1}},
+		{name: "recurring task", args: ProcessPlanScheduleArgs{PlanID: 1}, recurringInterval: &interval, wantRecurring: true},
+		{name: "invalid arguments", args: ProcessPlanScheduleArgs{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			task, err := ProcessPlanScheduleTask.NewTask(tt.args, testDue, tt.recurringInterval)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewTask() error = %v; wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if task.TaskName != "process_plan_schedule" {
+				t.Errorf("TaskName = %s; want process_plan_schedule", task.TaskName)
+			}
+			if task.MaxAttempt != DefaultMaxAttempt {
+				t.Errorf("MaxAttempt = %d; want %d", task.MaxAttempt, DefaultMaxAttempt)
+			}
+			if isRecurring := task.TaskType == models.ScheduledTaskTypeRecurring; isRecurring != tt.wantRecurring {
+				t.Errorf("TaskType = %s; want recurring %v", task.TaskType, tt.wantRecurring)
+			}
+			if planID, _ := task.Arguments["plan_id"].(float64); planID != float64(tt.args.PlanID) {
+				t.Errorf("Arguments[plan_id] = %v; want %d", task.Arguments["plan_id"], tt.args.PlanID)
+			}
+		})
+	}
+}
@@ -1,13 +1,48 @@
 package tasks
 
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
 // DefineTasks registers all available tasks
 func DefineTasks() {
 	// Register general tasks
-	RegisterHandler(LogInfoTask.TaskID(), LogInfoTask.HandleExecution)
+	LogInfoTask.Register()
 
 	// Register plan tasks
-	RegisterHandler(ProcessPlanScheduleTask.TaskID(), ProcessPlanScheduleTask.HandleExecution)
+	ProcessPlanScheduleTask.Register()
 
 	// Register notification tasks
-	RegisterHandler(SendNotificationTask.TaskID(), SendNotificationTask.HandleExecution)
+	SendNotificationTask.Register()
+	RetryNotificationDeliveriesTask.Register()
+	HandleWhatsappMessageTask.Register()
+	SendGroupDigestTask.Register()
+	SendGroupDigestsTask.Register()
+	CheckWhatsappSessionTask.Register()
+
+	// Register payment due tasks
+	MarkOverdueDuesTask.Register()
+	SendPaymentRemindersTask.Register()
+}
+
+// EnsureSystemTasks schedules the recurring tasks the app relies on, if they are not scheduled yet
+func EnsureSystemTasks(db *gorm.DB) error {
+	if _, err := MarkOverdueDuesTask.EnsureRecurring(db, MarkOverdueDuesArgs{}, MarkOverdueDuesInterval); err != nil {
+		return fmt.Errorf("failed to schedule overdue task: %w", err)
+	}
+	if _, err := SendPaymentRemindersTask.EnsureRecurring(db, SendPaymentRemindersArgs{}, SendPaymentRemindersInterval); err != nil {
+		return fmt.Errorf("failed to schedule reminder task: %w", err)
+	}
+	if _, err := RetryNotificationDeliveriesTask.EnsureRecurring(db, RetryNotificationDeliveriesArgs{}, RetryNotificationDeliveriesInterval); err != nil {
+		return fmt.Errorf("failed to schedule delivery retry task: %w", err)
+	}
+	if _, err := SendGroupDigestsTask.EnsureRecurring(db, SendGroupDigestsArgs{}, SendGroupDigestsInterval); err != nil {
+		return fmt.Errorf("failed to schedule group digest task: %w", err)
+	}
+	if _, err := CheckWhatsappSessionTask.EnsureRecurring(db, CheckWhatsappSessionArgs{}, CheckWhatsappSessionInterval); err != nil {
+		return fmt.Errorf("failed to schedule WhatsApp session check: %w", err)
+	}
+	return nil
 }
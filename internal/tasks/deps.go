@@ -0,0 +1,41 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+
+	"patungan_app_echo/internal/services"
+)
+
+// Deps holds the services task handlers use, built once by the worker at startup
+type Deps struct {
+	// Notifiers sends messages by channel, deliveries on channels without a notifier are skipped
+	Notifiers *services.NotifierRegistry
+	// Payments checks payments with the gateways, nil when the worker has none
+	Payments *services.PaymentService
+	// RetryPolicy spaces out the attempts of failed deliveries, RetryPolicyFromEnv when zero
+	RetryPolicy RetryPolicy
+}
+
+type depsKey struct{}
+
+// WithDeps returns a context that carries the dependencies to the handlers run with it
+func WithDeps(ctx context.Context, deps Deps) context.Context {
+	return context.WithValue(ctx, depsKey{}, deps)
+}
+
+// errNoDeps is returned by handlers run on a context without dependencies, e.g. by a worker
+// that was not set up with WithDeps
+var errNoDeps = errors.New("task dependencies are not set, the worker must run handlers with WithDeps")
+
+// depsFrom returns the dependencies carried by the context
+func depsFrom(ctx context.Context) (Deps, error) {
+	deps, ok := ctx.Value(depsKey{}).(Deps)
+	if !ok || deps.Notifiers == nil {
+		return Deps{}, errNoDeps
+	}
+	if deps.RetryPolicy == (RetryPolicy{}) {
+		deps.RetryPolicy = RetryPolicyFromEnv()
+	}
+	return deps, nil
+}
@@ -8,18 +8,18 @@ import (
 	"gorm.io/gorm"
 )
 
-// LogInfoTaskDef encapsulates the log info task
-type LogInfoTaskDef struct{}
-
-// TaskID returns the unique identifier for this task
-func (t *LogInfoTaskDef) TaskID() string {
-	return "log_info"
+// LogInfoArgs defines the arguments for the log info task
+type LogInfoArgs struct {
+	Message string `json:"message"`
 }
 
-// HandleExecution handles logging information
-func (t *LogInfoTaskDef) HandleExecution(ctx context.Context, db *gorm.DB, task models.ScheduledTask) (map[string]interface{}, error) {
-	message, ok := task.Arguments["message"].(string)
-	if !ok {
+// LogInfoTask logs a message, useful to check that the worker is running
+var LogInfoTask = Define("log_info", handleLogInfo, TaskOptions[LogInfoArgs]{})
+
+// handleLogInfo handles logging information
+func handleLogInfo(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args LogInfoArgs) (map[string]interface{}, error) {
+	message := args.Message
+	if message == "" {
 		message = "No message provided"
 	}
 	log.Printf("[Task: log_info] Message: %s", message)
@@ -32,6 +32,3 @@ func (t *LogInfoTaskDef) HandleExecution(ctx context.Context, db *gorm.DB, task
 		"max_attempts_info": maxAttempt,
 	}, nil
 }
-
-// LogInfoTask is the singleton instance of LogInfoTaskDef
-var LogInfoTask = &LogInfoTaskDef{}
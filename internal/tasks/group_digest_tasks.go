@@ -0,0 +1,253 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+)
+
+// SendGroupDigestArgs defines the arguments for posting a group digest
+type SendGroupDigestArgs struct {
+	DigestID uint `json:"digest_id"`
+}
+
+// Validate checks that the digest to post is set
+func (a SendGroupDigestArgs) Validate() error {
+	if a.DigestID == 0 {
+		return fmt.Errorf("digest_id is required")
+	}
+	return nil
+}
+
+// SendGroupDigestTask posts the payment status of one cycle of a plan to its WhatsApp group
+var SendGroupDigestTask = Define("send_group_digest", handleSendGroupDigest, TaskOptions[SendGroupDigestArgs]{
+	Timeout: 2 * time.Minute,
+})
+
+// handleSendGroupDigest composes the digest from the current state of the dues and posts it
+func handleSendGroupDigest(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args SendGroupDigestArgs) (map[string]interface{}, error) {
+	var digest models.PlanGroupDigest
+	if err := db.Preload("Plan").First(&digest, args.DigestID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch group digest %d: %w", args.DigestID, err)
+	}
+	if digest.SentAt != nil {
+		return map[string]interface{}{"status": "already_sent"}, nil
+	}
+
+	var dues []models.PaymentDue
+	if err := db.Preload("User").
+		Where("plan_id = ? AND due_date = ? AND payment_status <> ?", digest.PlanID, digest.DueDate, models.PaymentStatusCanceled).
+		Order("id").
+		Find(&dues).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch dues of plan %d: %w", digest.PlanID, err)
+	}
+	if len(dues) == 0 {
+		return map[string]interface{}{"status": "no_dues"}, nil
+	}
+	for i := range dues {
+		dues[i].Plan = digest.Plan
+	}
+
+	deps, err := depsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	message := formatGroupDigest(digest.Plan, dues)
+	messageID, err := deps.Notifiers.Send(ctx, models.NotificationChannelWhatsapp, digest.GroupID, services.NotifierMessage{Body: message})
+	var limited *services.RateLimitError
+	if errors.As(err, &limited) {
+		// Post it once the rate limit allows, the digest is composed again then
+		deferred, err := deferTask(db, task, args, time.Now().Add(limited.RetryAfter))
+		if err != nil {
+			return nil, err
+		}
+		if err := db.Model(&digest).Update("scheduled_task_id", deferred.ID).Error; err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"status": "deferred", "retry_after": limited.RetryAfter.String()}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to post digest of plan %d to %s: %w", digest.PlanID, digest.GroupID, err)
+	}
+
+	now := time.Now()
+	if err := db.Model(&digest).Updates(map[string]interface{}{
+		"sent_at":             now,
+		"message":             message,
+		"provider_message_id": messageID,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"status":     "sent",
+		"group_id":   digest.GroupID,
+		"message_id": messageID,
+	}, nil
+}
+
+// formatGroupDigest writes the one line status of a cycle, e.g. "Netflix Maret: 3/5 lunas, belum: Andi, Sari"
+func formatGroupDigest(plan models.Plan, dues []models.PaymentDue) string {
+	cycle := dues[0].LocalDueDate()
+	if dues[0].CycleDate != nil {
+		cycle = *dues[0].CycleDate
+	}
+
+	paid := 0
+	var unpaid []string
+	for _, due := range dues {
+		if due.PaymentStatus == models.PaymentStatusPaid {
+			paid++
+			continue
+		}
+		unpaid = append(unpaid, due.User.Name)
+	}
+
+	message := fmt.Sprintf("%s %s: %d/%d lunas", plan.Name, models.IndonesianMonth(cycle), paid, len(dues))
+	if len(unpaid) > 0 {
+		message += ", belum: " + strings.Join(unpaid, ", ")
+	}
+	return message
+}
+
+// enqueueGroupDigest records a digest of the dues of a plan due on dueDate and enqueues its post
+func enqueueGroupDigest(tx *gorm.DB, plan models.Plan, dueDate time.Time) error {
+	digest := models.PlanGroupDigest{
+		PlanID:  plan.ID,
+		DueDate: dueDate,
+		GroupID: plan.GroupDigest.ChatID(),
+	}
+	if err := tx.Create(&digest).Error; err != nil {
+		return fmt.Errorf("failed to record group digest of plan %d: %w", plan.ID, err)
+	}
+
+	digestTask, err := SendGroupDigestTask.Enqueue(tx, SendGroupDigestArgs{DigestID: digest.ID}, time.Now(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue group digest of plan %d: %w", plan.ID, err)
+	}
+	return tx.Model(&digest).Update("scheduled_task_id", digestTask.ID).Error
+}
+
+// SendGroupDigestsArgs defines the arguments for the scheduled digest task, it takes none
+type SendGroupDigestsArgs struct{}
+
+// SendGroupDigestsInterval is how often plans are checked for a repeated group digest
+const SendGroupDigestsInterval = "FREQ=HOURLY"
+
+// SendGroupDigestsTask repeats the group digest of cycles that are not fully paid, every
+// GroupDigest.IntervalDays of their plan
+var SendGroupDigestsTask = Define("send_group_digests", handleSendGroupDigests, TaskOptions[SendGroupDigestsArgs]{
+	Timeout: 10 * time.Minute,
+})
+
+// handleSendGroupDigests enqueues a digest for every open cycle whose last digest is older than the plan interval
+func handleSendGroupDigests(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args SendGroupDigestsArgs) (map[string]interface{}, error) {
+	var plans []models.Plan
+	if err := db.Where("group_digest_group_id <> '' AND group_digest_interval_days > 0").Find(&plans).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	enqueued := 0
+	for _, plan := range plans {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var dueDates []time.Time
+		if err := db.Model(&models.PaymentDue{}).
+			Where("plan_id = ? AND payment_status IN ?", plan.ID, []string{models.PaymentStatusPending, models.PaymentStatusOverdue}).
+			Distinct().
+			Pluck("due_date", &dueDates).Error; err != nil {
+			return nil, err
+		}
+
+		for _, dueDate := range dueDates {
+			var last models.PlanGroupDigest
+			err := db.Where("plan_id = ? AND due_date = ?", plan.ID, dueDate).Order("created_at DESC").First(&last).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if err == nil && now.Sub(last.CreatedAt) < time.Duration(plan.GroupDigest.IntervalDays)*24*time.Hour {
+				continue
+			}
+
+			if err := db.Transaction(func(tx *gorm.DB) error {
+				return enqueueGroupDigest(tx, plan, dueDate)
+			}); err != nil {
+				return nil, err
+			}
+			enqueued++
+		}
+	}
+
+	if enqueued > 0 {
+		log.Printf("[Task SendGroupDigests] Enqueued %d group digests", enqueued)
+	}
+
+	return map[string]interface{}{
+		"status":   "success",
+		"plans":    len(plans),
+		"enqueued": enqueued,
+	}, nil
+}
+
+// groupDuplicateWindow is how far back a message to the same group counts as a duplicate. The
+// reminders of all members are sent within minutes, and later reminder steps are days apart.
+const groupDuplicateWindow = 12 * time.Hour
+
+// groupDuplicateReason tells why a delivery to a WhatsApp group should not be sent, empty when it
+// should. The group of a plan with a digest gets the digest instead of a message per member, and
+// other groups get one message per event and cycle.
+func groupDuplicateReason(db *gorm.DB, delivery models.NotificationDelivery) (string, error) {
+	if delivery.Channel != models.NotificationChannelWhatsapp || !strings.HasSuffix(delivery.Recipient, "@g.us") {
+		return "", nil
+	}
+
+	if delivery.PaymentDueID == nil {
+		// Without a due, only the members notified by the same task are known to get the same message
+		var count int64
+		if err := db.Model(&models.NotificationDelivery{}).
+			Where("scheduled_task_id = ? AND recipient = ? AND status IN ?", delivery.ScheduledTaskID, delivery.Recipient,
+				[]models.NotificationDeliveryStatus{models.NotificationDeliveryStatusPending, models.NotificationDeliveryStatusSent}).
+			Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count > 0 {
+			return "already sent to this group", nil
+		}
+		return "", nil
+	}
+
+	var due models.PaymentDue
+	if err := db.Preload("Plan").Select("id", "plan_id", "due_date").First(&due, *delivery.PaymentDueID).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch payment due %d: %w", *delivery.PaymentDueID, err)
+	}
+	if due.Plan.GroupDigest.Enabled() && due.Plan.GroupDigest.ChatID() == delivery.Recipient {
+		return "the group gets the plan digest", nil
+	}
+
+	var count int64
+	if err := db.Model(&models.NotificationDelivery{}).
+		Joins("JOIN payment_dues ON payment_dues.id = notification_deliveries.payment_due_id").
+		Where("notification_deliveries.recipient = ? AND notification_deliveries.event = ? AND notification_deliveries.status IN ?",
+			delivery.Recipient, delivery.Event,
+			[]models.NotificationDeliveryStatus{models.NotificationDeliveryStatusPending, models.NotificationDeliveryStatusSent}).
+		Where("payment_dues.plan_id = ? AND payment_dues.due_date = ?", due.PlanID, due.DueDate).
+		Where("notification_deliveries.created_at > ?", time.Now().Add(-groupDuplicateWindow)).
+		Count(&count).Error; err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return "already sent to this group for another member", nil
+	}
+	return "", nil
+}
@@ -0,0 +1,58 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestFormatGroupDigest(t *testing.T) {
+	plan := models.Plan{Name: "Netflix", Timezone: "Asia/Jakarta"}
+	cycleDate := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	// Due on 1 April in Jakarta, billed for the March cycle
+	dueDate := time.Date(2026, 3, 31, 18, 0, 0, 0, time.UTC)
+
+	due := func(name, status string, cycle *time.Time) models.PaymentDue {
+		return models.PaymentDue{Plan: plan, DueDate: dueDate, CycleDate: cycle, User: models.User{Name: name}, PaymentStatus: status}
+	}
+
+	tests := []struct {
+		name     string
+		dues     []models.PaymentDue
+		expected string
+	}{
+		{
+			name: "some unpaid",
+			dues: []models.PaymentDue{
+				due("Budi", models.PaymentStatusPaid, &cycleDate),
+				due("Andi", models.PaymentStatusPending, &cycleDate),
+				due("Citra", models.PaymentStatusPaid, &cycleDate),
+				due("Sari", models.PaymentStatusOverdue, &cycleDate),
+				due("Dewi", models.PaymentStatusPaid, &cycleDate),
+			},
+			expected: "Netflix Maret: 3/5 lunas, belum: Andi, Sari",
+		},
+		{
+			name: "all paid",
+			dues: []models.PaymentDue{
+				due("Budi", models.PaymentStatusPaid, &cycleDate),
+				due("Andi", models.PaymentStatusPaid, &cycleDate),
+			},
+			expected: "Netflix Maret: 2/2 lunas",
+		},
+		{
+			name:     "due without cycle date uses the local due date",
+			dues:     []models.PaymentDue{due("Budi", models.PaymentStatusPending, nil)},
+			expected: "Netflix April: 0/1 lunas, belum: Budi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatGroupDigest(plan, tt.dues); got != tt.expected {
+				t.Errorf("formatGroupDigest() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
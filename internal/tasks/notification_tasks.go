@@ -2,13 +2,16 @@ package tasks
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
 
+	"patungan_app_echo/internal/metrics"
 	"patungan_app_echo/internal/models"
 	"patungan_app_echo/internal/services"
 	"time"
@@ -16,202 +19,530 @@ import (
 
 // NotificationUser represents the user in the notification payload
 type NotificationUser struct {
-	UserID      interface{} `json:"userId"` // Can be string or int
-	Username    string      `json:"username"`
-	Email       string      `json:"email"`
-	PhoneNumber string      `json:"phonenumber"`
-	PaymentLink string      `json:"payment_link"`
+	UserID       interface{}  `json:"userId"` // Can be string or int
+	Username     string       `json:"username"`
+	Email        string       `json:"email"`
+	PhoneNumber  string       `json:"phonenumber"`
+	PaymentLink  string       `json:"payment_link"`
+	Amount       models.Money `json:"amount,omitempty"`         // Amount billed to this user, overrides the task amount
+	PaymentDueID uint         `json:"payment_due_id,omitempty"` // Not sent once this due is paid or canceled
 }
 
 // SendNotificationArgs defines the arguments for a notification task
 type SendNotificationArgs struct {
-	Users         []NotificationUser `json:"users"`
-	NotifTemplate string             `json:"notiftemplate"`
-	Subject       string             `json:"subject"`
-	PlanName      string             `json:"plan_name"`
-	Amount        float64            `json:"amount"`
-	DueDate       string             `json:"due_date"`
-	AttemptCount  int                `json:"attempt_count"`
+	Users         []NotificationUser       `json:"users"`
+	Event         models.NotificationEvent `json:"event,omitempty"`         // Picks the message template of each channel
+	NotifTemplate string                   `json:"notiftemplate,omitempty"` // Ad-hoc message with $placeholders, used when Event is empty
+	Subject       string                   `json:"subject,omitempty"`
+	PlanName      string                   `json:"plan_name"`
+	Amount        models.Money             `json:"amount"`
+	DueDate       string                   `json:"due_date"`
 }
 
-// SendNotificationTaskDef encapsulates the notification task logic
-type SendNotificationTaskDef struct{}
-
-// TaskID returns the unique identifier for this task
-func (t *SendNotificationTaskDef) TaskID() string {
-	return "send_notification"
+// Validate checks that there is someone to notify and a message to send
+func (a SendNotificationArgs) Validate() error {
+	if len(a.Users) == 0 {
+		return fmt.Errorf("users is required")
+	}
+	if a.Event != "" {
+		if !a.Event.IsValid() {
+			return fmt.Errorf("unknown event %q", a.Event)
+		}
+		return nil
+	}
+	if a.NotifTemplate == "" {
+		return fmt.Errorf("event or notiftemplate is required")
+	}
+	return nil
 }
 
-// CreateTask builds a ScheduledTask record for this task
-func (t *SendNotificationTaskDef) CreateTask(args SendNotificationArgs) (*models.ScheduledTask, error) {
-	return BuildScheduledTask(t.TaskID(), args, time.Now(), nil, models.ScheduledTaskTypeOneTime, 3)
-}
+// SendNotificationTask records a delivery for each user and sends it through their preferred channel.
+// Sending to every user in turn takes longer than the default timeout.
+var SendNotificationTask = Define("send_notification", handleSendNotification, TaskOptions[SendNotificationArgs]{
+	Timeout: sendNotificationTimeout,
+})
 
-// HandleExecution handles sending notifications based on user preference
-func (t *SendNotificationTaskDef) HandleExecution(ctx context.Context, db *gorm.DB, task models.ScheduledTask) (map[string]interface{}, error) {
-	argsBytes, err := json.Marshal(task.Arguments)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal args: %w", err)
-	}
+// sendNotificationTimeout bounds a send_notification run. A new delivery is not retried before it
+// has passed, so a delivery whose first attempt was cut short by a worker crash is still sent.
+const sendNotificationTimeout = 10 * time.Minute
 
-	var parsedArgs SendNotificationArgs
-	if err := json.Unmarshal(argsBytes, &parsedArgs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal args: %w", err)
+// handleSendNotification records a delivery for each user and makes its first attempt.
+// Failed deliveries are retried by RetryNotificationDeliveriesTask.
+func handleSendNotification(ctx context.Context, db *gorm.DB, task models.ScheduledTask, parsedArgs SendNotificationArgs) (map[string]interface{}, error) {
+	deps, err := depsFrom(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	total := len(parsedArgs.Users)
 	successCount := 0
 	skippedCount := 0
+	deferredCount := 0
 	failureCount := 0
 	var failures []string
-	var failedUsers []NotificationUser
 
 	for _, user := range parsedArgs.Users {
-		// Fetch preference
-		var pref models.UserNotifPreference
-		err := db.Where("user_id = ?", user.UserID).First(&pref).Error
+		// Stop sending once the task deadline has passed, the worker records the run as timed out
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("notification sending interrupted after %d of %d users: %w", successCount+skippedCount+deferredCount+failureCount, total, err)
+		}
+
+		userID := notificationUserID(user.UserID)
+
+		// A retried run does not notify users that an earlier run already handled. Failed deliveries
+		// are built again, so that requeueing the task, e.g. with corrected arguments, sends them.
+		var handled int64
+		query := db.Model(&models.NotificationDelivery{}).
+			Where("scheduled_task_id = ? AND user_id = ? AND status <> ?", task.ID, userID, models.NotificationDeliveryStatusFailed)
+		if user.PaymentDueID != 0 {
+			query = query.Where("payment_due_id = ?", user.PaymentDueID)
+		}
+		if err := query.Count(&handled).Error; err != nil {
+			return nil, fmt.Errorf("failed to check deliveries of %s: %w", user.Username, err)
+		}
+		if handled > 0 {
+			continue
+		}
+
+		delivery, err := buildDelivery(deps.Notifiers, db, task, userID, user, parsedArgs)
 		if err != nil {
-			if err == gorm.ErrRecordNotFound {
-				// Skip if no preference found
-				log.Printf("Skipping notification for %s: no preference found", user.Username)
-				skippedCount++
-				continue
-			}
-			// Log error but continue? or mark as fail
-			log.Printf("Error fetching preference for %s: %v", user.Username, err)
+			log.Printf("Error preparing notification for %s: %v", user.Username, err)
+			metrics.ObserveNotification("", metrics.DeliveryOutcomeFailure)
 			failureCount++
-			failures = append(failures, fmt.Sprintf("%s: db error", user.Username))
-			failedUsers = append(failedUsers, user)
+			failures = append(failures, fmt.Sprintf("%s: %v", user.Username, err))
 			continue
 		}
+		if err := db.Create(&delivery).Error; err != nil {
+			return nil, fmt.Errorf("failed to record delivery for %s: %w", user.Username, err)
+		}
 
-		var sendErr error
-		if pref.Channel == models.NotificationChannelEmail {
-			sendErr = sendEmailNotif(user, parsedArgs)
-		} else if pref.Channel == models.NotificationChannelWhatsapp {
-			sendErr = sendWhatsappNotif(user, parsedArgs, pref)
-		} else if pref.Channel == models.NotificationChannelNone {
-			// Explicitly disabled, skip
-			log.Printf("Notification disabled (none) for %s", user.Username)
+		switch delivery.Status {
+		case models.NotificationDeliveryStatusSkipped:
+			log.Printf("Skipping notification for %s: %s", user.Username, delivery.LastError)
+			metrics.ObserveNotification(delivery.Channel, metrics.DeliveryOutcomeSkipped)
 			skippedCount++
 			continue
-		} else {
-			// Unknown channel, skip
-			log.Printf("Unsupported notification channel %s for %s", pref.Channel, user.Username)
-			skippedCount++
+		case models.NotificationDeliveryStatusFailed:
+			log.Printf("Cannot notify %s via %s: %s", user.Username, delivery.Channel, delivery.LastError)
+			metrics.ObserveNotification(delivery.Channel, metrics.DeliveryOutcomeFailure)
+			failureCount++
+			failures = append(failures, fmt.Sprintf("%s: %s", user.Username, delivery.LastError))
 			continue
 		}
 
-		if sendErr != nil {
-			log.Printf("Failed to send notification to %s via %s: %v", user.Username, pref.Channel, sendErr)
+		if err := attemptDelivery(ctx, db, deps, &delivery); isRateLimited(err) {
+			log.Printf("Deferring notification to %s via %s: %v", user.Username, delivery.Channel, err)
+			deferredCount++
+		} else if errors.Is(err, errDeliveryNotRecorded) {
+			return nil, err
+		} else if err != nil {
+			log.Printf("Failed to send notification to %s via %s: %v", user.Username, delivery.Channel, err)
 			failureCount++
-			failures = append(failures, fmt.Sprintf("%s: %v", user.Username, sendErr))
-			failedUsers = append(failedUsers, user)
+			failures = append(failures, fmt.Sprintf("%s: %v", user.Username, err))
 		} else {
 			successCount++
 		}
 	}
 
 	result := map[string]interface{}{
-		"total":   total,
-		"success": successCount,
-		"skipped": skippedCount,
-		"failure": failureCount,
+		"total":    total,
+		"success":  successCount,
+		"skipped":  skippedCount,
+		"deferred": deferredCount,
+		"failure":  failureCount,
 	}
-
 	if failureCount > 0 {
 		result["errors"] = failures
+	}
 
-		attempt := parsedArgs.AttemptCount
-		maxRetries := task.MaxAttempt
+	return result, nil
+}
+
+// RetryNotificationDeliveriesArgs defines the arguments for the delivery retry task, it takes none
+type RetryNotificationDeliveriesArgs struct{}
 
-		if attempt < maxRetries {
-			log.Printf("Partial failure: %d users failed. Rescheduling for Attempt %d", len(failedUsers), attempt+1)
+// RetryNotificationDeliveriesInterval is how often failed deliveries are retried
+const RetryNotificationDeliveriesInterval = "FREQ=MINUTELY;INTERVAL=5"
 
-			newArgs := parsedArgs
-			newArgs.Users = failedUsers
-			newArgs.AttemptCount = attempt + 1
+// retryDeliveriesBatchSize bounds how many deliveries a single retry run sends
+const retryDeliveriesBatchSize = 100
 
-			// Re-schedule in 5 minutes
-			nextRun := time.Now().Add(5 * time.Minute)
+// RetryNotificationDeliveriesTask sends the pending deliveries whose next attempt is due
+var RetryNotificationDeliveriesTask = Define("retry_notification_deliveries", handleRetryNotificationDeliveries, TaskOptions[RetryNotificationDeliveriesArgs]{
+	Timeout: 10 * time.Minute,
+})
 
-			newTask, err := BuildScheduledTask(t.TaskID(), newArgs, nextRun, nil, models.ScheduledTaskTypeOneTime, maxRetries)
-			if err == nil {
-				db.Create(newTask)
-			} else {
-				log.Printf("Failed to create retry task: %v", err)
+// handleRetryNotificationDeliveries makes the next attempt of every delivery that is due for a retry
+func handleRetryNotificationDeliveries(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args RetryNotificationDeliveriesArgs) (map[string]interface{}, error) {
+	deps, err := depsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []models.NotificationDelivery
+	if err := db.Where("status = ? AND next_attempt_at <= ?", models.NotificationDeliveryStatusPending, time.Now()).
+		Order("next_attempt_at").
+		Limit(retryDeliveriesBatchSize).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	successCount := 0
+	skippedCount := 0
+	deferredCount := 0
+	failureCount := 0
+	for i := range deliveries {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("delivery retries interrupted after %d of %d deliveries: %w", successCount+skippedCount+deferredCount+failureCount, len(deliveries), err)
+		}
+		delivery := &deliveries[i]
+
+		if delivery.PaymentDueID != nil {
+			if status, settled := settledDueStatus(db, *delivery.PaymentDueID); settled {
+				delivery.Status = models.NotificationDeliveryStatusSkipped
+				delivery.NextAttemptAt = nil
+				delivery.LastError = fmt.Sprintf("payment due is %s", status)
+				if err := db.Save(delivery).Error; err != nil {
+					return nil, err
+				}
+				metrics.ObserveNotification(delivery.Channel, metrics.DeliveryOutcomeSkipped)
+				skippedCount++
+				continue
 			}
+		}
+
+		if err := attemptDelivery(ctx, db, deps, delivery); isRateLimited(err) {
+			deferredCount++
+		} else if errors.Is(err, errDeliveryNotRecorded) {
+			return nil, err
+		} else if err != nil {
+			log.Printf("Retry %d of delivery %d via %s failed: %v", delivery.Attempts, delivery.ID, delivery.Channel, err)
+			failureCount++
 		} else {
-			log.Printf("Max attempts (%d) reached for %d failed users.", maxRetries, len(failedUsers))
-			return result, fmt.Errorf("max attempts reached, failed to deliver to %d users", len(failedUsers))
+			successCount++
 		}
 	}
 
-	return result, nil
+	return map[string]interface{}{
+		"total":    len(deliveries),
+		"success":  successCount,
+		"skipped":  skippedCount,
+		"deferred": deferredCount,
+		"failure":  failureCount,
+	}, nil
 }
 
-// SendNotificationTask is the singleton instance of SendNotificationTaskDef
-var SendNotificationTask = &SendNotificationTaskDef{}
-
-// sendWhatsappNotif handles sending WhatsApp notifications
-func sendWhatsappNotif(user NotificationUser, args SendNotificationArgs, pref models.UserNotifPreference) error {
-	notifTemplate := args.NotifTemplate
-	if notifTemplate == "" {
-		return fmt.Errorf("notiftemplate is missing")
+// buildDelivery prepares the delivery of a user: the channel from their preference, the recipient
+// and the rendered message. Deliveries that cannot be sent come back skipped or failed with the reason in LastError.
+func buildDelivery(notifiers *services.NotifierRegistry, db *gorm.DB, task models.ScheduledTask, userID uint, user NotificationUser, args SendNotificationArgs) (models.NotificationDelivery, error) {
+	taskID := task.ID
+	maxAttempts := task.MaxAttempt
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempt
+	}
+	// Leave the first attempt to this run, the retry task takes over if it never happens
+	claimedUntil := time.Now().Add(sendNotificationTimeout)
+
+	delivery := models.NotificationDelivery{
+		UserID:          userID,
+		ScheduledTaskID: &taskID,
+		Event:           args.Event,
+		Status:          models.NotificationDeliveryStatusPending,
+		MaxAttempts:     maxAttempts,
+		NextAttemptAt:   &claimedUntil,
+	}
+	if user.PaymentDueID != 0 {
+		dueID := user.PaymentDueID
+		delivery.PaymentDueID = &dueID
 	}
 
-	wahaService := services.NewWahaService()
+	skip := func(reason string) (models.NotificationDelivery, error) {
+		delivery.Status = models.NotificationDeliveryStatusSkipped
+		delivery.NextAttemptAt = nil
+		delivery.LastError = reason
+		return delivery, nil
+	}
+	fail := func(err error) (models.NotificationDelivery, error) {
+		delivery.Status = models.NotificationDeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+		return delivery, nil
+	}
 
-	msg := replacePlaceholders(notifTemplate, user, args)
+	// Bills and reminders are pointless once the due is settled
+	if status, settled := settledDueStatus(db, user.PaymentDueID); settled {
+		return skip(fmt.Sprintf("payment due is %s", status))
+	}
 
-	var chatId string
-	if pref.WhatsappTargetType == models.WhatsappTargetTypeGroup {
-		chatId = pref.WhatsappGroupID
-		if chatId == "" {
-			return fmt.Errorf("group ID is empty")
+	var pref models.UserNotifPreference
+	if err := db.Where("user_id = ?", userID).First(&pref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return skip("no notification preference")
 		}
-		if !strings.HasSuffix(chatId, "@g.us") {
-			chatId = chatId + "@g.us"
+		return delivery, fmt.Errorf("failed to fetch preference: %w", err)
+	}
+
+	if pref.Channel == models.NotificationChannelNone || pref.Channel == "" {
+		return skip("notifications are turned off")
+	}
+	delivery.Channel = pref.Channel
+	notifier, ok := notifiers.Get(pref.Channel)
+	if !ok {
+		return skip(fmt.Sprintf("unsupported channel %s", pref.Channel))
+	}
+	caps := notifier.Capabilities()
+
+	recipient, err := deliveryRecipient(user, pref)
+	if err != nil {
+		return fail(err)
+	}
+	if recipient == "" {
+		return fail(fmt.Errorf("no %s recipient", pref.Channel))
+	}
+	delivery.Recipient = recipient
+
+	reason, err := groupDuplicateReason(db, delivery)
+	if err != nil {
+		return delivery, err
+	}
+	if reason != "" {
+		return skip(reason)
+	}
+
+	subject, message, err := composeMessage(db, pref.Channel, user, args)
+	if err != nil {
+		return fail(err)
+	}
+	if caps.Subject && subject == "" {
+		subject = "Notification"
+	}
+	// Event templates of HTML channels are HTML, ad-hoc messages are plain text
+	delivery.IsHTML = caps.HTML && args.Event != ""
+	delivery.Subject = subject
+	delivery.Message = message
+
+	return delivery, nil
+}
+
+// attemptDelivery sends a pending delivery once and records the attempt. A failed delivery
+// is retried with backoff until it runs out of attempts. A delivery over the rate limit of its
+// channel is deferred instead, without using up an attempt.
+func attemptDelivery(ctx context.Context, db *gorm.DB, deps Deps, delivery *models.NotificationDelivery) error {
+	messageID, sendErr := dispatchDelivery(ctx, deps.Notifiers, *delivery)
+
+	var limited *services.RateLimitError
+	if errors.As(sendErr, &limited) {
+		nextAttempt := time.Now().Add(limited.RetryAfter)
+		delivery.NextAttemptAt = &nextAttempt
+		delivery.LastError = sendErr.Error()
+		if err := db.Save(delivery).Error; err != nil {
+			log.Printf("Failed to defer delivery %d: %v", delivery.ID, err)
 		}
+		metrics.ObserveNotification(delivery.Channel, metrics.DeliveryOutcomeDeferred)
+		return sendErr
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	attempt := models.NotificationDeliveryAttempt{
+		DeliveryID: delivery.ID,
+		Attempt:    delivery.Attempts,
+	}
+
+	if sendErr == nil {
+		delivery.Status = models.NotificationDeliveryStatusSent
+		delivery.SentAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.ProviderMessageID = messageID
+		delivery.LastError = ""
+		attempt.ProviderMessageID = messageID
+		metrics.ObserveNotification(delivery.Channel, metrics.DeliveryOutcomeSuccess)
 	} else {
-		// Personal
-		chatId = user.PhoneNumber
+		delivery.LastError = sendErr.Error()
+		attempt.Error = sendErr.Error()
+		if delivery.Attempts < delivery.MaxAttempts {
+			nextAttempt := now.Add(deps.RetryPolicy.Delay(delivery.Attempts))
+			delivery.NextAttemptAt = &nextAttempt
+		} else {
+			delivery.Status = models.NotificationDeliveryStatusFailed
+			delivery.NextAttemptAt = nil
+		}
+		metrics.ObserveNotification(delivery.Channel, metrics.DeliveryOutcomeFailure)
+	}
+	attempt.Status = delivery.Status
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(delivery).Error; err != nil {
+			return err
+		}
+		return tx.Create(&attempt).Error
+	})
+	if err != nil && sendErr == nil {
+		// Left pending, the delivery would be sent again by the retry task. Record at least that it
+		// was sent, without the attempt log.
+		log.Printf("Failed to record attempt %d of delivery %d: %v", attempt.Attempt, delivery.ID, err)
+		err = db.Model(delivery).Select("status", "attempts", "sent_at", "next_attempt_at", "provider_message_id", "last_error").Updates(delivery).Error
+		if err != nil {
+			return fmt.Errorf("%w: delivery %d was sent as %q: %v", errDeliveryNotRecorded, delivery.ID, messageID, err)
+		}
+	} else if err != nil {
+		log.Printf("Failed to record attempt %d of delivery %d: %v", attempt.Attempt, delivery.ID, err)
+	}
+
+	return sendErr
+}
+
+// errDeliveryNotRecorded is returned when a delivery was sent but storing that failed. The run
+// stops, sending more would not be recorded either.
+var errDeliveryNotRecorded = errors.New("sent delivery could not be recorded")
+
+// isRateLimited reports whether a send was deferred because the channel is over its rate limit
+func isRateLimited(err error) bool {
+	var limited *services.RateLimitError
+	return errors.As(err, &limited)
+}
+
+// dispatchDelivery sends the message of a delivery through the notifier of its channel and returns the provider message ID
+func dispatchDelivery(ctx context.Context, notifiers *services.NotifierRegistry, delivery models.NotificationDelivery) (string, error) {
+	return notifiers.Send(ctx, delivery.Channel, delivery.Recipient, services.NotifierMessage{
+		Subject: delivery.Subject,
+		Body:    delivery.Message,
+		IsHTML:  delivery.IsHTML,
+	})
+}
+
+// deliveryRecipient returns where a user's notifications go on their preferred channel
+func deliveryRecipient(user NotificationUser, pref models.UserNotifPreference) (string, error) {
+	switch pref.Channel {
+	case models.NotificationChannelEmail:
+		return user.Email, nil
+	case models.NotificationChannelWhatsapp:
+		return whatsappChatID(user, pref)
+	case models.NotificationChannelTelegram:
+		return strings.TrimSpace(pref.TelegramChatID), nil
+	}
+	return "", fmt.Errorf("unsupported channel %s", pref.Channel)
+}
+
+// whatsappChatID returns the chat a WhatsApp notification goes to, the user's group or their own number
+func whatsappChatID(user NotificationUser, pref models.UserNotifPreference) (string, error) {
+	if pref.WhatsappTargetType != models.WhatsappTargetTypeGroup {
+		return user.PhoneNumber, nil
 	}
 
-	return wahaService.SendMessage(chatId, msg)
+	chatId := pref.WhatsappGroupID
+	if chatId == "" {
+		return "", fmt.Errorf("group ID is empty")
+	}
+	if !strings.HasSuffix(chatId, "@g.us") {
+		chatId = chatId + "@g.us"
+	}
+	return chatId, nil
 }
 
-// sendEmailNotif handles sending Email notifications
-func sendEmailNotif(user NotificationUser, args SendNotificationArgs) error {
-	notifTemplate := args.NotifTemplate
-	if notifTemplate == "" {
-		return fmt.Errorf("notiftemplate is missing")
+// settledDueStatus reports whether a payment due is paid or canceled, together with its status
+func settledDueStatus(db *gorm.DB, paymentDueID uint) (string, bool) {
+	if paymentDueID == 0 {
+		return "", false
+	}
+	var due models.PaymentDue
+	if err := db.Select("id", "payment_status").First(&due, paymentDueID).Error; err != nil {
+		return "", false
 	}
+	settled := due.PaymentStatus == models.PaymentStatusPaid || due.PaymentStatus == models.PaymentStatusCanceled
+	return due.PaymentStatus, settled
+}
 
-	emailService := services.NewEmailService()
+// notificationUserID reads the loosely typed NotificationUser.UserID, 0 when it is not a user ID
+func notificationUserID(id interface{}) uint {
+	switch v := id.(type) {
+	case uint:
+		return v
+	case int:
+		if v > 0 {
+			return uint(v)
+		}
+	case float64:
+		if v > 0 {
+			return uint(v)
+		}
+	case string:
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return uint(n)
+		}
+	}
+	return 0
+}
 
-	// Simple subject extraction or default
-	subject := "Notification"
-	if args.Subject != "" {
-		subject = args.Subject
+// composeMessage returns the subject and body sent to a user, rendered from the event template
+// of the channel, or from the ad-hoc NotifTemplate when the task has no event
+func composeMessage(db *gorm.DB, channel models.NotificationChannel, user NotificationUser, args SendNotificationArgs) (string, string, error) {
+	if args.Event == "" {
+		if args.NotifTemplate == "" {
+			return "", "", fmt.Errorf("notiftemplate is missing")
+		}
+		return args.Subject, replacePlaceholders(args.NotifTemplate, user, args), nil
 	}
 
-	msg := replacePlaceholders(notifTemplate, user, args)
+	tmpl := loadNotificationTemplate(db, args.Event, channel)
+	subject, body, err := tmpl.Render(notificationData(user, args))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to render %s template for %s: %w", args.Event, channel, err)
+	}
+	return subject, body, nil
+}
+
+// loadNotificationTemplate returns the stored template of an event and channel, or the built-in default
+func loadNotificationTemplate(db *gorm.DB, event models.NotificationEvent, channel models.NotificationChannel) models.NotificationTemplate {
+	var tmpl models.NotificationTemplate
+	err := db.Where("event = ? AND channel = ?", event, channel).First(&tmpl).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load %s template for %s, using the default: %v", event, channel, err)
+		}
+		return models.DefaultNotificationTemplate(event, channel)
+	}
+	return tmpl
+}
 
-	return emailService.SendEmail([]string{user.Email}, subject, msg)
+// notificationData builds the template data of a user
+func notificationData(user NotificationUser, args SendNotificationArgs) models.NotificationData {
+	amount := args.Amount
+	if user.Amount != 0 {
+		amount = user.Amount
+	}
+	return models.NotificationData{
+		Name:        user.Username,
+		Email:       user.Email,
+		PlanName:    args.PlanName,
+		Amount:      amount,
+		DueDate:     args.DueDate,
+		PaymentLink: user.PaymentLink,
+	}
 }
 
+// placeholderPattern matches a whole $placeholder, so $name does not match the start of $name_xyz
+var placeholderPattern = regexp.MustCompile(`\$[a-z_]+`)
+
+// replacePlaceholders fills the $placeholders of an ad-hoc message, unknown placeholders are left as they are
 func replacePlaceholders(template string, user NotificationUser, args SendNotificationArgs) string {
-	res := strings.ReplaceAll(template, "$name", user.Username)
-	res = strings.ReplaceAll(res, "$username", user.Username)
-	res = strings.ReplaceAll(res, "$email", user.Email)
-
-	res = strings.ReplaceAll(res, "$notiftemplate", args.NotifTemplate)
-	res = strings.ReplaceAll(res, "$subject", args.Subject)
-	res = strings.ReplaceAll(res, "$plan_name", args.PlanName)
-	res = strings.ReplaceAll(res, "$amount", fmt.Sprintf("%v", args.Amount))
-	res = strings.ReplaceAll(res, "$due_date", args.DueDate)
-	res = strings.ReplaceAll(res, "$paymentlink", user.PaymentLink)
-
-	return res
+	data := notificationData(user, args)
+	values := map[string]string{
+		"$name":          user.Username,
+		"$username":      user.Username,
+		"$email":         user.Email,
+		"$notiftemplate": args.NotifTemplate,
+		"$subject":       args.Subject,
+		"$plan_name":     args.PlanName,
+		"$amount":        data.Amount.String(),
+		"$due_date":      args.DueDate,
+		"$paymentlink":   user.PaymentLink,
+	}
+
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		if value, ok := values[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
 }
@@ -0,0 +1,273 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+)
+
+func TestReplacePlaceholders(t *testing.T) {
+	user := NotificationUser{Username: "Budi", Email: "budi@example.com", PaymentLink: "https://example.com/p/abc", Amount: 25000}
+	args := SendNotificationArgs{PlanName: "Netflix", Amount: 100000, DueDate: "05 Jan 2026"}
+
+	tests := []struct {
+		name     string
+		template string
+		expected string
+	}{
+		{
+			name:     "all placeholders",
+			template: "Halo $name, $plan_name Rp $amount jatuh tempo $due_date di $paymentlink",
+			expected: "Halo Budi, Netflix Rp 25000 jatuh tempo 05 Jan 2026 di https://example.com/p/abc",
+		},
+		{
+			name:     "longer placeholder is not clobbered",
+			template: "$name_xyz $username",
+			expected: "$name_xyz Budi",
+		},
+		{
+			name:     "placeholder followed by punctuation",
+			template: "Halo $name!",
+			expected: "Halo Budi!",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replacePlaceholders(tt.template, user, args); got != tt.expected {
+				t.Errorf("replacePlaceholders() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSendNotificationArgsValidate(t *testing.T) {
+	users := []NotificationUser{{Username: "Budi"}}
+
+	tests := []struct {
+		name    string
+		args    SendNotificationArgs
+		wantErr bool
+	}{
+		{name: "event", args: SendNotificationArgs{Users: users, Event: models.NotificationEventReminder}},
+		{name: "ad-hoc message", args: SendNotificationArgs{Users: users, NotifTemplate: "Halo $name"}},
+		{name: "unknown event", args: SendNotificationArgs{Users: users, Event: "birthday"}, wantErr: true},
+		{name: "no message", args: SendNotificationArgs{Users: users}, wantErr: true},
+		{name: "no users", args: SendNotificationArgs{Event: models.NotificationEventReminder}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.args.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNotificationUserID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       interface{}
+		expected uint
+	}{
+		{name: "uint", id: uint(7), expected: 7},
+		{name: "decoded from json", id: float64(12), expected: 12},
+		{name: "numeric string", id: "42", expected: 42},
+		{name: "firebase uid", id: "aBc123", expected: 0},
+		{name: "missing", id: nil, expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notificationUserID(tt.id); got != tt.expected {
+				t.Errorf("notificationUserID(%v) = %d, want %d", tt.id, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWhatsappChatID(t *testing.T) {
+	user := NotificationUser{PhoneNumber: "081246361829"}
+
+	tests := []struct {
+		name     string
+		pref     models.UserNotifPreference
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "personal",
+			pref:     models.UserNotifPreference{WhatsappTargetType: models.WhatsappTargetTypePersonal},
+			expected: "081246361829",
+		},
+		{
+			name:     "group without suffix",
+			pref:     models.UserNotifPreference{WhatsappTargetType: models.WhatsappTargetTypeGroup, WhatsappGroupID: "120363407813232111"},
+			expected: "120363407813232111@g.us",
+		},
+		{
+			name:    "group without id",
+			pref:    models.UserNotifPreference{WhatsappTargetType: models.WhatsappTargetTypeGroup},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := whatsappChatID(user, tt.pref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("whatsappChatID() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("whatsappChatID() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDeliveryRecipient(t *testing.T) {
+	user := NotificationUser{Email: "budi@example.com", PhoneNumber: "081246361829"}
+
+	tests := []struct {
+		name     string
+		pref     models.UserNotifPreference
+		expected string
+		wantErr  bool
+	}{
+		{name: "email", pref: models.UserNotifPreference{Channel: models.NotificationChannelEmail}, expected: "budi@example.com"},
+		{name: "whatsapp", pref: models.UserNotifPreference{Channel: models.NotificationChannelWhatsapp}, expected: "081246361829"},
+		{name: "telegram", pref: models.UserNotifPreference{Channel: models.NotificationChannelTelegram, TelegramChatID: " 987654321 "}, expected: "987654321"},
+		{name: "unknown channel", pref: models.UserNotifPreference{Channel: "sms"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := deliveryRecipient(user, tt.pref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("deliveryRecipient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("deliveryRecipient() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+// fakeNotifier records the messages it is asked to send
+type fakeNotifier struct {
+	caps      services.NotifierCapabilities
+	err       error
+	recipient string
+	msg       services.NotifierMessage
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, recipient string, msg services.NotifierMessage) (string, error) {
+	n.recipient = recipient
+	n.msg = msg
+	if n.err != nil {
+		return "", n.err
+	}
+	return "msg-1", nil
+}
+
+func (n *fakeNotifier) Capabilities() services.NotifierCapabilities {
+	return n.caps
+}
+
+func TestDispatchDelivery(t *testing.T) {
+	email := &fakeNotifier{caps: services.NotifierCapabilities{Subject: true, HTML: true}}
+	telegram := &fakeNotifier{err: errors.New("chat not found")}
+	registry := services.NewNotifierRegistry()
+	registry.Register(models.NotificationChannelEmail, email)
+	registry.Register(models.NotificationChannelTelegram, telegram)
+
+	tests := []struct {
+		name     string
+		delivery models.NotificationDelivery
+		notifier *fakeNotifier
+		wantID   string
+		wantErr  bool
+	}{
+		{
+			name:     "sent through the channel notifier",
+			delivery: models.NotificationDelivery{Channel: models.NotificationChannelEmail, Recipient: "budi@example.com", Subject: "Tagihan", Message: "<p>Halo</p>", IsHTML: true},
+			notifier: email,
+			wantID:   "msg-1",
+		},
+		{
+			name:     "notifier error",
+			delivery: models.NotificationDelivery{Channel: models.NotificationChannelTelegram, Recipient: "1", Message: "Halo"},
+			notifier: telegram,
+			wantErr:  true,
+		},
+		{
+			name:     "channel without notifier",
+			delivery: models.NotificationDelivery{Channel: models.NotificationChannelWhatsapp, Recipient: "0812", Message: "Halo"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := dispatchDelivery(context.Background(), registry, tt.delivery)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dispatchDelivery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if id != tt.wantID {
+				t.Errorf("dispatchDelivery() = %q, want %q", id, tt.wantID)
+			}
+			if tt.notifier == nil {
+				return
+			}
+			want := services.NotifierMessage{Subject: tt.delivery.Subject, Body: tt.delivery.Message, IsHTML: tt.delivery.IsHTML}
+			if tt.notifier.recipient != tt.delivery.Recipient || tt.notifier.msg != want {
+				t.Errorf("notifier got %q %+v, want %q %+v", tt.notifier.recipient, tt.notifier.msg, tt.delivery.Recipient, want)
+			}
+		})
+	}
+}
+
+func TestHandlersWithoutDeps(t *testing.T) {
+	// A worker that did not set the dependencies fails the tasks instead of skipping every delivery
+	ctx := context.Background()
+	task := models.ScheduledTask{ID: 1}
+
+	if _, err := handleSendNotification(ctx, nil, task, SendNotificationArgs{}); !errors.Is(err, errNoDeps) {
+		t.Errorf("handleSendNotification() error = %v, want errNoDeps", err)
+	}
+	if _, err := handleRetryNotificationDeliveries(ctx, nil, task, RetryNotificationDeliveriesArgs{}); !errors.Is(err, errNoDeps) {
+		t.Errorf("handleRetryNotificationDeliveries() error = %v, want errNoDeps", err)
+	}
+	if _, err := depsFrom(WithDeps(ctx, Deps{})); !errors.Is(err, errNoDeps) {
+		t.Errorf("depsFrom() without notifiers error = %v, want errNoDeps", err)
+	}
+}
+
+func TestIsRateLimited(t *testing.T) {
+	limited := &services.RateLimitError{RetryAfter: 30 * time.Second}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{name: "rate limited", err: limited, expected: true},
+		{name: "wrapped", err: fmt.Errorf("failed to reply: %w", limited), expected: true},
+		{name: "other error", err: errors.New("chat not found")},
+		{name: "no error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRateLimited(tt.err); got != tt.expected {
+				t.Errorf("isRateLimited(%v) = %v, want %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package tasks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"patungan_app_echo/internal/models"
+)
+
+// MarkOverdueDuesArgs defines the arguments for the overdue task, it takes none
+type MarkOverdueDuesArgs struct{}
+
+// MarkOverdueDuesInterval is how often open dues are checked for being overdue
+const MarkOverdueDuesInterval = "FREQ=HOURLY"
+
+// MarkOverdueDuesTask turns pending dues past their grace period overdue and keeps their late fees up to date.
+// Only the latest state matters, so missed runs are not caught up on.
+var MarkOverdueDuesTask = Define("mark_overdue_dues", handleMarkOverdueDues, TaskOptions[MarkOverdueDuesArgs]{
+	Timeout: 10 * time.Minute,
+})
+
+// handleMarkOverdueDues checks every open due that is past its due date
+func handleMarkOverdueDues(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args MarkOverdueDuesArgs) (map[string]interface{}, error) {
+	now := time.Now()
+	markedOverdue := 0
+	feesUpdated := 0
+
+	var dues []models.PaymentDue
+	result := db.Preload("Plan").
+		Where("payment_status IN ?", []string{models.PaymentStatusPending, models.PaymentStatusOverdue}).
+		Where("due_date <= ?", now).
+		FindInBatches(&dues, 100, func(tx *gorm.DB, batch int) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			for _, due := range dues {
+				if due.Plan.ID == 0 {
+					// The plan was deleted, its dues are canceled separately
+					continue
+				}
+
+				overdueAt := due.Plan.OverdueAt(due.DueDate)
+				if now.Before(overdueAt) {
+					continue
+				}
+
+				lateFee := due.Plan.LateFee.FeeFor(due.CalculatedPayAmount, overdueAt, now)
+				updates := map[string]interface{}{}
+				if due.PaymentStatus == models.PaymentStatusPending {
+					updates["payment_status"] = models.PaymentStatusOverdue
+					updates["overdue_at"] = overdueAt
+				}
+				if lateFee != due.LateFee {
+					updates["late_fee"] = lateFee
+				}
+				if len(updates) == 0 {
+					continue
+				}
+
+				// Guard on the status so a payment settled in the meantime is not turned overdue
+				res := db.Model(&models.PaymentDue{}).
+					Where("id = ? AND payment_status IN ?", due.ID, []string{models.PaymentStatusPending, models.PaymentStatusOverdue}).
+					Updates(updates)
+				if res.Error != nil {
+					return fmt.Errorf("failed to update payment due %d: %w", due.ID, res.Error)
+				}
+				if res.RowsAffected == 0 {
+					continue
+				}
+
+				if _, ok := updates["payment_status"]; ok {
+					markedOverdue++
+				}
+				if _, ok := updates["late_fee"]; ok {
+					feesUpdated++
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if markedOverdue > 0 || feesUpdated > 0 {
+		log.Printf("[Task MarkOverdueDues] %d dues turned overdue, %d late fees updated", markedOverdue, feesUpdated)
+	}
+
+	return map[string]interface{}{
+		"status":         "success",
+		"marked_overdue": markedOverdue,
+		"fees_updated":   feesUpdated,
+	}, nil
+}
+
+// SendPaymentRemindersArgs defines the arguments for the reminder task, it takes none
+type SendPaymentRemindersArgs struct{}
+
+// SendPaymentRemindersInterval is how often open dues are checked for reminders to send
+const SendPaymentRemindersInterval = "FREQ=HOURLY"
+
+// SendPaymentRemindersTask enqueues the reminders of open dues following their plan's reminder policy
+var SendPaymentRemindersTask = Define("send_payment_reminders", handleSendPaymentReminders, TaskOptions[SendPaymentRemindersArgs]{
+	Timeout: 10 * time.Minute,
+})
+
+// reminderEvents maps each reminder kind to the notification template it is sent with
+var reminderEvents = map[models.ReminderKind]models.NotificationEvent{
+	models.ReminderKindBeforeDue: models.NotificationEventReminder,
+	models.ReminderKindOnDue:     models.NotificationEventReminder,
+	models.ReminderKindOverdue:   models.NotificationEventOverdue,
+}
+
+// handleSendPaymentReminders sends the latest due reminder of every open due that was not sent yet
+func handleSendPaymentReminders(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args SendPaymentRemindersArgs) (map[string]interface{}, error) {
+	now := time.Now()
+	sent := 0
+
+	var dues []models.PaymentDue
+	result := db.Preload("Plan").Preload("User").
+		Joins("JOIN plans ON plans.id = payment_dues.plan_id AND plans.deleted_at IS NULL").
+		Where("plans.reminder_enabled = ?", true).
+		Where("payment_dues.payment_status IN ?", []string{models.PaymentStatusPending, models.PaymentStatusOverdue}).
+		FindInBatches(&dues, 100, func(tx *gorm.DB, batch int) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			for _, due := range dues {
+				// Reminders scheduled before the due existed are covered by the bill notification
+				step, ok := due.Plan.Reminders.LatestStep(due.DueDate, due.Plan.OverdueAt(due.DueDate), due.CreatedAt, now)
+				if !ok {
+					continue
+				}
+
+				created, err := enqueueReminder(db, due, step)
+				if err != nil {
+					return err
+				}
+				if created {
+					sent++
+				}
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if sent > 0 {
+		log.Printf("[Task SendPaymentReminders] Enqueued %d reminders", sent)
+	}
+
+	return map[string]interface{}{
+		"status": "success",
+		"sent":   sent,
+	}, nil
+}
+
+// enqueueReminder records a reminder step for a due and enqueues its notification.
+// Returns false when the step was already sent.
+func enqueueReminder(db *gorm.DB, due models.PaymentDue, step models.ReminderStep) (bool, error) {
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		reminder := models.PaymentDueReminder{
+			PaymentDueID: due.ID,
+			Kind:         step.Kind,
+			Sequence:     step.Sequence,
+			ScheduledFor: step.At,
+		}
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+		if res.Error != nil {
+			return fmt.Errorf("failed to record reminder for payment due %d: %w", due.ID, res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		notifArgs := SendNotificationArgs{
+			Users: []NotificationUser{{
+				UserID:       due.UserID,
+				Username:     due.User.Name,
+				Email:        due.User.Email,
+				PhoneNumber:  due.User.Phone,
+				PaymentLink:  paymentLink(due.UUID),
+				Amount:       due.PayableAmount(),
+				PaymentDueID: due.ID,
+			}},
+			Event:    reminderEvents[step.Kind],
+			PlanName: due.Plan.Name,
+			DueDate:  due.LocalDueDate().Format("02 Jan 2006"),
+		}
+		notificationTask, err := SendNotificationTask.Enqueue(tx, notifArgs, time.Now(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to enqueue reminder for payment due %d: %w", due.ID, err)
+		}
+
+		if err := tx.Model(&reminder).Update("scheduled_task_id", notificationTask.ID).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// NotifyPaymentReceived enqueues the payment_received notification of a recorded payment.
+// It is a services.PaymentListener, so it runs in the transaction recording the payment.
+func NotifyPaymentReceived(tx *gorm.DB, due *models.PaymentDue, payment *models.UserPayment) error {
+	var paid models.PaymentDue
+	if err := tx.Preload("Plan").Preload("User").First(&paid, due.ID).Error; err != nil {
+		return fmt.Errorf("failed to load payment due %d: %w", due.ID, err)
+	}
+	if _, err := SendNotificationTask.Enqueue(tx, paymentEventArgs(models.NotificationEventPaymentReceived, paid, payment.TotalPay), time.Now(), nil); err != nil {
+		return fmt.Errorf("failed to enqueue payment notification for payment due %d: %w", due.ID, err)
+	}
+	return nil
+}
+
+// NotifyRefund enqueues the refund notification of a refund, in the transaction recording it.
+// The due must have its plan and user loaded.
+func NotifyRefund(tx *gorm.DB, due models.PaymentDue, refund models.Refund) error {
+	if _, err := SendNotificationTask.Enqueue(tx, paymentEventArgs(models.NotificationEventRefund, due, refund.TotalRefund), time.Now(), nil); err != nil {
+		return fmt.Errorf("failed to enqueue refund notification for payment due %d: %w", due.ID, err)
+	}
+	return nil
+}
+
+// paymentEventArgs builds the notification of a payment or refund of a due. The due is not set on
+// the user, since notifications of paid and canceled dues are otherwise not sent.
+func paymentEventArgs(event models.NotificationEvent, due models.PaymentDue, amount models.Money) SendNotificationArgs {
+	return SendNotificationArgs{
+		Users: []NotificationUser{{
+			UserID:      due.UserID,
+			Username:    due.User.Name,
+			Email:       due.User.Email,
+			PhoneNumber: due.User.Phone,
+			PaymentLink: paymentLink(due.UUID),
+			Amount:      amount,
+		}},
+		Event:    event,
+		PlanName: due.Plan.Name,
+		Amount:   amount,
+		DueDate:  due.LocalDueDate().Format("02 Jan 2006"),
+	}
+}
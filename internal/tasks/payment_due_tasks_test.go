@@ -0,0 +1,40 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestPaymentEventArgs(t *testing.T) {
+	t.Setenv("APP_URL", "https://patungan.example/")
+	due := models.PaymentDue{
+		ID:      9,
+		UserID:  4,
+		UUID:    "abc",
+		DueDate: time.Date(2026, 4, 5, 2, 0, 0, 0, time.UTC),
+		User:    models.User{Name: "Budi", Email: "budi@example.com", Phone: "6281234567890"},
+		Plan:    models.Plan{Name: "Netflix Keluarga", Timezone: "Asia/Jakarta"},
+	}
+
+	for _, event := range []models.NotificationEvent{models.NotificationEventPaymentReceived, models.NotificationEventRefund} {
+		t.Run(string(event), func(t *testing.T) {
+			args := paymentEventArgs(event, due, models.Money(45000))
+			if err := args.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			if args.Event != event || args.PlanName != "Netflix Keluarga" || args.DueDate != "05 Apr 2026" {
+				t.Errorf("args = %+v, want the %s event of the plan", args, event)
+			}
+			user := args.Users[0]
+			if user.UserID != uint(4) || user.Amount != models.Money(45000) || user.PaymentLink != "https://patungan.example/p/abc" {
+				t.Errorf("user = %+v, want the payer with the paid amount", user)
+			}
+			// Set, it would skip the notification since the due is paid or canceled
+			if user.PaymentDueID != 0 {
+				t.Errorf("PaymentDueID = %d, want 0", user.PaymentDueID)
+			}
+		})
+	}
+}
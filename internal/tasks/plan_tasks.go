@@ -5,56 +5,47 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"patungan_app_echo/internal/models"
 )
 
 // ProcessPlanScheduleArgs defines the arguments for a plan schedule task
 type ProcessPlanScheduleArgs struct {
-	PlanID            uint      `json:"plan_id"`
-	Due               time.Time `json:"-"`
-	RecurringInterval *string   `json:"-"`
+	PlanID uint `json:"plan_id"`
 }
 
-// ProcessPlanScheduleTaskDef encapsulates the plan schedule processing logic
-type ProcessPlanScheduleTaskDef struct{}
-
-// TaskID returns the unique identifier for this task
-func (t *ProcessPlanScheduleTaskDef) TaskID() string {
-	return "process_plan_schedule"
-}
-
-// CreateTask builds a ScheduledTask record for this task
-func (t *ProcessPlanScheduleTaskDef) CreateTask(args ProcessPlanScheduleArgs) (*models.ScheduledTask, error) {
-	taskType := models.ScheduledTaskTypeOneTime
-	if args.RecurringInterval != nil && *args.RecurringInterval != "" {
-		taskType = models.ScheduledTaskTypeRecurring
+// Validate checks that the arguments reference a plan
+func (a ProcessPlanScheduleArgs) Validate() error {
+	if a.PlanID == 0 {
+		return fmt.Errorf("plan_id is required")
 	}
-	return BuildScheduledTask(t.TaskID(), args, args.Due, args.RecurringInterval, taskType, 3)
+	return nil
 }
 
-// HandleExecution handles the processing of plan schedules
-func (t *ProcessPlanScheduleTaskDef) HandleExecution(ctx context.Context, db *gorm.DB, task models.ScheduledTask) (map[string]interface{}, error) {
-	argsBytes, err := json.Marshal(task.Arguments)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal args: %w", err)
-	}
-
-	var parsedArgs ProcessPlanScheduleArgs
-	if err := json.Unmarshal(argsBytes, &parsedArgs); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal args: %w", err)
-	}
+// ProcessPlanScheduleTask creates the payment dues of a plan occurrence and notifies its participants.
+// Every missed occurrence is caught up on, so members are billed for each period.
+var ProcessPlanScheduleTask = Define("process_plan_schedule", handleProcessPlanSchedule, TaskOptions[ProcessPlanScheduleArgs]{
+	Timeout:       2 * time.Minute,
+	MisfirePolicy: models.MisfirePolicyCatchUpAll,
+	Requeue:       prepareProcessPlanScheduleRequeue,
+})
 
+// handleProcessPlanSchedule handles the processing of plan schedules
+func handleProcessPlanSchedule(ctx context.Context, db *gorm.DB, task models.ScheduledTask, parsedArgs ProcessPlanScheduleArgs) (map[string]interface{}, error) {
 	planID := parsedArgs.PlanID
 
 	var plan models.Plan
-	if err := db.Preload("Participants.User").Preload("ScheduledTask").First(&plan, planID).Error; err != nil {
+	// Participants are ordered so the remainder of the split always goes to the same people
+	err := db.Preload("Participants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id asc")
+	}).Preload("Participants.User").First(&plan, planID).Error
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch plan: %w", err)
 	}
 
@@ -67,86 +58,124 @@ func (t *ProcessPlanScheduleTaskDef) HandleExecution(ctx context.Context, db *go
 		totalPortions += p.Portion
 	}
 
-	if totalPortions == 0 {
-		return nil, fmt.Errorf("total portions is 0")
+	// Split the price in whole rupiah according to the plan split mode, the amounts always add up to the plan total
+	amounts, err := plan.SplitAmounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to split plan price: %w", err)
 	}
 
-	pricePerPortion := plan.TotalPrice / float64(totalPortions)
+	// Use the occurrence date rather than Due, which moves forward while the task is retried
+	occurrence := task.OccurrenceDue()
+	cycleDate := models.BillingCycleDate(occurrence, plan.Location())
+	dueDate := plan.DueDateFor(occurrence)
 
 	var createdDues []uint
-	var notificationUsers []NotificationUser
-
-	appBaseURL := os.Getenv("APP_URL")
-	if appBaseURL == "" {
-		appBaseURL = "http://localhost:8080"
-	}
+	var existingDues []uint
+	var notificationArgs *SendNotificationArgs
+
+	// Generate the dues of the cycle and their notification together, so a failed run leaves nothing behind
+	// and a repeated run of the same cycle does not bill anyone twice
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var notificationUsers []NotificationUser
+
+		for i, p := range plan.Participants {
+			amount := amounts[i]
+
+			due := models.PaymentDue{
+				PlanID:              plan.ID,
+				UserID:              p.UserID,
+				Portion:             p.Portion,
+				CalculatedPayAmount: amount,
+				PaymentStatus:       models.PaymentStatusPending,
+				DueDate:             dueDate,
+				CycleDate:           &cycleDate,
+				UUID:                uuid.New().String(),
+			}
+			result := tx.Clauses(clause.OnConflict{
+				Columns:     []clause.Column{{Name: "plan_id"}, {Name: "user_id"}, {Name: "cycle_date"}},
+				TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+				DoNothing:   true,
+			}).Create(&due)
+			if result.Error != nil {
+				return fmt.Errorf("failed to create payment due for user %d: %w", p.UserID, result.Error)
+			}
 
-	for _, p := range plan.Participants {
-		amount := pricePerPortion * float64(p.Portion)
-
-		due := models.PaymentDue{
-			PlanID:              plan.ID,
-			UserID:              p.UserID,
-			Portion:             p.Portion,
-			CalculatedPayAmount: amount,
-			PaymentStatus:       models.PaymentStatusPending,
-			DueDate:             plan.ScheduledTask.Due,
-			UUID:                uuid.New().String(),
-		}
-		if err := db.Create(&due).Error; err != nil {
-			log.Printf("Failed to create PaymentDue for user %d: %v", p.UserID, err)
-			continue
+			if result.RowsAffected == 0 {
+				// The due of this cycle was generated by an earlier run, its participant was already notified
+				var existing models.PaymentDue
+				if err := tx.Where("plan_id = ? AND user_id = ? AND cycle_date = ?", plan.ID, p.UserID, cycleDate).First(&existing).Error; err != nil {
+					return fmt.Errorf("failed to fetch existing payment due for user %d: %w", p.UserID, err)
+				}
+				existingDues = append(existingDues, existing.ID)
+				continue
+			}
+			createdDues = append(createdDues, due.ID)
+
+			notificationUsers = append(notificationUsers, NotificationUser{
+				UserID:       p.UserID,
+				Username:     p.User.Name,
+				Email:        p.User.Email,
+				PhoneNumber:  p.User.Phone,
+				PaymentLink:  paymentLink(due.UUID),
+				Amount:       amount,
+				PaymentDueID: due.ID,
+			})
 		}
-		createdDues = append(createdDues, due.ID)
-
-		paymentLink := fmt.Sprintf("%s/p/%s", appBaseURL, due.UUID)
-
-		notificationUsers = append(notificationUsers, NotificationUser{
-			UserID:      p.UserID,
-			Username:    p.User.Name,
-			Email:       p.User.Email,
-			PhoneNumber: p.User.Phone,
-			PaymentLink: paymentLink,
-		})
-	}
 
-	if len(notificationUsers) > 0 {
-		notifArgs := SendNotificationArgs{
-			Users:         notificationUsers,
-			NotifTemplate: "Halo $name, tagihan untuk plan $plan_name sudah jatuh tempo. Yuk segera dibayar di $paymentlink",
-			Subject:       "Tagihan Plan " + plan.Name,
-			PlanName:      plan.Name,
-			Amount:        pricePerPortion,
-			DueDate:       plan.ScheduledTask.Due.Format("02 Jan 2006"),
+		if len(notificationUsers) == 0 {
+			return nil
 		}
 
-		notifTask, err := SendNotificationTask.CreateTask(notifArgs)
-		if err != nil {
-			log.Printf("Failed to create notification task args: %v", err)
-		} else {
-			if err := db.Create(notifTask).Error; err != nil {
-				log.Printf("Failed to create notification task: %v", err)
+		if plan.GroupDigest.Enabled() {
+			if err := enqueueGroupDigest(tx, plan, dueDate); err != nil {
+				return err
 			}
 		}
 
-		// Serialize the argument explicitly as requested for logging
-		serializedArgs, _ := json.Marshal(notifArgs)
-		log.Printf("[Task ProcessPlanSchedule] Generated notification args: %s", string(serializedArgs))
-
-		return map[string]interface{}{
-			"status":            "success",
-			"created_count":     len(createdDues),
-			"total_portions":    totalPortions,
-			"notification_args": string(serializedArgs),
-		}, nil
+		notifArgs := SendNotificationArgs{
+			Users:    notificationUsers,
+			Event:    models.NotificationEventDueCreated,
+			PlanName: plan.Name,
+			DueDate:  dueDate.In(plan.Location()).Format("02 Jan 2006"),
+		}
+		if _, err := SendNotificationTask.Enqueue(tx, notifArgs, time.Now(), nil); err != nil {
+			return fmt.Errorf("failed to create notification task: %w", err)
+		}
+		notificationArgs = &notifArgs
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"status":         "success",
+		"cycle_date":     cycleDate.Format("2006-01-02"),
 		"created_count":  len(createdDues),
+		"existing_count": len(existingDues),
+		"split_mode":     string(plan.SplitMode),
 		"total_portions": totalPortions,
-	}, nil
+	}
+	if len(existingDues) > 0 {
+		log.Printf("[Task ProcessPlanSchedule] %d dues of plan %d for cycle %s already existed", len(existingDues), plan.ID, cycleDate.Format("2006-01-02"))
+		result["existing_dues"] = existingDues
+	}
+
+	if notificationArgs != nil {
+		// Serialize the argument explicitly as requested for logging
+		serializedArgs, _ := json.Marshal(notificationArgs)
+		log.Printf("[Task ProcessPlanSchedule] Generated notification args: %s", string(serializedArgs))
+		result["notification_args"] = string(serializedArgs)
+	}
+
+	return result, nil
 }
 
-// ProcessPlanScheduleTask is the singleton instance of ProcessPlanScheduleTaskDef
-var ProcessPlanScheduleTask = &ProcessPlanScheduleTaskDef{}
+// prepareProcessPlanScheduleRequeue makes sure the plan still exists before a failed plan task is requeued
+func prepareProcessPlanScheduleRequeue(db *gorm.DB, task *models.ScheduledTask, args *ProcessPlanScheduleArgs) error {
+	var plan models.Plan
+	if err := db.First(&plan, args.PlanID).Error; err != nil {
+		return fmt.Errorf("plan %d no longer exists: %w", args.PlanID, err)
+	}
+	return nil
+}
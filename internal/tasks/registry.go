@@ -3,7 +3,9 @@ package tasks
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -14,35 +16,131 @@ import (
 // It takes context, db connection, and the scheduled task models, and returns a result map and error
 type TaskHandler func(ctx context.Context, db *gorm.DB, task models.ScheduledTask) (map[string]interface{}, error)
 
+// DefaultTaskTimeout is the execution deadline for tasks registered without their own timeout
+const DefaultTaskTimeout = 5 * time.Minute
+
+// registeredTask is a handler together with its execution deadline, argument validation and
+// the settings of new tasks
+type registeredTask struct {
+	handler       TaskHandler
+	timeout       time.Duration
+	validate      func(arguments map[string]interface{}) error
+	maxAttempt    int
+	misfirePolicy models.MisfirePolicy
+}
+
+// RequeueHook prepares a task before it is put back in the queue, e.g. resetting
+// task-specific counters in its arguments. Returning an error aborts the requeue.
+type RequeueHook func(db *gorm.DB, task *models.ScheduledTask) error
+
 // Registry stores the mapping of task names to handlers
 type Registry struct {
-	mu       sync.RWMutex
-	handlers map[string]TaskHandler
+	mu           sync.RWMutex
+	handlers     map[string]registeredTask
+	requeueHooks map[string]RequeueHook
 }
 
 // GlobalRegistry is the default global registry
 var GlobalRegistry = &Registry{
-	handlers: make(map[string]TaskHandler),
+	handlers:     make(map[string]registeredTask),
+	requeueHooks: make(map[string]RequeueHook),
 }
 
-// Register adds a handler for a task name
-func (r *Registry) Register(name string, handler TaskHandler) {
+// Register adds a handler for a task name. The handler's context is canceled once
+// timeout has elapsed; a zero timeout uses DefaultTaskTimeout.
+func (r *Registry) Register(name string, handler TaskHandler, timeout time.Duration) {
+	r.add(name, registeredTask{handler: handler, timeout: timeout})
+}
+
+// add stores a registry entry, filling in the defaults
+func (r *Registry) add(name string, entry registeredTask) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.handlers[name] = handler
+	if entry.timeout <= 0 {
+		entry.timeout = DefaultTaskTimeout
+	}
+	if entry.maxAttempt <= 0 {
+		entry.maxAttempt = DefaultMaxAttempt
+	}
+	if entry.misfirePolicy == "" {
+		entry.misfirePolicy = models.MisfirePolicyRunOnce
+	}
+	r.handlers[name] = entry
 }
 
 // Get retrieves a handler for a task name
 func (r *Registry) Get(name string) (TaskHandler, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	handler, ok := r.handlers[name]
-	return handler, ok
+	entry, ok := r.handlers[name]
+	return entry.handler, ok
+}
+
+// Timeout returns the execution deadline for a task name
+func (r *Registry) Timeout(name string) time.Duration {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if entry, ok := r.handlers[name]; ok {
+		return entry.timeout
+	}
+	return DefaultTaskTimeout
+}
+
+// TaskDefaults returns the max attempts and misfire policy new tasks of a task name get
+func (r *Registry) TaskDefaults(name string) (int, models.MisfirePolicy) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if entry, ok := r.handlers[name]; ok {
+		return entry.maxAttempt, entry.misfirePolicy
+	}
+	return DefaultMaxAttempt, models.MisfirePolicyRunOnce
+}
+
+// ValidateArguments checks raw arguments against the schema of a task name.
+// Tasks registered without typed arguments accept any arguments.
+func (r *Registry) ValidateArguments(name string, arguments map[string]interface{}) error {
+	r.mu.RLock()
+	entry, ok := r.handlers[name]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("unknown task %q", name)
+	}
+	if entry.validate == nil {
+		return nil
+	}
+	return entry.validate(arguments)
+}
+
+// Names returns the registered task names in alphabetical order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RegisterRequeueHook adds a requeue hook for a task name
+func (r *Registry) RegisterRequeueHook(name string, hook RequeueHook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requeueHooks[name] = hook
+}
+
+// GetRequeueHook retrieves the requeue hook for a task name
+func (r *Registry) GetRequeueHook(name string) (RequeueHook, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	hook, ok := r.requeueHooks[name]
+	return hook, ok
 }
 
 // RegisterHandler is a helper to register to the global registry
-func RegisterHandler(name string, handler TaskHandler) {
-	GlobalRegistry.Register(name, handler)
+func RegisterHandler(name string, handler TaskHandler, timeout time.Duration) {
+	GlobalRegistry.Register(name, handler, timeout)
 }
 
 // GetHandler is a helper to get from the global registry
@@ -50,11 +148,26 @@ func GetHandler(name string) (TaskHandler, bool) {
 	return GlobalRegistry.Get(name)
 }
 
+// GetTimeout is a helper to get a task's execution deadline from the global registry
+func GetTimeout(name string) time.Duration {
+	return GlobalRegistry.Timeout(name)
+}
+
+// GetTaskDefaults is a helper to get the settings of new tasks from the global registry
+func GetTaskDefaults(name string) (int, models.MisfirePolicy) {
+	return GlobalRegistry.TaskDefaults(name)
+}
+
+// ValidateArguments is a helper to validate task arguments against the global registry
+func ValidateArguments(name string, arguments map[string]interface{}) error {
+	return GlobalRegistry.ValidateArguments(name, arguments)
+}
+
 // Initialize registers default tasks (can be expanded)
 func Initialize() {
 	// Example task
 	RegisterHandler("example_task", func(ctx context.Context, db *gorm.DB, task models.ScheduledTask) (map[string]interface{}, error) {
 		fmt.Printf("Executing example_task with args: %v\n", task.Arguments)
 		return map[string]interface{}{"status": "success", "message": "Example task executed"}, nil
-	})
+	}, 0)
 }
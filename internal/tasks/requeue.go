@@ -0,0 +1,92 @@
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+)
+
+// ErrTaskNotRunnable is returned when running a task that is done or disabled
+var ErrTaskNotRunnable = errors.New("task is not runnable")
+
+// RequeueTask puts a task back in the queue so the worker runs it on its next pass.
+// It is used to retry failed tasks as well as to run a task ahead of its due date.
+// The attempt counter is reset and the original occurrence date is kept, so tasks
+// that depend on it (e.g. payment due dates) still see the date they were scheduled for.
+func RequeueTask(db *gorm.DB, task *models.ScheduledTask) error {
+	if task.Arguments == nil {
+		task.Arguments = make(map[string]interface{})
+	}
+
+	if hook, ok := GlobalRegistry.GetRequeueHook(task.TaskName); ok {
+		if err := hook(db, task); err != nil {
+			return err
+		}
+	}
+
+	occurrence := task.OccurrenceDue()
+	task.Status = models.ScheduledTaskStatusActive
+	task.Attempt = 0
+	task.Due = time.Now()
+	task.OriginalDue = &occurrence
+	task.ClaimedBy = nil
+	task.LeaseUntil = nil
+
+	if err := db.Model(task).
+		Select("status", "attempt", "due", "original_due", "claimed_by", "lease_until", "arguments").
+		Updates(task).Error; err != nil {
+		return err
+	}
+
+	return models.NotifyScheduledTaskDue(db, task.ID)
+}
+
+// RunTaskNow runs a task ahead of its schedule and returns the task that will run. A recurring
+// task whose next occurrence is still ahead gets a separate one-time run, so that occurrence
+// still runs on its own date and the schedule is left as it is. Other tasks are requeued.
+func RunTaskNow(db *gorm.DB, task *models.ScheduledTask) (*models.ScheduledTask, error) {
+	if task.Status == models.ScheduledTaskStatusDone || task.Status == models.ScheduledTaskStatusDisabled {
+		return nil, fmt.Errorf("%w: it is %s", ErrTaskNotRunnable, task.Status)
+	}
+
+	now := time.Now()
+	if task.TaskType != models.ScheduledTaskTypeRecurring || !task.OccurrenceDue().After(now) {
+		if err := RequeueTask(db, task); err != nil {
+			return nil, err
+		}
+		return task, nil
+	}
+
+	run := oneTimeRun(*task, now)
+	if hook, ok := GlobalRegistry.GetRequeueHook(run.TaskName); ok {
+		if err := hook(db, run); err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to create run of %s task: %w", task.TaskName, err)
+	}
+	return run, nil
+}
+
+// oneTimeRun returns a one-time copy of a task due at now, running outside its schedule
+func oneTimeRun(task models.ScheduledTask, now time.Time) *models.ScheduledTask {
+	args := make(map[string]interface{}, len(task.Arguments))
+	for k, v := range task.Arguments {
+		args[k] = v
+	}
+	return &models.ScheduledTask{
+		TaskName:      task.TaskName,
+		Arguments:     args,
+		Due:           now,
+		Status:        models.ScheduledTaskStatusActive,
+		TaskType:      models.ScheduledTaskTypeOneTime,
+		MaxAttempt:    task.MaxAttempt,
+		MisfirePolicy: task.MisfirePolicy,
+		Timezone:      task.Timezone,
+	}
+}
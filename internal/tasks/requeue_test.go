@@ -0,0 +1,46 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestOneTimeRun(t *testing.T) {
+	monthly := "FREQ=MONTHLY"
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	occurrence := time.Date(2026, 4, 1, 9, 0, 0, 0, time.UTC)
+	task := models.ScheduledTask{
+		ID:                7,
+		TaskName:          "process_plan_schedule",
+		Arguments:         map[string]interface{}{"plan_id": float64(3)},
+		Due:               occurrence,
+		RecurringInterval: &monthly,
+		Status:            models.ScheduledTaskStatusActive,
+		TaskType:          models.ScheduledTaskTypeRecurring,
+		MaxAttempt:        3,
+		MisfirePolicy:     models.MisfirePolicyCatchUpAll,
+		Timezone:          "Asia/Jakarta",
+	}
+
+	run := oneTimeRun(task, now)
+	if run.ID != 0 || run.TaskType != models.ScheduledTaskTypeOneTime || run.RecurringInterval != nil {
+		t.Errorf("run = %+v, want a new one-time task", run)
+	}
+	if !run.Due.Equal(now) || run.OriginalDue != nil {
+		t.Errorf("run due = %s, original due = %v, want due now", run.Due, run.OriginalDue)
+	}
+	if run.TaskName != task.TaskName || run.Arguments["plan_id"] != float64(3) || run.MaxAttempt != 3 || run.Timezone != "Asia/Jakarta" {
+		t.Errorf("run = %+v, want the name, arguments and options of the task", run)
+	}
+
+	// Once the run completes the worker marks it done, the schedule keeps its occurrence
+	if next := run.NextDueAfterRun(); !next.Equal(now) {
+		t.Errorf("run NextDueAfterRun() = %s, want %s so it is marked done", next, now)
+	}
+	run.Arguments["plan_id"] = float64(4)
+	if task.Arguments["plan_id"] != float64(3) || !task.Due.Equal(occurrence) {
+		t.Errorf("task = %+v, want it left as it was", task)
+	}
+}
@@ -0,0 +1,86 @@
+package tasks
+
+import (
+	"log"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Default retry settings, overridable via TASK_RETRY_BASE_DELAY, TASK_RETRY_MAX_DELAY and TASK_RETRY_JITTER
+const (
+	DefaultRetryBaseDelay = 30 * time.Second
+	DefaultRetryMaxDelay  = 1 * time.Hour
+	DefaultRetryJitter    = 0.2
+)
+
+// RetryPolicy computes how long a failed task waits before its next attempt.
+// The delay doubles with every attempt (BaseDelay, 2*BaseDelay, 4*BaseDelay, ...),
+// is capped at MaxDelay and randomized by +/- Jitter (a fraction of the delay).
+type RetryPolicy struct {
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	Jitter    float64
+}
+
+// RetryPolicyFromEnv builds a RetryPolicy from environment variables, using defaults for unset values
+func RetryPolicyFromEnv() RetryPolicy {
+	policy := RetryPolicy{
+		BaseDelay: DefaultRetryBaseDelay,
+		MaxDelay:  DefaultRetryMaxDelay,
+		Jitter:    DefaultRetryJitter,
+	}
+
+	if val := os.Getenv("TASK_RETRY_BASE_DELAY"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			policy.BaseDelay = d
+		} else {
+			log.Printf("Invalid TASK_RETRY_BASE_DELAY %q, using default %s", val, DefaultRetryBaseDelay)
+		}
+	}
+	if val := os.Getenv("TASK_RETRY_MAX_DELAY"); val != "" {
+		if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			policy.MaxDelay = d
+		} else {
+			log.Printf("Invalid TASK_RETRY_MAX_DELAY %q, using default %s", val, DefaultRetryMaxDelay)
+		}
+	}
+	if val := os.Getenv("TASK_RETRY_JITTER"); val != "" {
+		if j, err := strconv.ParseFloat(val, 64); err == nil && j >= 0 && j < 1 {
+			policy.Jitter = j
+		} else {
+			log.Printf("Invalid TASK_RETRY_JITTER %q, using default %.2f", val, DefaultRetryJitter)
+		}
+	}
+
+	return policy
+}
+
+// Delay returns the wait before retrying after the given failed attempt (1-based)
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	return p.delay(attempt, rand.Float64())
+}
+
+// delay computes the backoff for a given random sample in [0, 1)
+func (p RetryPolicy) delay(attempt int, sample float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 {
+		// Spread the delay over [delay*(1-jitter), delay*(1+jitter))
+		factor := 1 + p.Jitter*(2*sample-1)
+		delay = time.Duration(float64(delay) * factor)
+	}
+
+	return delay
+}
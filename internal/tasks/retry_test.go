@@ -0,0 +1,62 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		BaseDelay: 30 * time.Second,
+		MaxDelay:  5 * time.Minute,
+	}
+
+	tests := []struct {
+		name     string
+		attempt  int
+		expected time.Duration
+	}{
+		{name: "first attempt uses base delay", attempt: 1, expected: 30 * time.Second},
+		{name: "second attempt doubles", attempt: 2, expected: 60 * time.Second},
+		{name: "third attempt doubles again", attempt: 3, expected: 120 * time.Second},
+		{name: "capped at max delay", attempt: 5, expected: 5 * time.Minute},
+		{name: "large attempt stays capped", attempt: 100, expected: 5 * time.Minute},
+		{name: "zero attempt treated as first", attempt: 0, expected: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := policy.delay(tt.attempt, 0.5)
+			if result != tt.expected {
+				t.Errorf("delay(%d) = %s; want %s", tt.attempt, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	policy := RetryPolicy{
+		BaseDelay: 100 * time.Second,
+		MaxDelay:  time.Hour,
+		Jitter:    0.2,
+	}
+
+	tests := []struct {
+		name     string
+		sample   float64
+		expected time.Duration
+	}{
+		{name: "lowest sample", sample: 0, expected: 80 * time.Second},
+		{name: "middle sample", sample: 0.5, expected: 100 * time.Second},
+		{name: "high sample", sample: 0.75, expected: 110 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := policy.delay(1, tt.sample)
+			if result != tt.expected {
+				t.Errorf("delay(1, %v) = %s; want %s", tt.sample, result, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,342 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+)
+
+// HandleWhatsappMessageArgs is an incoming WhatsApp message for the bot
+type HandleWhatsappMessageArgs struct {
+	MessageID string `json:"message_id,omitempty"`
+	ChatID    string `json:"chat_id"`          // chat to reply in, a number or a group
+	Sender    string `json:"sender,omitempty"` // who wrote the message, differs from ChatID in groups
+	Body      string `json:"body"`
+}
+
+// Validate checks that there is a message to answer
+func (a HandleWhatsappMessageArgs) Validate() error {
+	if a.ChatID == "" {
+		return fmt.Errorf("chat_id is required")
+	}
+	if strings.TrimSpace(a.Body) == "" {
+		return fmt.Errorf("body is required")
+	}
+	return nil
+}
+
+// IsGroup reports whether the message was sent in a group chat
+func (a HandleWhatsappMessageArgs) IsGroup() bool {
+	return strings.HasSuffix(a.ChatID, "@g.us")
+}
+
+// HandleWhatsappMessageTask answers a bot command received through the WAHA webhook
+var HandleWhatsappMessageTask = Define("handle_whatsapp_message", handleWhatsappMessage, TaskOptions[HandleWhatsappMessageArgs]{
+	Timeout:    2 * time.Minute,
+	MaxAttempt: 2,
+})
+
+// botCommand is a command members can send to the bot
+type botCommand string
+
+const (
+	botCommandDues    botCommand = "tagihan"
+	botCommandPaid    botCommand = "lunas"
+	botCommandStop    botCommand = "stop"
+	botCommandUnknown botCommand = ""
+)
+
+// botHelp lists the commands of the bot
+const botHelp = "Perintah yang tersedia:\n" +
+	"• tagihan - daftar tagihan yang belum dibayar\n" +
+	"• lunas <id> - cek pembayaran tagihan\n" +
+	"• stop - matikan notifikasi"
+
+// groupSummaryPlanLimit bounds how many plans a group summary lists
+const groupSummaryPlanLimit = 5
+
+// parseBotCommand reads the command and its argument from a message, e.g. "Lunas #12"
+func parseBotCommand(text string) (botCommand, string) {
+	fields := strings.Fields(strings.ToLower(text))
+	if len(fields) == 0 {
+		return botCommandUnknown, ""
+	}
+
+	arg := ""
+	if len(fields) > 1 {
+		arg = strings.TrimPrefix(fields[1], "#")
+	}
+	switch command := botCommand(fields[0]); command {
+	case botCommandDues, botCommandPaid, botCommandStop:
+		return command, arg
+	}
+	return botCommandUnknown, ""
+}
+
+// handleWhatsappMessage runs the command of a message and replies in the chat it came from.
+// In groups only "tagihan" is answered, with a summary of who has paid.
+func handleWhatsappMessage(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args HandleWhatsappMessageArgs) (map[string]interface{}, error) {
+	command, arg := parseBotCommand(args.Body)
+	if args.IsGroup() && command != botCommandDues {
+		return map[string]interface{}{"status": "ignored"}, nil
+	}
+
+	deps, err := depsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		reply string
+		user  models.User
+		found bool
+	)
+	if !args.IsGroup() {
+		// The summary of a group does not depend on who asked, only personal chats look up the sender
+		if user, found, err = findUserByChatID(db, args.ChatID); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case args.IsGroup():
+		reply, err = groupSummaryReply(db, args.ChatID)
+	case !found:
+		reply = "Nomor ini belum terdaftar di Patungan. Hubungi admin plan kamu untuk mendaftarkan nomor ini."
+	case command == botCommandDues:
+		reply, err = pendingDuesReply(db, user)
+	case command == botCommandPaid:
+		reply, err = verifyPaymentReply(ctx, db, deps.Payments, user, arg)
+	case command == botCommandStop:
+		reply, err = stopNotificationsReply(db, user)
+	default:
+		reply = fmt.Sprintf("Halo %s!\n\n%s", user.Name, botHelp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if reply == "" {
+		return map[string]interface{}{"status": "ignored"}, nil
+	}
+
+	messageID, err := deps.Notifiers.Send(ctx, models.NotificationChannelWhatsapp, args.ChatID, services.NotifierMessage{Body: reply})
+	var limited *services.RateLimitError
+	if errors.As(err, &limited) {
+		// Commands are safe to handle again, reply once the rate limit allows
+		if _, err := deferTask(db, task, args, time.Now().Add(limited.RetryAfter)); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"status": "deferred", "retry_after": limited.RetryAfter.String()}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to reply to %s: %w", args.ChatID, err)
+	}
+
+	return map[string]interface{}{
+		"status":     "replied",
+		"command":    string(command),
+		"message_id": messageID,
+	}, nil
+}
+
+// findUserByChatID finds the user whose phone number is the given WhatsApp chat
+func findUserByChatID(db *gorm.DB, chatID string) (models.User, bool, error) {
+	var user models.User
+	err := db.Select("id", "name", "phone").Where("TRIM(phone) IN ?", services.ChatIDPhones(chatID)).Order("id").First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.User{}, false, nil
+	}
+	if err != nil {
+		return models.User{}, false, fmt.Errorf("failed to look up sender: %w", err)
+	}
+	return user, true, nil
+}
+
+// pendingDuesReply lists the unpaid dues of a user with their payment links
+func pendingDuesReply(db *gorm.DB, user models.User) (string, error) {
+	var dues []models.PaymentDue
+	if err := db.Preload("Plan").
+		Where("user_id = ? AND payment_status IN ?", user.ID, []string{models.PaymentStatusPending, models.PaymentStatusOverdue}).
+		Order("due_date").
+		Find(&dues).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch dues of user %d: %w", user.ID, err)
+	}
+	return formatPendingDues(user, dues), nil
+}
+
+// formatPendingDues writes the reply to "tagihan"
+func formatPendingDues(user models.User, dues []models.PaymentDue) string {
+	if len(dues) == 0 {
+		return fmt.Sprintf("Halo %s, tidak ada tagihan yang belum dibayar.", user.Name)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Halo %s, tagihan kamu yang belum dibayar:\n", user.Name)
+	for _, due := range dues {
+		fmt.Fprintf(&b, "\n#%d %s - Rp %s\nJatuh tempo %s", due.ID, due.Plan.Name, due.PayableAmount(), due.LocalDueDate().Format("02 Jan 2006"))
+		if due.PaymentStatus == models.PaymentStatusOverdue {
+			b.WriteString(" (terlambat)")
+		}
+		fmt.Fprintf(&b, "\n%s\n", paymentLink(due.UUID))
+	}
+	b.WriteString("\nSudah bayar? Balas \"lunas <id>\" untuk cek pembayaran.")
+	return b.String()
+}
+
+// verifyPaymentReply checks the payment of one of the user's dues with the payment gateway
+func verifyPaymentReply(ctx context.Context, db *gorm.DB, payments *services.PaymentService, user models.User, arg string) (string, error) {
+	dueID, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil {
+		return "Format: lunas <id>, contoh: lunas 12. Balas \"tagihan\" untuk melihat id tagihan kamu.", nil
+	}
+
+	var due models.PaymentDue
+	if err := db.Preload("Plan").Where("id = ? AND user_id = ?", dueID, user.ID).First(&due).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Sprintf("Tagihan #%d tidak ditemukan.", dueID), nil
+		}
+		return "", err
+	}
+
+	if due.PaymentStatus == models.PaymentStatusPending || due.PaymentStatus == models.PaymentStatusOverdue {
+		if payments == nil {
+			return "", fmt.Errorf("no payment service to verify payment due %d", due.ID)
+		}
+		if err := payments.VerifyPaymentStatus(ctx, due.ID); err != nil {
+			return "", fmt.Errorf("failed to verify payment due %d: %w", due.ID, err)
+		}
+		if err := db.First(&due, due.ID).Error; err != nil {
+			return "", err
+		}
+	}
+
+	switch due.PaymentStatus {
+	case models.PaymentStatusPaid:
+		return fmt.Sprintf("Pembayaran tagihan #%d %s sudah kami terima. Terima kasih!", due.ID, due.Plan.Name), nil
+	case models.PaymentStatusCanceled:
+		return fmt.Sprintf("Tagihan #%d %s sudah dibatalkan.", due.ID, due.Plan.Name), nil
+	}
+	return fmt.Sprintf("Pembayaran tagihan #%d %s belum kami terima. Bayar di %s", due.ID, due.Plan.Name, paymentLink(due.UUID)), nil
+}
+
+// stopNotificationsReply turns off the notifications of a user
+func stopNotificationsReply(db *gorm.DB, user models.User) (string, error) {
+	var pref models.UserNotifPreference
+	err := db.Where("user_id = ?", user.ID).First(&pref).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	pref.UserID = user.ID
+	pref.Channel = models.NotificationChannelNone
+	if err := db.Save(&pref).Error; err != nil {
+		return "", fmt.Errorf("failed to turn off notifications of user %d: %w", user.ID, err)
+	}
+	return "Notifikasi Patungan sudah dimatikan. Kamu bisa mengaktifkannya lagi dari pengaturan notifikasi di aplikasi.", nil
+}
+
+// groupSummaryReply summarizes the latest dues of the plans bound to a group, by their group
+// digest or by participants who get their notifications in the group. Groups without a plan get
+// no reply, the payment status of a plan is only shared with the group it belongs to.
+func groupSummaryReply(db *gorm.DB, chatID string) (string, error) {
+	groupIDs := []string{chatID, strings.TrimSuffix(chatID, "@g.us")}
+
+	var planIDs []uint
+	if err := db.Model(&models.Plan{}).Where("TRIM(group_digest_group_id) IN ?", groupIDs).Pluck("id", &planIDs).Error; err != nil {
+		return "", err
+	}
+	var preferencePlanIDs []uint
+	if err := db.Model(&models.PlanParticipant{}).
+		Joins("JOIN user_notif_preferences ON user_notif_preferences.user_id = plan_participants.user_id AND user_notif_preferences.deleted_at IS NULL").
+		Where("user_notif_preferences.whatsapp_target_type = ? AND user_notif_preferences.whatsapp_group_id IN ?",
+			models.WhatsappTargetTypeGroup, groupIDs).
+		Distinct().
+		Pluck("plan_participants.plan_id", &preferencePlanIDs).Error; err != nil {
+		return "", err
+	}
+	planIDs = append(planIDs, preferencePlanIDs...)
+	if len(planIDs) == 0 {
+		return "", nil
+	}
+
+	var plans []models.Plan
+	if err := db.Where("id IN ?", planIDs).Order("name").Limit(groupSummaryPlanLimit).Find(&plans).Error; err != nil {
+		return "", err
+	}
+
+	var summaries []string
+	for _, plan := range plans {
+		var latest models.PaymentDue
+		err := db.Where("plan_id = ? AND payment_status <> ?", plan.ID, models.PaymentStatusCanceled).Order("due_date DESC").First(&latest).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		var dues []models.PaymentDue
+		if err := db.Preload("User").
+			Where("plan_id = ? AND due_date = ? AND payment_status <> ?", plan.ID, latest.DueDate, models.PaymentStatusCanceled).
+			Find(&dues).Error; err != nil {
+			return "", err
+		}
+		for i := range dues {
+			dues[i].Plan = plan
+		}
+		summaries = append(summaries, formatPlanSummary(plan, dues))
+	}
+	if len(summaries) == 0 {
+		return "Belum ada tagihan untuk plan di grup ini.", nil
+	}
+	return strings.Join(summaries, "\n\n"), nil
+}
+
+// formatPlanSummary lists who has and hasn't paid the dues of one billing date of a plan
+func formatPlanSummary(plan models.Plan, dues []models.PaymentDue) string {
+	var b strings.Builder
+	b.WriteString(plan.Name)
+	if len(dues) > 0 {
+		fmt.Fprintf(&b, " - jatuh tempo %s", dues[0].LocalDueDate().Format("02 Jan 2006"))
+	}
+
+	var paid, unpaid []string
+	for _, due := range dues {
+		if due.PaymentStatus == models.PaymentStatusPaid {
+			paid = append(paid, fmt.Sprintf("✅ %s", due.User.Name))
+			continue
+		}
+		line := fmt.Sprintf("❌ %s - Rp %s", due.User.Name, due.PayableAmount())
+		if due.PaymentStatus == models.PaymentStatusOverdue {
+			line += " (terlambat)"
+		}
+		unpaid = append(unpaid, line)
+	}
+
+	fmt.Fprintf(&b, "\nSudah bayar %d dari %d", len(paid), len(dues))
+	for _, line := range append(paid, unpaid...) {
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
+
+// appURL returns the base URL of the app, without a trailing slash, used in the links of every message
+func appURL() string {
+	if url := os.Getenv("APP_URL"); url != "" {
+		return strings.TrimSuffix(url, "/")
+	}
+	return "http://localhost:8080"
+}
+
+// paymentLink returns the public payment page of a due
+func paymentLink(uuid string) string {
+	return appURL() + "/p/" + uuid
+}
@@ -0,0 +1,80 @@
+package tasks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestParseBotCommand(t *testing.T) {
+	tests := []struct {
+		text        string
+		wantCommand botCommand
+		wantArg     string
+	}{
+		{text: "tagihan", wantCommand: botCommandDues},
+		{text: "  Tagihan  ", wantCommand: botCommandDues},
+		{text: "lunas 12", wantCommand: botCommandPaid, wantArg: "12"},
+		{text: "LUNAS #12", wantCommand: botCommandPaid, wantArg: "12"},
+		{text: "lunas", wantCommand: botCommandPaid},
+		{text: "stop", wantCommand: botCommandStop},
+		{text: "halo", wantCommand: botCommandUnknown},
+		{text: "", wantCommand: botCommandUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			command, arg := parseBotCommand(tt.text)
+			if command != tt.wantCommand || arg != tt.wantArg {
+				t.Errorf("parseBotCommand(%q) = %q, %q, want %q, %q", tt.text, command, arg, tt.wantCommand, tt.wantArg)
+			}
+		})
+	}
+}
+
+func TestFormatPendingDues(t *testing.T) {
+	t.Setenv("APP_URL", "https://patungan.test/")
+	user := models.User{Name: "Budi"}
+	plan := models.Plan{Name: "Netflix", Timezone: "Asia/Jakarta"}
+	dueDate := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+
+	if got := formatPendingDues(user, nil); got != "Halo Budi, tidak ada tagihan yang belum dibayar." {
+		t.Errorf("formatPendingDues() without dues = %q", got)
+	}
+
+	dues := []models.PaymentDue{
+		{ID: 12, Plan: plan, DueDate: dueDate, UUID: "abc", CalculatedPayAmount: 50000, PaymentStatus: models.PaymentStatusPending},
+		{ID: 13, Plan: plan, DueDate: dueDate, UUID: "def", CalculatedPayAmount: 50000, LateFee: 5000, PaymentStatus: models.PaymentStatusOverdue},
+	}
+	got := formatPendingDues(user, dues)
+	for _, want := range []string{
+		"#12 Netflix - Rp 50000\nJatuh tempo 05 Jan 2026\nhttps://patungan.test/p/abc",
+		"#13 Netflix - Rp 55000\nJatuh tempo 05 Jan 2026 (terlambat)\nhttps://patungan.test/p/def",
+		"lunas <id>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("formatPendingDues() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestFormatPlanSummary(t *testing.T) {
+	plan := models.Plan{Name: "Netflix", Timezone: "Asia/Jakarta"}
+	dueDate := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	dues := []models.PaymentDue{
+		{Plan: plan, DueDate: dueDate, User: models.User{Name: "Budi"}, CalculatedPayAmount: 50000, PaymentStatus: models.PaymentStatusPending},
+		{Plan: plan, DueDate: dueDate, User: models.User{Name: "Sari"}, CalculatedPayAmount: 50000, PaymentStatus: models.PaymentStatusPaid},
+		{Plan: plan, DueDate: dueDate, User: models.User{Name: "Andi"}, CalculatedPayAmount: 50000, LateFee: 5000, PaymentStatus: models.PaymentStatusOverdue},
+	}
+
+	expected := "Netflix - jatuh tempo 05 Jan 2026\n" +
+		"Sudah bayar 1 dari 3\n" +
+		"✅ Sari\n" +
+		"❌ Budi - Rp 50000\n" +
+		"❌ Andi - Rp 55000 (terlambat)"
+	if got := formatPlanSummary(plan, dues); got != expected {
+		t.Errorf("formatPlanSummary() = %q, want %q", got, expected)
+	}
+}
@@ -0,0 +1,160 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"patungan_app_echo/internal/models"
+	"patungan_app_echo/internal/services"
+)
+
+// CheckWhatsappSessionArgs defines the arguments for the scheduled session check, it takes none
+type CheckWhatsappSessionArgs struct{}
+
+// CheckWhatsappSessionInterval is how often the WAHA session is checked
+const CheckWhatsappSessionInterval = "FREQ=MINUTELY;INTERVAL=5"
+
+// whatsappAlertRepeat is how long admins wait for a reminder while the session stays down
+const whatsappAlertRepeat = 6 * time.Hour
+
+// whatsappCheckRetention is how long session checks are kept for the admin page
+const whatsappCheckRetention = 7 * 24 * time.Hour
+
+// CheckWhatsappSessionTask records the status of the WAHA session and emails the admins when
+// it stops working, and again once it works
+var CheckWhatsappSessionTask = Define("check_whatsapp_session", handleCheckWhatsappSession, TaskOptions[CheckWhatsappSessionArgs]{
+	Timeout: time.Minute,
+})
+
+// handleCheckWhatsappSession queries the session, records the check and sends the alert it calls for
+func handleCheckWhatsappSession(ctx context.Context, db *gorm.DB, task models.ScheduledTask, args CheckWhatsappSessionArgs) (map[string]interface{}, error) {
+	deps, err := depsFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	notifier, _ := deps.Notifiers.Get(models.NotificationChannelWhatsapp)
+	waha, ok := notifier.(*services.WahaService)
+	if !ok {
+		// WhatsApp messages are not sent through WAHA, e.g. in a dry run
+		return map[string]interface{}{"status": "skipped"}, nil
+	}
+
+	check := models.WhatsappSessionCheck{Session: waha.Session()}
+	session, err := waha.SessionStatus(ctx)
+	if err != nil {
+		check.Status = models.WhatsappSessionStatusUnreachable
+		check.Error = err.Error()
+	} else {
+		check.Status = session.Status
+		check.Account = session.Account()
+	}
+
+	var lastAlert models.WhatsappSessionCheck
+	err = db.Where("session = ? AND alert <> ''", check.Session).Order("created_at DESC").First(&lastAlert).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	var lastAlertPtr *models.WhatsappSessionCheck
+	if err == nil {
+		lastAlertPtr = &lastAlert
+	}
+
+	now := time.Now()
+	alert := whatsappSessionAlert(check, lastAlertPtr, now)
+	if alert != "" {
+		sent, err := alertAdmins(ctx, db, deps.Notifiers, whatsappAlertMessage(check, alert))
+		if err != nil {
+			log.Printf("[Task CheckWhatsappSession] Failed to alert admins: %v", err)
+		}
+		if sent > 0 {
+			check.Alert = alert
+		}
+	}
+
+	if err := db.Create(&check).Error; err != nil {
+		return nil, fmt.Errorf("failed to record session check: %w", err)
+	}
+	if err := db.Where("created_at < ?", now.Add(-whatsappCheckRetention)).Delete(&models.WhatsappSessionCheck{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to prune session checks: %w", err)
+	}
+
+	return map[string]interface{}{
+		"status":         "success",
+		"session":        check.Session,
+		"session_status": check.Status,
+		"alert":          check.Alert,
+	}, nil
+}
+
+// whatsappSessionAlert tells which alert a check calls for, given the last check admins were
+// alerted of. Admins hear when the session goes down, every whatsappAlertRepeat while it stays
+// down, and when it works again after they were told it was down.
+func whatsappSessionAlert(check models.WhatsappSessionCheck, lastAlert *models.WhatsappSessionCheck, now time.Time) string {
+	if check.Working() {
+		if lastAlert != nil && lastAlert.Alert == models.WhatsappSessionAlertDown {
+			return models.WhatsappSessionAlertRecovered
+		}
+		return ""
+	}
+
+	if lastAlert == nil || lastAlert.Alert == models.WhatsappSessionAlertRecovered || now.Sub(lastAlert.CreatedAt) >= whatsappAlertRepeat {
+		return models.WhatsappSessionAlertDown
+	}
+	return ""
+}
+
+// whatsappAlertMessage writes the email telling admins about the session
+func whatsappAlertMessage(check models.WhatsappSessionCheck, alert string) services.NotifierMessage {
+	pageURL := appURL() + "/admin/whatsapp"
+
+	if alert == models.WhatsappSessionAlertRecovered {
+		return services.NotifierMessage{
+			Subject: fmt.Sprintf("WhatsApp session %s is working again", check.Session),
+			Body: fmt.Sprintf("The WhatsApp session %s is logged in as %s and sends notifications again.\n\n%s",
+				check.Session, check.Account, pageURL),
+		}
+	}
+
+	body := fmt.Sprintf("The WhatsApp session %s is %s, WhatsApp notifications are not being sent.\n", check.Session, check.Status)
+	switch check.Status {
+	case models.WhatsappSessionStatusScanQR:
+		body += "The account was logged out, scan the QR code on the WhatsApp page to pair it again.\n"
+	case models.WhatsappSessionStatusUnreachable:
+		body += fmt.Sprintf("WAHA could not be reached: %s\n", check.Error)
+	default:
+		body += "Restart the session from the WhatsApp page, or log out and pair it again if that does not help.\n"
+	}
+	body += "\n" + pageURL
+
+	return services.NotifierMessage{
+		Subject: fmt.Sprintf("WhatsApp session %s is %s", check.Session, check.Status),
+		Body:    body,
+	}
+}
+
+// alertAdmins emails a message to every admin and returns how many received it
+func alertAdmins(ctx context.Context, db *gorm.DB, notifiers *services.NotifierRegistry, msg services.NotifierMessage) (int, error) {
+	var admins []models.User
+	if err := db.Where("user_type = ? AND email <> ''", models.UserTypeAdmin).Find(&admins).Error; err != nil {
+		return 0, fmt.Errorf("failed to fetch admins: %w", err)
+	}
+	if len(admins) == 0 {
+		return 0, fmt.Errorf("no admin with an email address")
+	}
+
+	sent := 0
+	var errs []error
+	for _, admin := range admins {
+		if _, err := notifiers.Send(ctx, models.NotificationChannelEmail, admin.Email, msg); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", admin.Email, err))
+			continue
+		}
+		sent++
+	}
+	return sent, errors.Join(errs...)
+}
@@ -0,0 +1,89 @@
+package tasks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"patungan_app_echo/internal/models"
+)
+
+func TestWhatsappSessionAlert(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	alerted := func(alert string, ago time.Duration) *models.WhatsappSessionCheck {
+		return &models.WhatsappSessionCheck{CreatedAt: now.Add(-ago), Alert: alert}
+	}
+
+	tests := []struct {
+		name      string
+		status    string
+		lastAlert *models.WhatsappSessionCheck
+		expected  string
+	}{
+		{name: "working, never alerted", status: models.WhatsappSessionStatusWorking},
+		{name: "working after recovery", status: models.WhatsappSessionStatusWorking, lastAlert: alerted(models.WhatsappSessionAlertRecovered, time.Hour)},
+		{name: "working after down", status: models.WhatsappSessionStatusWorking, lastAlert: alerted(models.WhatsappSessionAlertDown, time.Hour), expected: models.WhatsappSessionAlertRecovered},
+		{name: "down, never alerted", status: models.WhatsappSessionStatusScanQR, expected: models.WhatsappSessionAlertDown},
+		{name: "down after recovery", status: models.WhatsappSessionStatusFailed, lastAlert: alerted(models.WhatsappSessionAlertRecovered, 10*time.Minute), expected: models.WhatsappSessionAlertDown},
+		{name: "still down, recently alerted", status: models.WhatsappSessionStatusUnreachable, lastAlert: alerted(models.WhatsappSessionAlertDown, time.Hour)},
+		{name: "still down, alert repeated", status: models.WhatsappSessionStatusStopped, lastAlert: alerted(models.WhatsappSessionAlertDown, whatsappAlertRepeat), expected: models.WhatsappSessionAlertDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := models.WhatsappSessionCheck{Status: tt.status}
+			if got := whatsappSessionAlert(check, tt.lastAlert, now); got != tt.expected {
+				t.Errorf("whatsappSessionAlert() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestWhatsappAlertMessage(t *testing.T) {
+	t.Setenv("APP_URL", "https://patungan.example/")
+
+	tests := []struct {
+		name        string
+		check       models.WhatsappSessionCheck
+		alert       string
+		wantSubject string
+		wantBody    string
+	}{
+		{
+			name:        "logged out",
+			check:       models.WhatsappSessionCheck{Session: "default", Status: models.WhatsappSessionStatusScanQR},
+			alert:       models.WhatsappSessionAlertDown,
+			wantSubject: "WhatsApp session default is SCAN_QR_CODE",
+			wantBody:    "scan the QR code",
+		},
+		{
+			name:        "unreachable",
+			check:       models.WhatsappSessionCheck{Session: "default", Status: models.WhatsappSessionStatusUnreachable, Error: "connection refused"},
+			alert:       models.WhatsappSessionAlertDown,
+			wantSubject: "WhatsApp session default is UNREACHABLE",
+			wantBody:    "connection refused",
+		},
+		{
+			name:        "recovered",
+			check:       models.WhatsappSessionCheck{Session: "default", Status: models.WhatsappSessionStatusWorking, Account: "6281234567890 (Patungan)"},
+			alert:       models.WhatsappSessionAlertRecovered,
+			wantSubject: "WhatsApp session default is working again",
+			wantBody:    "logged in as 6281234567890 (Patungan)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := whatsappAlertMessage(tt.check, tt.alert)
+			if msg.Subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", msg.Subject, tt.wantSubject)
+			}
+			if !strings.Contains(msg.Body, tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", msg.Body, tt.wantBody)
+			}
+			if !strings.HasSuffix(msg.Body, "https://patungan.example/admin/whatsapp") {
+				t.Errorf("body = %q, want it to end with the WhatsApp page URL", msg.Body)
+			}
+		})
+	}
+}
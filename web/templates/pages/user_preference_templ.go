@@ -13,8 +13,14 @@ import (
 	"patungan_app_echo/internal/models"
 )
 
+// TelegramLinkProps is the deep link used to link a Telegram chat from the preference popup
+type TelegramLinkProps struct {
+	Code     string
+	StartURL string // empty when the bot username is not configured
+}
+
 // UserPreferencePopup renders the notification preference popup
-func UserPreferencePopup(user models.User, pref models.UserNotifPreference) templ.Component {
+func UserPreferencePopup(user models.User, pref models.UserNotifPreference, telegram TelegramLinkProps, errMsg string) templ.Component {
 	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
 		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
 		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
@@ -42,7 +48,7 @@ func UserPreferencePopup(user models.User, pref models.UserNotifPreference) temp
 		var templ_7745c5c3_Var2 string
 		templ_7745c5c3_Var2, templ_7745c5c3_Err = templ.JoinStringErrs(user.Name)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 17, Col: 100}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 23, Col: 100}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var2))
 		if templ_7745c5c3_Err != nil {
@@ -55,120 +61,313 @@ func UserPreferencePopup(user models.User, pref models.UserNotifPreference) temp
 		var templ_7745c5c3_Var3 string
 		templ_7745c5c3_Var3, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/users/%d/preference", user.ID))
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 19, Col: 62}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 25, Col: 62}
 		}
 		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var3))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" hx-target=\"#notif-pref-modal\" hx-swap=\"outerHTML\"><div class=\"space-y-4\"><!-- Channel Selection --><div><label class=\"block text-sm font-medium text-text-secondary mb-2\">Preferred Channel</label><div class=\"flex flex-wrap gap-4\"><label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"none\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 3, "\" hx-target=\"#notif-pref-modal\" hx-swap=\"outerHTML\">")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if errMsg != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, "<p class=\"text-sm text-red-500 mb-4\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var4 string
+			templ_7745c5c3_Var4, templ_7745c5c3_Err = templ.JoinStringErrs(errMsg)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 27, Col: 50}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var4))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, "</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, "<div class=\"space-y-4\"><!-- Channel Selection --><div><label class=\"block text-sm font-medium text-text-secondary mb-2\">Preferred Channel</label><div class=\"flex flex-wrap gap-4\"><label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"none\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if pref.Channel == models.NotificationChannelNone || pref.Channel == "" {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 4, " checked")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, " checked")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 5, " onchange=\"toggleWhatsappOptions(false)\"> <span class=\"ml-2 text-text-primary\">None</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"email\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, " onchange=\"toggleChannelOptions(this.value)\"> <span class=\"ml-2 text-text-primary\">None</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"email\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if pref.Channel == models.NotificationChannelEmail {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 6, " checked")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, " checked")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 7, " onchange=\"toggleWhatsappOptions(false)\"> <span class=\"ml-2 text-text-primary\">Email</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"whatsapp\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, " onchange=\"toggleChannelOptions(this.value)\"> <span class=\"ml-2 text-text-primary\">Email</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"whatsapp\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if pref.Channel == models.NotificationChannelWhatsapp {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 8, " checked")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, " checked")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 9, " onchange=\"toggleWhatsappOptions(true)\"> <span class=\"ml-2 text-text-primary\">WhatsApp</span></label></div></div><!-- WhatsApp Options -->")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, " onchange=\"toggleChannelOptions(this.value)\"> <span class=\"ml-2 text-text-primary\">WhatsApp</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"channel\" value=\"telegram\" class=\"form-radio text-primary focus:ring-primary border-border bg-bg-body\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var4 = []any{"p-4 bg-bg-body rounded-lg border border-border transition-all duration-200", templ.KV("hidden", pref.Channel != models.NotificationChannelWhatsapp)}
-		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var4...)
+		if pref.Channel == models.NotificationChannelTelegram {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, " checked")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, " onchange=\"toggleChannelOptions(this.value)\"> <span class=\"ml-2 text-text-primary\">Telegram</span></label></div></div><!-- WhatsApp Options -->")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 10, "<div id=\"whatsapp-options\" class=\"")
+		var templ_7745c5c3_Var5 = []any{"p-4 bg-bg-body rounded-lg border border-border transition-all duration-200", templ.KV("hidden", pref.Channel != models.NotificationChannelWhatsapp)}
+		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var5...)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var5 string
-		templ_7745c5c3_Var5, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var4).String())
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, "<div id=\"whatsapp-options\" class=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var6 string
+		templ_7745c5c3_Var6, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var5).String())
 		if templ_7745c5c3_Err != nil {
 			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 1, Col: 0}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var5))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var6))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 11, "\"><label class=\"block text-sm font-medium text-text-secondary mb-2\">WhatsApp Target</label><div class=\"flex gap-4 mb-3\"><label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"whatsapp_target_type\" value=\"personal\" class=\"form-radio text-green-600 focus:ring-green-600 border-border bg-bg-card\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "\"><label class=\"block text-sm font-medium text-text-secondary mb-2\">WhatsApp Target</label><div class=\"flex gap-4 mb-3\"><label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"whatsapp_target_type\" value=\"personal\" class=\"form-radio text-green-600 focus:ring-green-600 border-border bg-bg-card\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if pref.WhatsappTargetType == models.WhatsappTargetTypePersonal {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 12, " checked")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, " checked")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 13, " onchange=\"toggleGroupInput(false)\"> <span class=\"ml-2 text-text-primary\">Personal Number</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"whatsapp_target_type\" value=\"group\" class=\"form-radio text-green-600 focus:ring-green-600 border-border bg-bg-card\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, " onchange=\"toggleGroupInput(false)\"> <span class=\"ml-2 text-text-primary\">Personal Number</span></label> <label class=\"inline-flex items-center cursor-pointer\"><input type=\"radio\" name=\"whatsapp_target_type\" value=\"group\" class=\"form-radio text-green-600 focus:ring-green-600 border-border bg-bg-card\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
 		if pref.WhatsappTargetType == models.WhatsappTargetTypeGroup {
-			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 14, " checked")
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, " checked")
 			if templ_7745c5c3_Err != nil {
 				return templ_7745c5c3_Err
 			}
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 15, " onchange=\"toggleGroupInput(true)\"> <span class=\"ml-2 text-text-primary\">Group</span></label></div>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 20, " onchange=\"toggleGroupInput(true)\"> <span class=\"ml-2 text-text-primary\">Group</span></label></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var6 = []any{"transition-all duration-200", templ.KV("hidden", pref.WhatsappTargetType != models.WhatsappTargetTypeGroup)}
-		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var6...)
+		var templ_7745c5c3_Var7 = []any{"transition-all duration-200", templ.KV("hidden", pref.WhatsappTargetType != models.WhatsappTargetTypeGroup)}
+		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var7...)
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 16, "<div id=\"group-id-container\" class=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 21, "<div id=\"group-id-container\" class=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var7 string
-		templ_7745c5c3_Var7, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var6).String())
+		var templ_7745c5c3_Var8 string
+		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var7).String())
 		if templ_7745c5c3_Err != nil {
 			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 1, Col: 0}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var7))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 17, "\"><label class=\"block text-xs font-medium text-text-secondary mb-1\">Group ID (Chat ID)</label> <input type=\"text\" name=\"whatsapp_group_id\" value=\"")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 22, "\"><label class=\"block text-xs font-medium text-text-secondary mb-1\">Group ID (Chat ID)</label> <input type=\"text\" name=\"whatsapp_group_id\" value=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		var templ_7745c5c3_Var8 string
-		templ_7745c5c3_Var8, templ_7745c5c3_Err = templ.JoinStringErrs(pref.WhatsappGroupID)
+		var templ_7745c5c3_Var9 string
+		templ_7745c5c3_Var9, templ_7745c5c3_Err = templ.JoinStringErrs(pref.WhatsappGroupID)
 		if templ_7745c5c3_Err != nil {
-			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 66, Col: 79}
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 81, Col: 79}
 		}
-		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var8))
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var9))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 23, "\" class=\"w-full px-3 py-2 bg-bg-card border border-border rounded-lg text-text-primary focus:outline-none focus:ring-2 focus:ring-primary text-sm\" placeholder=\"e.g. 123456789@g.us\"><p class=\"text-xs text-text-secondary mt-1\">Get this ID from WAHA logs or API.</p></div></div><!-- Telegram Options -->")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var10 = []any{"p-4 bg-bg-body rounded-lg border border-border transition-all duration-200", templ.KV("hidden", pref.Channel != models.NotificationChannelTelegram)}
+		templ_7745c5c3_Err = templ.RenderCSSItems(ctx, templ_7745c5c3_Buffer, templ_7745c5c3_Var10...)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 24, "<div id=\"telegram-options\" class=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var11 string
+		templ_7745c5c3_Var11, templ_7745c5c3_Err = templ.JoinStringErrs(templ.CSSClasses(templ_7745c5c3_Var10).String())
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 1, Col: 0}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var11))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 25, "\"><input type=\"hidden\" name=\"telegram_link_code\" value=\"")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		var templ_7745c5c3_Var12 string
+		templ_7745c5c3_Var12, templ_7745c5c3_Err = templ.JoinStringErrs(telegram.Code)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 90, Col: 74}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var12))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 26, "\"> ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if telegram.StartURL != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 27, "<p class=\"text-sm text-text-secondary mb-3\"><a href=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var13 templ.SafeURL
+			templ_7745c5c3_Var13, templ_7745c5c3_Err = templ.JoinURLErrs(templ.SafeURL(telegram.StartURL))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 93, Col: 50}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var13))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 28, "\" target=\"_blank\" rel=\"noopener\" class=\"text-primary font-medium\" style=\"text-decoration: underline;\">Open the bot in Telegram</a> and press Start, then find the chat here.</p><button type=\"button\" hx-post=\"")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var14 string
+			templ_7745c5c3_Var14, templ_7745c5c3_Err = templ.JoinStringErrs(fmt.Sprintf("/users/%d/preference/telegram-link", user.ID))
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 96, Col: 97}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var14))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 29, "\" hx-target=\"#telegram-chat-id-field\" hx-swap=\"outerHTML\" class=\"px-3 py-1.5 mb-3 text-sm text-text-primary bg-bg-card border border-border rounded-lg font-medium hover:bg-bg-hover transition-colors\">Find my chat</button>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = TelegramChatIDField(pref.TelegramChatID, "", "").Render(ctx, templ_7745c5c3_Buffer)
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 30, "</div></div><div class=\"flex justify-end gap-3 mt-6 pt-4 border-t border-border\"><button type=\"button\" onclick=\"document.getElementById('notif-pref-modal').remove()\" class=\"px-4 py-2 text-text-primary bg-bg-body border border-border rounded-lg font-medium hover:bg-bg-hover transition-colors\">Cancel</button> <button type=\"submit\" class=\"px-4 py-2 text-white bg-primary rounded-lg font-medium hover:bg-primary-hover transition-colors shadow-sm\">Save Changes</button></div></form><script>\n\t\t\t\tfunction toggleChannelOptions(channel) {\n\t\t\t\t\tdocument.getElementById('whatsapp-options').classList.toggle('hidden', channel !== 'whatsapp');\n\t\t\t\t\tdocument.getElementById('telegram-options').classList.toggle('hidden', channel !== 'telegram');\n\t\t\t\t}\n\t\t\t\tfunction toggleGroupInput(show) {\n\t\t\t\t\tconst el = document.getElementById('group-id-container');\n\t\t\t\t\tif (show) el.classList.remove('hidden');\n\t\t\t\t\telse el.classList.add('hidden');\n\t\t\t\t}\n\t\t\t</script></div></div>")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		return nil
+	})
+}
+
+// TelegramChatIDField renders the Telegram chat ID input, refreshed when a chat is found through the bot
+func TelegramChatIDField(chatID, notice, errMsg string) templ.Component {
+	return templruntime.GeneratedTemplate(func(templ_7745c5c3_Input templruntime.GeneratedComponentInput) (templ_7745c5c3_Err error) {
+		templ_7745c5c3_W, ctx := templ_7745c5c3_Input.Writer, templ_7745c5c3_Input.Context
+		if templ_7745c5c3_CtxErr := ctx.Err(); templ_7745c5c3_CtxErr != nil {
+			return templ_7745c5c3_CtxErr
+		}
+		templ_7745c5c3_Buffer, templ_7745c5c3_IsBuffer := templruntime.GetBuffer(templ_7745c5c3_W)
+		if !templ_7745c5c3_IsBuffer {
+			defer func() {
+				templ_7745c5c3_BufErr := templruntime.ReleaseBuffer(templ_7745c5c3_Buffer)
+				if templ_7745c5c3_Err == nil {
+					templ_7745c5c3_Err = templ_7745c5c3_BufErr
+				}
+			}()
+		}
+		ctx = templ.InitializeContext(ctx)
+		templ_7745c5c3_Var15 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var15 == nil {
+			templ_7745c5c3_Var15 = templ.NopComponent
+		}
+		ctx = templ.ClearChildren(ctx)
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 31, "<div id=\"telegram-chat-id-field\"><label class=\"block text-xs font-medium text-text-secondary mb-1\">Telegram Chat ID</label> <input type=\"text\" name=\"telegram_chat_id\" value=\"")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 18, "\" class=\"w-full px-3 py-2 bg-bg-card border border-border rounded-lg text-text-primary focus:outline-none focus:ring-2 focus:ring-primary text-sm\" placeholder=\"e.g. 123456789@g.us\"><p class=\"text-xs text-text-secondary mt-1\">Get this ID from WAHA logs or API.</p></div></div></div><div class=\"flex justify-end gap-3 mt-6 pt-4 border-t border-border\"><button type=\"button\" onclick=\"document.getElementById('notif-pref-modal').remove()\" class=\"px-4 py-2 text-text-primary bg-bg-body border border-border rounded-lg font-medium hover:bg-bg-hover transition-colors\">Cancel</button> <button type=\"submit\" class=\"px-4 py-2 text-white bg-primary rounded-lg font-medium hover:bg-primary-hover transition-colors shadow-sm\">Save Changes</button></div></form><script>\n\t\t\t\tfunction toggleWhatsappOptions(show) {\n\t\t\t\t\tconst el = document.getElementById('whatsapp-options');\n\t\t\t\t\tif (show) el.classList.remove('hidden');\n\t\t\t\t\telse el.classList.add('hidden');\n\t\t\t\t}\n\t\t\t\tfunction toggleGroupInput(show) {\n\t\t\t\t\tconst el = document.getElementById('group-id-container');\n\t\t\t\t\tif (show) el.classList.remove('hidden');\n\t\t\t\t\telse el.classList.add('hidden');\n\t\t\t\t}\n\t\t\t</script></div></div>")
+		var templ_7745c5c3_Var16 string
+		templ_7745c5c3_Var16, templ_7745c5c3_Err = templ.JoinStringErrs(chatID)
+		if templ_7745c5c3_Err != nil {
+			return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 135, Col: 59}
+		}
+		_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var16))
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 32, "\" class=\"w-full px-3 py-2 bg-bg-card border border-border rounded-lg text-text-primary focus:outline-none focus:ring-2 focus:ring-primary text-sm\" placeholder=\"e.g. 123456789\"> ")
+		if templ_7745c5c3_Err != nil {
+			return templ_7745c5c3_Err
+		}
+		if notice != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 33, "<p class=\"text-xs text-green-500 mt-1\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var17 string
+			templ_7745c5c3_Var17, templ_7745c5c3_Err = templ.JoinStringErrs(notice)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 139, Col: 50}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var17))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 34, "</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		if errMsg != "" {
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 35, "<p class=\"text-xs text-red-500 mt-1\">")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			var templ_7745c5c3_Var18 string
+			templ_7745c5c3_Var18, templ_7745c5c3_Err = templ.JoinStringErrs(errMsg)
+			if templ_7745c5c3_Err != nil {
+				return templ.Error{Err: templ_7745c5c3_Err, FileName: `web/templates/pages/user_preference.templ`, Line: 142, Col: 48}
+			}
+			_, templ_7745c5c3_Err = templ_7745c5c3_Buffer.WriteString(templ.EscapeString(templ_7745c5c3_Var18))
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+			templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 36, "</p>")
+			if templ_7745c5c3_Err != nil {
+				return templ_7745c5c3_Err
+			}
+		}
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 37, "<p class=\"text-xs text-text-secondary mt-1\">The bot can only message chats that started it.</p></div>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}
@@ -193,12 +392,12 @@ func UserPreferenceSuccess() templ.Component {
 			}()
 		}
 		ctx = templ.InitializeContext(ctx)
-		templ_7745c5c3_Var9 := templ.GetChildren(ctx)
-		if templ_7745c5c3_Var9 == nil {
-			templ_7745c5c3_Var9 = templ.NopComponent
+		templ_7745c5c3_Var19 := templ.GetChildren(ctx)
+		if templ_7745c5c3_Var19 == nil {
+			templ_7745c5c3_Var19 = templ.NopComponent
 		}
 		ctx = templ.ClearChildren(ctx)
-		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 19, "<script>\n\t\t(function() {\n\t\t\tconst toast = document.createElement('div');\n\t\t\ttoast.style.position = 'fixed';\n\t\t\ttoast.style.bottom = '2rem';\n\t\t\ttoast.style.right = '2rem';\n\t\t\ttoast.style.zIndex = '99999';\n\t\t\ttoast.style.backgroundColor = '#10b981';\n\t\t\ttoast.style.color = 'white';\n\t\t\ttoast.style.padding = '0.75rem 1.5rem';\n\t\t\ttoast.style.borderRadius = '0.5rem';\n\t\t\ttoast.style.boxShadow = '0 10px 15px -3px rgba(0, 0, 0, 0.1), 0 4px 6px -2px rgba(0, 0, 0, 0.05)';\n\t\t\ttoast.style.transition = 'all 0.3s ease';\n\t\t\ttoast.style.opacity = '0';\n\t\t\ttoast.style.transform = 'translateY(1rem)';\n\t\t\ttoast.innerText = 'Settings saved successfully!';\n\t\t\t\n\t\t\tdocument.body.appendChild(toast);\n\t\t\t\n\t\t\tsetTimeout(() => {\n\t\t\t\ttoast.style.opacity = '1';\n\t\t\t\ttoast.style.transform = 'translateY(0)';\n\t\t\t}, 10);\n\t\t\t\n\t\t\tsetTimeout(() => {\n\t\t\t\ttoast.style.opacity = '0';\n\t\t\t\ttoast.style.transform = 'translateY(1rem)';\n\t\t\t\tsetTimeout(() => toast.remove(), 300);\n\t\t\t}, 3000);\n\n\t\t\t// Self-destruct: remove this script tag from the DOM\n\t\t\t// Since hx-swap=\"outerHTML\" replaced the modal with this script,\n\t\t\t// removing the script leaves nothing behind.\n\t\t\tconst script = document.currentScript;\n\t\t\tif (script) {\n\t\t\t\tsetTimeout(() => script.remove(), 100);\n\t\t\t}\n\t\t})();\n\t</script>")
+		templ_7745c5c3_Err = templruntime.WriteString(templ_7745c5c3_Buffer, 38, "<script>\n\t\t(function() {\n\t\t\tconst toast = document.createElement('div');\n\t\t\ttoast.style.position = 'fixed';\n\t\t\ttoast.style.bottom = '2rem';\n\t\t\ttoast.style.right = '2rem';\n\t\t\ttoast.style.zIndex = '99999';\n\t\t\ttoast.style.backgroundColor = '#10b981';\n\t\t\ttoast.style.color = 'white';\n\t\t\ttoast.style.padding = '0.75rem 1.5rem';\n\t\t\ttoast.style.borderRadius = '0.5rem';\n\t\t\ttoast.style.boxShadow = '0 10px 15px -3px rgba(0, 0, 0, 0.1), 0 4px 6px -2px rgba(0, 0, 0, 0.05)';\n\t\t\ttoast.style.transition = 'all 0.3s ease';\n\t\t\ttoast.style.opacity = '0';\n\t\t\ttoast.style.transform = 'translateY(1rem)';\n\t\t\ttoast.innerText = 'Settings saved successfully!';\n\t\t\t\n\t\t\tdocument.body.appendChild(toast);\n\t\t\t\n\t\t\tsetTimeout(() => {\n\t\t\t\ttoast.style.opacity = '1';\n\t\t\t\ttoast.style.transform = 'translateY(0)';\n\t\t\t}, 10);\n\t\t\t\n\t\t\tsetTimeout(() => {\n\t\t\t\ttoast.style.opacity = '0';\n\t\t\t\ttoast.style.transform = 'translateY(1rem)';\n\t\t\t\tsetTimeout(() => toast.remove(), 300);\n\t\t\t}, 3000);\n\n\t\t\t// Self-destruct: remove this script tag from the DOM\n\t\t\t// Since hx-swap=\"outerHTML\" replaced the modal with this script,\n\t\t\t// removing the script leaves nothing behind.\n\t\t\tconst script = document.currentScript;\n\t\t\tif (script) {\n\t\t\t\tsetTimeout(() => script.remove(), 100);\n\t\t\t}\n\t\t})();\n\t</script>")
 		if templ_7745c5c3_Err != nil {
 			return templ_7745c5c3_Err
 		}